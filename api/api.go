@@ -1,39 +1,25 @@
 package api
 
 import (
-	"encoding/json"
-	"net/http"
 	"time"
 )
 
-type Error struct {
-	Code int
-	Message string
-}
+// Prefix est le chemin de la version courante de l'API ; les routes sans préfixe sont dépréciées
+const Prefix = "/api/v1"
 
-type Session struct {
-	Token     string
-	UserID    int
-	ExpiresAt time.Time
+// User est l'utilisateur d'une session
+type User struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	IsAdmin  bool   `json:"is_admin"`
+	Plan     string `json:"plan"`
 }
 
-func writeError(w http.ResponseWriter, message string, code int) {
-	resp := Error{
-		Code:    code,
-		Message: message,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-
-	json.NewEncoder(w).Encode(resp)
+// Session est la réponse de POST /login et de POST /logout.
+// ExpiresAt est la fin de validité de la session dans la réponse de /login,
+// et l'heure de la déconnexion dans la réponse de /logout, sans Token.
+type Session struct {
+	Token     string     `json:"token,omitempty"`
+	User      User       `json:"user"`
+	ExpiresAt *time.Time `json:"expires_at"`
 }
-
-var (
-	RequestErrorHandler = func(w http.ResponseWriter,  err error) {
-		writeError(w, err.Error(), http.StatusBadRequest)
-	}
-	InternalErrorHandler = func(w http.ResponseWriter) {
-		writeError(w, "An Unexpected Error Occurred.", http.StatusInternalServerError)
-	}
-)
\ No newline at end of file
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Codes d'erreur stables : les clients testent le code, pas le message
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeQuotaExceeded    = "quota_exceeded"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeUpstreamError    = "upstream_error"
+	CodeUnavailable      = "unavailable"
+	CodeInternal         = "internal_error"
+)
+
+// En-tête de l'identifiant de requête, posé sur la réponse par middleware.RequestID
+const RequestIDHeader = "X-Request-Id"
+
+// Error est le contenu de toute réponse d'erreur : {"error": {...}}
+type Error struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	RequestID string       `json:"request_id,omitempty"`
+	Details   []FieldError `json:"details,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+// FieldError est l'erreur de validation d'un champ de la requête
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// Invalid retourne une erreur de validation pour field ; son texte est "<field> <message>"
+func Invalid(field, format string, args ...any) error {
+	return &FieldError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// fieldErrors retrouve les FieldError de err, y compris dans un errors.Join
+func fieldErrors(err error) []FieldError {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var details []FieldError
+		for _, e := range joined.Unwrap() {
+			details = append(details, fieldErrors(e)...)
+		}
+		return details
+	}
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		return []FieldError{*fe}
+	}
+	return nil
+}
+
+// WriteError écrit l'enveloppe d'erreur avec l'identifiant de la requête en cours
+func WriteError(w http.ResponseWriter, status int, code, message string, details ...FieldError) {
+	resp := ErrorResponse{Error{
+		Code:      code,
+		Message:   message,
+		RequestID: w.Header().Get(RequestIDHeader),
+		Details:   details,
+	}}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+var (
+	// RequestErrorHandler répond 400 avec le texte de err, qui doit être destiné au client.
+	// Les erreurs créées par Invalid donnent validation_failed et le détail par champ.
+	RequestErrorHandler = func(w http.ResponseWriter, err error) {
+		details := fieldErrors(err)
+		if len(details) == 0 {
+			WriteError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+		messages := make([]string, len(details))
+		for i, d := range details {
+			messages[i] = d.Field + " " + d.Message
+		}
+		WriteError(w, http.StatusBadRequest, CodeValidationFailed, strings.Join(messages, "; "), details...)
+	}
+	// InternalErrorHandler ne donne jamais la cause au client, elle doit être loguée par l'appelant
+	InternalErrorHandler = func(w http.ResponseWriter) {
+		WriteError(w, http.StatusInternalServerError, CodeInternal, "An Unexpected Error Occurred.")
+	}
+	NotFoundErrorHandler = func(w http.ResponseWriter, message string) {
+		WriteError(w, http.StatusNotFound, CodeNotFound, message)
+	}
+	UnauthorizedErrorHandler = func(w http.ResponseWriter, message string) {
+		WriteError(w, http.StatusUnauthorized, CodeUnauthorized, message)
+	}
+	ForbiddenErrorHandler = func(w http.ResponseWriter, message string) {
+		WriteError(w, http.StatusForbidden, CodeForbidden, message)
+	}
+	ConflictErrorHandler = func(w http.ResponseWriter, err error) {
+		WriteError(w, http.StatusConflict, CodeConflict, err.Error())
+	}
+)
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// APIKey est une clé d'API de l'utilisateur ; Key n'est renseignée qu'à la création
+type APIKey struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Prefix     string  `json:"prefix"`
+	CreatedAt  string  `json:"created_at"`
+	LastUsedAt *string `json:"last_used_at"`
+	Key        string  `json:"key"`
+}
+
+// CreateAPIKey crée une clé d'API ; la clé retournée ne peut plus être relue ensuite
+func (c *Client) CreateAPIKey(ctx context.Context, name string) (*APIKey, error) {
+	body, err := json.Marshal(map[string]string{"name": name})
+	if err != nil {
+		return nil, err
+	}
+	var k APIKey
+	if err := c.do(ctx, http.MethodPost, "/api_keys", "application/json", bytes.NewReader(body), &k); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ListAPIKeys retourne les clés d'API de l'utilisateur, sans les clés elles-mêmes
+func (c *Client) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	var keys []APIKey
+	if err := c.do(ctx, http.MethodGet, "/api_keys", "", nil, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// DeleteAPIKey révoque une clé d'API
+func (c *Client) DeleteAPIKey(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api_keys/"+escape(id), "", nil, nil)
+}
@@ -0,0 +1,141 @@
+// Package client est le client Go de l'API : connexion, clés d'API, scripts, exécutions et logs.
+//
+//	c := client.New("https://api.example.com")
+//	if _, err := c.Login(ctx, "alice", "secret"); err != nil { ... } // ou c.SetAPIKey(key)
+//	id, err := c.Run(ctx, scriptID)
+//	execution, err := c.StreamLogs(ctx, id, func(l client.LogEntry) error { ... })
+//
+// Les erreurs de l'API sont des *Error, comparables avec errors.Is aux ErrNotFound, ErrUnauthorized, ...
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+)
+
+// Intervalle par défaut entre deux lectures d'une exécution en cours
+const DefaultPollInterval = time.Second
+
+// Nom du cookie de session posé par POST /login
+const sessionCookie = "session_token"
+
+// Client appelle une instance de l'API ; il est sûr en usage concurrent
+type Client struct {
+	// URL de l'instance, sans /api/v1 (https://api.example.com)
+	BaseURL string
+	// Client HTTP utilisé pour chaque requête, http.DefaultClient par défaut
+	HTTPClient *http.Client
+	// Attente entre deux lectures dans WaitExecution et StreamLogs
+	PollInterval time.Duration
+
+	mu     sync.RWMutex
+	token  string
+	apiKey string
+}
+
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:      strings.TrimRight(baseURL, "/"),
+		HTTPClient:   http.DefaultClient,
+		PollInterval: DefaultPollInterval,
+	}
+}
+
+// SetToken authentifie les requêtes suivantes avec un jeton de session existant,
+// par exemple celui d'un Login fait par un autre processus
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// SetAPIKey authentifie les requêtes suivantes avec une clé d'API (Authorization: Bearer),
+// à la place du jeton de session ; vide pour revenir à la session
+func (c *Client) SetAPIKey(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.apiKey = key
+}
+
+// Token retourne le jeton de session courant, vide avant Login
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// Login ouvre une session ; le jeton est gardé pour les requêtes suivantes
+func (c *Client) Login(ctx context.Context, username, password string) (*api.Session, error) {
+	body, err := json.Marshal(map[string]string{"username": username, "password": password})
+	if err != nil {
+		return nil, err
+	}
+	var session api.Session
+	if err := c.do(ctx, http.MethodPost, "/login", "application/json", bytes.NewReader(body), &session); err != nil {
+		return nil, err
+	}
+	c.SetToken(session.Token)
+	return &session, nil
+}
+
+// Logout ferme la session courante et oublie le jeton
+func (c *Client) Logout(ctx context.Context) error {
+	if err := c.do(ctx, http.MethodPost, "/logout", "", nil, nil); err != nil {
+		return err
+	}
+	c.SetToken("")
+	return nil
+}
+
+// do envoie une requête sur api.Prefix+path et décode la réponse JSON dans out (ignorée si nil)
+func (c *Client) do(ctx context.Context, method, path, contentType string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+api.Prefix+path, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	c.mu.RLock()
+	token, apiKey := c.token, c.apiKey
+	c.mu.RUnlock()
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	} else if token != "" {
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: token})
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return decodeError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// escape protège un identifiant placé dans un chemin
+func escape(id string) string {
+	return url.PathEscape(id)
+}
@@ -0,0 +1,308 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/database"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/handlers"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/languages"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestServer démarre le vrai routeur avec l'utilisatrice alice et retourne son identifiant
+func newTestServer(t *testing.T) (*httptest.Server, *store.Store, int) {
+	t.Helper()
+	st, err := store.Open(database.SQLite, database.Memory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	langs, err := languages.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	config := handlers.DefaultConfig()
+	config.DataDir = t.TempDir()
+	// Les scripts des tests échouent avant de lancer un conteneur : aucun démon Docker n'est appelé
+	app := handlers.New(st, langs, nil, config, logger)
+	srv := httptest.NewServer(app.Handler())
+	t.Cleanup(srv.Close)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := st.Users.Create("alice", string(hash), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, st, userID
+}
+
+func TestLoginAndErrors(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	ctx := context.Background()
+	c := New(srv.URL + "/")
+
+	_, err := c.Login(ctx, "alice", "wrong")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrUnauthorized) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.RequestID == "" {
+		t.Fatalf("bad password: %#v", err)
+	}
+
+	session, err := c.Login(ctx, "alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.User.Username != "alice" || c.Token() == "" || c.Token() != session.Token {
+		t.Fatalf("session %+v, token %q", session, c.Token())
+	}
+
+	if _, err := c.GetScript(ctx, "missing"); !errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("missing script: %v", err)
+	}
+
+	// Un jeton existant suffit pour un autre client
+	other := New(srv.URL)
+	other.SetToken(c.Token())
+	if _, err := other.GetScript(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("token reuse: %v", err)
+	}
+
+	if err := c.Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.GetScript(ctx, "missing"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("after logout: %v", err)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	ctx := context.Background()
+	c := New(srv.URL)
+	if _, err := c.Login(ctx, "alice", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := c.CreateAPIKey(ctx, "ci")
+	if err != nil || !strings.HasPrefix(k.Key, k.Prefix) || k.ID == "" {
+		t.Fatalf("create: %+v %v", k, err)
+	}
+	if _, err := c.CreateAPIKey(ctx, "ci"); !errors.Is(err, ErrConflict) {
+		t.Fatalf("duplicate name: %v", err)
+	}
+
+	// La clé suffit, sans session
+	ci := New(srv.URL)
+	ci.SetAPIKey(k.Key)
+	if _, err := ci.GetScript(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("request with the key: %v", err)
+	}
+	keys, err := ci.ListAPIKeys(ctx)
+	if err != nil || len(keys) != 1 || keys[0].ID != k.ID || keys[0].Key != "" || keys[0].LastUsedAt == nil {
+		t.Fatalf("list: %+v %v", keys, err)
+	}
+
+	if err := c.DeleteAPIKey(ctx, k.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ci.GetScript(ctx, "missing"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("revoked key: %v", err)
+	}
+	if err := c.DeleteAPIKey(ctx, k.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("delete twice: %v", err)
+	}
+}
+
+func TestUploadValidation(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	ctx := context.Background()
+	c := New(srv.URL)
+	if _, err := c.Login(ctx, "alice", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Langue inconnue : refusée avant tout accès à Docker
+	_, err := c.UploadScript(ctx, Upload{
+		Name:     "hello",
+		Language: "cobol",
+		Tags:     []string{"a", "b"},
+		Filename: "hello.cob",
+		File:     strings.NewReader("DISPLAY 'hello'."),
+	})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrValidationFailed) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("upload: %v", err)
+	}
+	if len(apiErr.Details) != 1 || apiErr.Details[0].Field != "language" {
+		t.Fatalf("details %+v", apiErr.Details)
+	}
+
+	_, err = c.UploadScript(ctx, Upload{})
+	if !errors.As(err, &apiErr) || len(apiErr.Details) != 2 {
+		t.Fatalf("empty upload: %v", err)
+	}
+}
+
+func TestRunAndStreamLogs(t *testing.T) {
+	srv, st, userID := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Langue retirée de l'instance : l'exécution échoue sans lancer de conteneur
+	err := st.Scripts.Create(store.Script{ID: "script-1", UserID: userID, Name: "s", Language: "cobol", DockerImage: "cobol", FilePath: "s.cob", Mounts: "[]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(srv.URL)
+	c.PollInterval = 10 * time.Millisecond
+	if _, err := c.Run(ctx, "script-1"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("run without session: %v", err)
+	}
+	if _, err := c.Login(ctx, "alice", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := c.Run(ctx, "script-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs []LogEntry
+	execution, err := c.StreamLogs(ctx, id, func(l LogEntry) error {
+		logs = append(logs, l)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if execution.ID != id || execution.Status != "failed" || !execution.Finished() || execution.ExitCode == nil || *execution.ExitCode != -1 {
+		t.Fatalf("execution %+v", execution)
+	}
+	if len(logs) != 1 || logs[0].Stream != "stderr" || !strings.Contains(logs[0].Content, "cobol is not supported") {
+		t.Fatalf("logs %+v", logs)
+	}
+
+	waited, err := c.WaitExecution(ctx, id)
+	if err != nil || waited.Status != "failed" {
+		t.Fatalf("wait: %+v %v", waited, err)
+	}
+
+	// L'erreur du callback arrête la lecture
+	stop := errors.New("stop")
+	if _, err := c.StreamLogs(ctx, id, func(LogEntry) error { return stop }); !errors.Is(err, stop) {
+		t.Fatalf("callback error: %v", err)
+	}
+}
+
+// roundTripFunc enregistre les requêtes du client avant de les envoyer
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestStreamLogsReadsNewEntriesOnly(t *testing.T) {
+	srv, st, userID := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := st.Scripts.Create(store.Script{ID: "script-1", UserID: userID, Name: "s", Language: "python", DockerImage: "python", FilePath: "s.py", Mounts: "[]"}); err != nil {
+		t.Fatal(err)
+	}
+	startedAt := time.Now().UTC().Format(time.RFC3339)
+	if err := st.Executions.Create(store.Execution{ID: "exec-1", ScriptID: "script-1", UserID: userID, Status: "running", Trigger: "manual", StartedAt: &startedAt}); err != nil {
+		t.Fatal(err)
+	}
+	appendLog := func(seq int64, content string) {
+		t.Helper()
+		err := st.Logs.Append([]store.LogChunk{{ID: content, ExecutionID: "exec-1", Stream: "stdout", Content: content, Seq: seq, Size: int64(len(content))}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	appendLog(1, "first")
+
+	var queries []string
+	c := New(srv.URL)
+	c.PollInterval = 10 * time.Millisecond
+	c.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(r.URL.Path, "/logs") {
+			queries = append(queries, r.URL.RawQuery)
+		}
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	if _, err := c.Login(ctx, "alice", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	// La suite arrive pendant la lecture, puis l'exécution se termine
+	var got []string
+	execution, err := c.StreamLogs(ctx, "exec-1", func(l LogEntry) error {
+		got = append(got, l.Content)
+		if l.Content == "first" {
+			appendLog(2, "second")
+			return st.Executions.Finish("exec-1", "success", 0, time.Now().UTC().Format(time.RFC3339))
+		}
+		return nil
+	})
+	if err != nil || execution.Status != "success" {
+		t.Fatalf("execution %+v, %v", execution, err)
+	}
+	if strings.Join(got, ",") != "first,second" {
+		t.Errorf("entries %v", got)
+	}
+	if strings.Join(queries, ",") != ",after=1" {
+		t.Errorf("log queries %q", queries)
+	}
+}
+
+func TestWaitExecutionContext(t *testing.T) {
+	srv, st, userID := newTestServer(t)
+	if err := st.Scripts.Create(store.Script{ID: "script-1", UserID: userID, Name: "s", Language: "python", DockerImage: "python", FilePath: "s.py", Mounts: "[]"}); err != nil {
+		t.Fatal(err)
+	}
+	startedAt := time.Now().UTC().Format(time.RFC3339)
+	if err := st.Executions.Create(store.Execution{ID: "exec-1", ScriptID: "script-1", UserID: userID, Status: "running", Trigger: "manual", StartedAt: &startedAt}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(srv.URL)
+	c.PollInterval = 10 * time.Millisecond
+	if _, err := c.Login(context.Background(), "alice", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.WaitExecution(ctx, "exec-1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait on a running execution: %v", err)
+	}
+}
+
+func TestDecodeErrorWithoutEnvelope(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != api.Prefix+"/executions/x" {
+			t.Errorf("path %s", r.URL.Path)
+		}
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := New(srv.URL).GetExecution(context.Background(), "x")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Code != "" ||
+		apiErr.RetryAfter != 30*time.Second || !strings.Contains(apiErr.Message, "maintenance") {
+		t.Fatalf("error %#v", err)
+	}
+}
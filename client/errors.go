@@ -0,0 +1,81 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+)
+
+// Error est une réponse d'erreur de l'API ({"error": {...}}).
+// errors.Is compare le code : errors.Is(err, client.ErrNotFound)
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+	// Champs refusés, pour le code validation_failed
+	Details []api.FieldError
+	// Délai demandé par Retry-After (429, 503), zéro sinon
+	RetryAfter time.Duration
+}
+
+// Erreurs de référence pour errors.Is
+var (
+	ErrInvalidRequest   = &Error{Code: api.CodeInvalidRequest}
+	ErrValidationFailed = &Error{Code: api.CodeValidationFailed}
+	ErrUnauthorized     = &Error{Code: api.CodeUnauthorized}
+	ErrForbidden        = &Error{Code: api.CodeForbidden}
+	ErrNotFound         = &Error{Code: api.CodeNotFound}
+	ErrConflict         = &Error{Code: api.CodeConflict}
+	ErrQuotaExceeded    = &Error{Code: api.CodeQuotaExceeded}
+	ErrTooManyRequests  = &Error{Code: api.CodeTooManyRequests}
+	ErrUnavailable      = &Error{Code: api.CodeUnavailable}
+)
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("api: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+	return msg
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// decodeError lit l'enveloppe d'erreur ; une réponse qui n'en est pas une
+// (proxy devant l'API, ...) garde son statut et le début de son corps
+func decodeError(resp *http.Response) error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get(api.RequestIDHeader),
+	}
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(s) * time.Second
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var envelope api.ErrorResponse
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Error.Code != "" {
+		e.Code = envelope.Error.Code
+		e.Message = envelope.Error.Message
+		e.Details = envelope.Error.Details
+		if envelope.Error.RequestID != "" {
+			e.RequestID = envelope.Error.RequestID
+		}
+		return e
+	}
+
+	e.Message = http.StatusText(resp.StatusCode)
+	if len(body) > 0 {
+		e.Message = string(body[:min(len(body), 200)])
+	}
+	return e
+}
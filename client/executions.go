@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Execution est une exécution de script, telle que retournée par GET /executions/{id}
+type Execution struct {
+	ID         string  `json:"id"`
+	ScriptID   string  `json:"script_id"`
+	Status     string  `json:"status"`
+	Trigger    string  `json:"trigger"`
+	ScheduleID *string `json:"schedule_id"`
+	TriggerID  *string `json:"trigger_id"`
+	ExitCode   *int    `json:"exit_code"`
+	StartedAt  *string `json:"started_at"`
+	FinishedAt *string `json:"finished_at"`
+	// nil tant que le conteneur n'a pas été mesuré
+	Metrics *Metrics `json:"metrics"`
+}
+
+// Finished indique si l'exécution est terminée (success, failed ou timed_out)
+func (e *Execution) Finished() bool {
+	return e.Status != "queued" && e.Status != "running"
+}
+
+// Metrics sont les ressources consommées par une exécution
+type Metrics struct {
+	DurationMs      int64 `json:"duration_ms"`
+	PeakMemoryBytes int64 `json:"peak_memory_bytes"`
+	CPUTimeMs       int64 `json:"cpu_time_ms"`
+	NetRxBytes      int64 `json:"net_rx_bytes"`
+	NetTxBytes      int64 `json:"net_tx_bytes"`
+	BlockReadBytes  int64 `json:"block_read_bytes"`
+	BlockWriteBytes int64 `json:"block_write_bytes"`
+}
+
+// LogEntry est une sortie d'une exécution : stream build, stdout ou stderr.
+// Seq est sa position dans l'exécution, à passer à LogsAfter.
+type LogEntry struct {
+	Stream    string `json:"stream"`
+	Content   string `json:"content"`
+	CreatedAt string `json:"created_at"`
+	Seq       int64  `json:"seq"`
+}
+
+// Run lance un script et retourne l'identifiant de l'exécution
+func (c *Client) Run(ctx context.Context, scriptID string) (string, error) {
+	var started struct {
+		ExecutionID string `json:"execution_id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/scripts/"+escape(scriptID)+"/run", "", nil, &started); err != nil {
+		return "", err
+	}
+	return started.ExecutionID, nil
+}
+
+// GetExecution retourne l'état d'une exécution
+func (c *Client) GetExecution(ctx context.Context, id string) (*Execution, error) {
+	var e Execution
+	if err := c.do(ctx, http.MethodGet, "/executions/"+escape(id), "", nil, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Logs retourne les logs enregistrés d'une exécution
+func (c *Client) Logs(ctx context.Context, id string) ([]LogEntry, error) {
+	return c.LogsAfter(ctx, id, 0)
+}
+
+// LogsAfter retourne les logs enregistrés après la sortie de numéro seq, tous si seq vaut 0
+func (c *Client) LogsAfter(ctx context.Context, id string, seq int64) ([]LogEntry, error) {
+	path := "/executions/" + escape(id) + "/logs"
+	if seq > 0 {
+		path += "?after=" + strconv.FormatInt(seq, 10)
+	}
+	var logs []LogEntry
+	if err := c.do(ctx, http.MethodGet, path, "", nil, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// WaitExecution relit l'exécution toutes les PollInterval jusqu'à ce qu'elle se termine.
+// Pour borner l'attente, passer un contexte avec une échéance.
+func (c *Client) WaitExecution(ctx context.Context, id string) (*Execution, error) {
+	for {
+		e, err := c.GetExecution(ctx, id)
+		if err != nil || e.Finished() {
+			return e, err
+		}
+		if err := c.sleep(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// StreamLogs appelle fn pour chaque nouvelle sortie de l'exécution, dans l'ordre,
+// jusqu'à la fin de l'exécution qu'il retourne. Une erreur de fn arrête la lecture.
+// L'API enregistre les logs par blocs (build, puis sortie du conteneur) : les nouveaux
+// sont demandés toutes les PollInterval, après le dernier reçu.
+func (c *Client) StreamLogs(ctx context.Context, id string, fn func(LogEntry) error) (*Execution, error) {
+	var seq int64
+	for {
+		// L'état est lu avant les logs : une exécution terminée a déjà tout enregistré
+		e, err := c.GetExecution(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		logs, err := c.LogsAfter(ctx, id, seq)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range logs {
+			if err := fn(l); err != nil {
+				return nil, err
+			}
+			seq = max(seq, l.Seq)
+		}
+		if e.Finished() {
+			return e, nil
+		}
+		if err := c.sleep(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) sleep(ctx context.Context) error {
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// Script est un script hébergé, tel que retourné par GET /scripts/{id}
+type Script struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Language    string   `json:"language"`
+	DockerImage string   `json:"docker_image"`
+	FilePath    string   `json:"file_path"`
+	Mounts      []Mount  `json:"mounts"`
+	Tags        []string `json:"tags"`
+	CreatedAt   string   `json:"created_at"`
+	LastRunAt   *string  `json:"last_run_at"`
+}
+
+// Mount est un volume monté dans les exécutions d'un script
+type Mount struct {
+	Volume   string `json:"volume"`
+	Path     string `json:"path"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+// Upload décrit un script à envoyer ; Name, Language et File sont obligatoires
+type Upload struct {
+	Name        string
+	Description string
+	Language    string
+	// Image personnalisée, soumise à l'allow-list de l'instance
+	DockerImage string
+	Tags        []string
+	// Nom du fichier envoyé, "script" si vide
+	Filename string
+	File     io.Reader
+}
+
+// UploadScript envoie un script (multipart/form-data) et retourne son identifiant
+func (c *Client) UploadScript(ctx context.Context, u Upload) (string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, field := range [][2]string{
+		{"name", u.Name},
+		{"description", u.Description},
+		{"language", u.Language},
+		{"docker_image", u.DockerImage},
+		{"tags", strings.Join(u.Tags, ",")},
+	} {
+		if field[1] == "" {
+			continue
+		}
+		if err := mw.WriteField(field[0], field[1]); err != nil {
+			return "", err
+		}
+	}
+	if u.File != nil {
+		filename := u.Filename
+		if filename == "" {
+			filename = "script"
+		}
+		part, err := mw.CreateFormFile("file", filename)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.Copy(part, u.File); err != nil {
+			return "", err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/scripts/upload", mw.FormDataContentType(), &body, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// GetScript retourne un script de l'utilisateur connecté
+func (c *Client) GetScript(ctx context.Context, id string) (*Script, error) {
+	var s Script
+	if err := c.do(ctx, http.MethodGet, "/scripts/"+escape(id), "", nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DeleteScript supprime un script, ses exécutions et les serveurs qui le font tourner
+func (c *Client) DeleteScript(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/scripts/"+escape(id), "", nil, nil)
+}
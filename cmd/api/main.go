@@ -1,129 +1,160 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"net/http"
-	"database/sql"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
-	"github.com/go-chi/chi"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/config"
 	"github.com/Cryptowave2-0/webhosting-goapi/internal/handlers"
-	"github.com/Cryptowave2-0/webhosting-goapi/internal/auth"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/languages"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/go-chi/chi"
 	log "github.com/sirupsen/logrus"
-	_ "github.com/mattn/go-sqlite3"
+	_ "time/tzdata"
 )
 
 func main() {
 	log.SetReportCaller(true)
-	var r *chi.Mux = chi.NewRouter()
 
-	db, err := sql.Open("sqlite3", "app.db")
+	cfg, args, err := config.Load(os.Args[1:])
 	if err != nil {
-		fmt.Println(err.Error())
-		log.Fatal(err)
+		log.Fatalf("invalid configuration: %v", err)
 	}
-	
-	defer db.Close()
 
-	initDB(db)
-
-	auth.Setup(db)
-	handlers.Setup(db)
-
-	fmt.Println(`
-	 ______    ______   __    __
-	/\  __ \  /\  == \ /\ \  /\_\
-	\ \  __ \ \ \  _-/ \ \ \ \/_/_
-	 \ \_\ \ \ \ \_\    \ \_\  /\_\
-	  \/_/\/_/  \/_/     \/_/  \/_/
-	`)
+	// Sous-commandes : api migrate [up|down [n]|to <version>|status], api config print
+	if len(args) > 0 {
+		switch {
+		case args[0] == "migrate":
+			if err := runMigrate(cfg.Database, args[1:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case args[0] == "config" && len(args) == 2 && args[1] == "print":
+			if err := cfg.Print(os.Stdout); err != nil {
+				log.Fatal(err)
+			}
+			return
+		default:
+			log.Fatalf("unknown command %q (supported: migrate, config print)", strings.Join(args, " "))
+		}
+	}
 
-	fmt.Println("Starting GO API service...")
+	var r *chi.Mux = chi.NewRouter()
 
-	handlers.RegisterAPIRoutes(r)
+	driver := cfg.Database.Driver
+	st, err := store.Open(driver, cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("failed opening the database: %v", err)
+	}
+	defer st.Close()
 
-	err1 := http.ListenAndServe(":8000", r)
-	if err1 != nil {
-		log.Error(err1)
+	if err := st.SetupSearch(); err != nil {
+		log.Fatal(err)
 	}
-	
-}
 
-func initDB(db *sql.DB) {
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS users (
-			id INTEGER NOT NULL UNIQUE PRIMARY KEY AUTOINCREMENT,
-			username TEXT NOT NULL UNIQUE,
-			password TEXT NOT NULL
-		);`)
+	langs, err := languages.Load(cfg.Languages.File)
 	if err != nil {
-    	log.Fatalf("failed creating users table: %v", err)
-	} else {
-		fmt.Println("Table 'users' created succesfully")
+		log.Fatalf("failed loading languages: %v", err)
+	}
+	for name, image := range cfg.Languages.Images {
+		if err := langs.SetImage(name, image); err != nil {
+			log.Fatalf("failed configuring languages: %v", err)
+		}
 	}
 
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS sessions (
-			token TEXT PRIMARY KEY,
-			user_id INTEGER NOT NULL,
-			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
-		);
-		`)
+	docker, err := handlers.NewDockerRuntime()
 	if err != nil {
-    	log.Fatalf("failed creating users table: %v", err)
-	} else {
-		fmt.Println("Table 'sessions' created succesfully")
+		log.Fatalf("failed creating the Docker client: %v", err)
 	}
+	defer docker.Close()
 
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS scripts (
-			id          TEXT PRIMARY KEY,
-			user_id     INTEGER NOT NULL,
-			name        TEXT NOT NULL,
-			description TEXT DEFAULT '',
-			language    TEXT NOT NULL,
-			docker_image TEXT NOT NULL,
-			file_path   TEXT NOT NULL,
-			created_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
-		);`)
-	if err != nil {
-		log.Fatalf("failed creating scripts table: %v", err)
-	} else {
-		fmt.Println("Table 'scripts' created succesfully")
+	app := handlers.New(st, langs, docker, handlersConfig(cfg), log.StandardLogger())
+	app.RegisterAPIRoutes(r)
+
+	srv := &http.Server{Addr: cfg.Listen, Handler: r}
+	var redirect *http.Server
+	if cfg.TLS.Enabled() {
+		if redirect, err = setupTLS(cfg, srv); err != nil {
+			log.Fatalf("failed configuring TLS: %v", err)
+		}
 	}
 
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS executions (
-			id          TEXT PRIMARY KEY,
-			script_id   TEXT NOT NULL,
-			user_id     INTEGER NOT NULL,
-			status      TEXT NOT NULL DEFAULT 'pending',
-			exit_code   INTEGER,
-			started_at  DATETIME,
-			finished_at DATETIME,
-			created_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY(script_id) REFERENCES scripts(id) ON DELETE CASCADE,
-			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
-		);`)
-	if err != nil {
-		log.Fatalf("failed creating executions table: %v", err)
-	} else {
-		fmt.Println("Table 'executions' created succesfully")
+	app.ReconcileServers()
+	app.ResumeExecutions()
+
+	// SIGINT / SIGTERM : arrêt propre, les tâches de fond s'arrêtent avec ctx
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	app.StartScheduler(ctx)
+	app.StartWebhookDispatcher(ctx)
+	app.StartServerMonitor(ctx)
+	app.StartArtifactJanitor(ctx)
+	app.StartLogJanitor(ctx)
+
+	scheme := "http"
+	if cfg.TLS.Enabled() {
+		scheme = "https"
+	}
+	log.Infof("starting the API on %s (%s)", cfg.Listen, scheme)
+	serveErr := make(chan error, 2)
+	go func() {
+		if cfg.TLS.Enabled() {
+			// Certificat fourni par srv.TLSConfig
+			serveErr <- srv.ListenAndServeTLS("", "")
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+	if redirect != nil {
+		go func() {
+			serveErr <- redirect.ListenAndServe()
+		}()
 	}
 
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS logs (
-			id           TEXT PRIMARY KEY,
-			execution_id TEXT NOT NULL,
-			stream       TEXT NOT NULL,
-			content      TEXT NOT NULL,
-			created_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY(execution_id) REFERENCES executions(id) ON DELETE CASCADE
-		);`)
-	if err != nil {
-		log.Fatalf("failed creating logs table: %v", err)
-	} else {
-		fmt.Println("Table 'logs' created succesfully")
+	select {
+	case err := <-serveErr:
+		// Port déjà pris, certificat illisible... : l'API n'a jamais servi
+		log.Fatalf("http server: %v", err)
+	case <-ctx.Done():
 	}
+	stop()
+	log.Infof("shutting down, waiting up to %s for requests and executions", cfg.ShutdownTimeout)
+
+	// Plus de nouvelles exécutions, puis fin des requêtes en cours et des exécutions
+	app.Drain()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Warnf("http server: %v", err)
+	}
+	if redirect != nil {
+		redirect.Shutdown(shutdownCtx)
+	}
+	if err := app.Shutdown(shutdownCtx); err != nil {
+		log.Warn("executions still running were detached, they will be resumed at the next start")
+	}
+	log.Info("stopped")
+}
 
-}
\ No newline at end of file
+// handlersConfig retourne les réglages de l'instance de l'API
+func handlersConfig(c config.Config) handlers.Config {
+	return handlers.Config{
+		DataDir:           c.DataDir,
+		SecureCookies:     c.SecureCookies(),
+		SessionTTL:        c.Sessions.TTL,
+		ProxyBaseDomain:   c.Proxy.BaseDomain,
+		ProxyAPIURL:       c.Proxy.APIURL,
+		ProxyPathMode:     c.Proxy.PathMode,
+		ArtifactRetention: c.Artifacts.Retention,
+		LogRetention:      c.Logs.Retention,
+		LogKeepPerScript:  c.Logs.KeepPerScript,
+		ExecutionTimeout:  c.Executions.Timeout,
+		LegacyRoutes:      c.LegacyRoutes.Enabled,
+		LegacySunset:      c.LegacyRoutes.Sunset,
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/config"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/database"
+)
+
+// runMigrate — api migrate [up|down [n]|to <version>|status]
+// Sans argument, applique les migrations en attente
+func runMigrate(cfg config.Database, args []string) error {
+	driver := cfg.Driver
+	db, err := database.Open(driver, cfg.URL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch {
+	case action == "up" && len(args) <= 1:
+		err = database.Migrate(db, driver)
+	case action == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("migrate down: invalid number of steps %q", args[1])
+			}
+		}
+		err = database.Rollback(db, driver, steps)
+	case action == "to" && len(args) == 2:
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil || version < 0 {
+			return fmt.Errorf("migrate to: invalid version %q", args[1])
+		}
+		err = database.MigrateTo(db, driver, version)
+	case action == "status" && len(args) == 1:
+		return printMigrationStatus(db, driver)
+	default:
+		return fmt.Errorf("usage: migrate [up | down [n] | to <version> | status]")
+	}
+	if err != nil {
+		return err
+	}
+	return printMigrationStatus(db, driver)
+}
+
+// printMigrationStatus affiche chaque migration embarquée et sa date d'application
+func printMigrationStatus(db *sql.DB, driver string) error {
+	status, err := database.Status(db, driver)
+	if err != nil {
+		return err
+	}
+	for _, m := range status {
+		applied := "pending"
+		if m.AppliedAt != "" {
+			applied = "applied " + m.AppliedAt
+		}
+		fmt.Printf("%04d_%s\t%s\n", m.Version, m.Name, applied)
+	}
+	return nil
+}
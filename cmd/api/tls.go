@@ -0,0 +1,53 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/certs"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/config"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+)
+
+// setupTLS prépare le serveur HTTPS et, si tls.redirect_listen est renseigné,
+// le serveur HTTP en clair qui redirige vers HTTPS et répond aux challenges ACME http-01
+func setupTLS(cfg config.Config, srv *http.Server) (*http.Server, error) {
+	var tlsConfig *tls.Config
+	redirect := middleware.RedirectHTTPS(port(cfg.Listen))
+
+	if cfg.TLS.CertFile != "" {
+		reloader, err := certs.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = &tls.Config{GetCertificate: reloader.GetCertificate}
+	} else {
+		manager, err := certs.NewManager(certs.ACMEOptions{
+			Domains:      cfg.TLS.ACME.Domains,
+			Email:        cfg.TLS.ACME.Email,
+			CacheDir:     cfg.TLS.ACME.CacheDir,
+			DirectoryURL: cfg.TLS.ACME.DirectoryURL,
+			CAFile:       cfg.TLS.ACME.CAFile,
+		})
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = manager.TLSConfig()
+		redirect = manager.HTTPHandler(redirect)
+	}
+	tlsConfig.MinVersion = tls.VersionTLS12
+
+	srv.TLSConfig = tlsConfig
+	srv.Handler = middleware.HSTS(cfg.TLS.HSTS.MaxAge, cfg.TLS.HSTS.IncludeSubdomains)(srv.Handler)
+
+	if cfg.TLS.RedirectListen == "" {
+		return nil, nil
+	}
+	return &http.Server{Addr: cfg.TLS.RedirectListen, Handler: redirect}, nil
+}
+
+func port(address string) string {
+	_, p, _ := net.SplitHostPort(address)
+	return p
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// APIKeyPrefix commence chaque clé d'API, pour la distinguer d'un jeton de session
+const APIKeyPrefix = "whk_"
+
+// GenerateAPIKey retourne une nouvelle clé d'API
+func GenerateAPIKey() (string, error) {
+	token, err := GenerateSessionToken()
+	if err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + strings.TrimRight(token, "="), nil
+}
+
+// HashAPIKey retourne le hash sous lequel la clé est enregistrée
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetUserFromAPIKey retourne l'utilisateur de la clé, store.ErrNotFound si elle n'existe pas.
+// La date de dernière utilisation n'est qu'indicative : son échec est logué sans refuser la clé.
+func (s *Service) GetUserFromAPIKey(key string) (int, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return 0, store.ErrNotFound
+	}
+	k, err := s.store.APIKeys.GetByHash(HashAPIKey(key))
+	if err != nil {
+		return 0, err
+	}
+	if err := s.store.APIKeys.Touch(k.ID, time.Now()); err != nil {
+		log.WithError(err).WithField("api_key", k.ID).Warn("api key last use not recorded")
+	}
+	return k.UserID, nil
+}
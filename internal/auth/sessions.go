@@ -1,30 +1,38 @@
 package auth
 
 import (
-	"database/sql"
-)
+	"time"
 
-var db *sql.DB
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+)
 
-func Setup(database *sql.DB) {
-	db = database
+// Service gère les sessions et les droits des utilisateurs d'un store
+type Service struct {
+	store *store.Store
 }
 
-func SaveSession(token string, userID int) error {
-	_, err := db.Exec("INSERT OR REPLACE INTO sessions(token, user_id) VALUES(?, ?)", token, userID)
-	return err
+func New(st *store.Store) *Service {
+	return &Service{store: st}
 }
 
-func GetUserFromSession(token string) (int, error) {
-	var userID int
-	err := db.QueryRow("SELECT user_id FROM sessions WHERE token = ?", token).Scan(&userID)
-	if err != nil {
-		return 0, err
+// SaveSession enregistre une session valable jusqu'à expiresAt, et supprime au passage
+// les sessions expirées pour que la table ne grossisse pas
+func (s *Service) SaveSession(token string, userID int, expiresAt time.Time) error {
+	if err := s.store.Sessions.DeleteExpired(time.Now()); err != nil {
+		return err
 	}
-	return userID, nil
+	return s.store.Sessions.Save(token, userID, expiresAt)
+}
+
+// GetUserFromSession retourne l'utilisateur de la session, store.ErrNotFound si elle n'existe pas ou a expiré
+func (s *Service) GetUserFromSession(token string) (int, error) {
+	return s.store.Sessions.UserID(token, time.Now())
 }
 
-func DeleteSession(token string) error {
-	_, err := db.Exec("DELETE FROM sessions WHERE token = ?", token)
-	return err
-}
\ No newline at end of file
+func (s *Service) DeleteSession(token string) error {
+	return s.store.Sessions.Delete(token)
+}
+func (s *Service) IsAdmin(userID int) (bool, error) {
+	user, err := s.store.Users.Get(userID)
+	return user.IsAdmin, err
+}
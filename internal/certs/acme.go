@@ -0,0 +1,61 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ACMEOptions décrit le serveur ACME et les domaines à certifier
+type ACMEOptions struct {
+	Domains  []string
+	Email    string
+	CacheDir string
+	// Vide : Let's Encrypt (production)
+	DirectoryURL string
+	// Certificat d'une autorité supplémentaire pour joindre DirectoryURL (Pebble)
+	CAFile string
+}
+
+// NewManager crée le gestionnaire autocert : les certificats sont obtenus au premier handshake
+// (challenge tls-alpn-01, ou http-01 via HTTPHandler), gardés dans CacheDir et renouvelés avant expiration.
+func NewManager(o ACMEOptions) (*autocert.Manager, error) {
+	if len(o.Domains) == 0 {
+		return nil, errors.New("acme: no domain")
+	}
+	if err := os.MkdirAll(o.CacheDir, 0700); err != nil {
+		return nil, err
+	}
+
+	client := &acme.Client{DirectoryURL: o.DirectoryURL}
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("acme: no certificate found in %s", o.CAFile)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+		client.HTTPClient = &http.Client{Transport: transport}
+	}
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(o.CacheDir),
+		HostPolicy: autocert.HostWhitelist(o.Domains...),
+		Email:      o.Email,
+		Client:     client,
+	}, nil
+}
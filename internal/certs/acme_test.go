@@ -0,0 +1,71 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"testing"
+)
+
+// TestManagerWithPebble obtient un certificat d'un serveur ACME de test Pebble
+// (https://github.com/letsencrypt/pebble) démarré à part, par exemple :
+//
+//	PEBBLE_VA_ALWAYS_VALID=1 pebble -config test/config/pebble-config.json
+//	PEBBLE_DIRECTORY_URL=https://localhost:14000/dir PEBBLE_CA_FILE=test/certs/pebble.minica.pem go test ./internal/certs
+//
+// Les challenges sont servis sur les ports de validation de Pebble (5001 tls-alpn-01, 5002 http-01) ;
+// le domaine (ACME_TEST_DOMAIN, localhost par défaut) doit y pointer pour Pebble.
+// Sans PEBBLE_DIRECTORY_URL le test est ignoré.
+func TestManagerWithPebble(t *testing.T) {
+	directory := os.Getenv("PEBBLE_DIRECTORY_URL")
+	if directory == "" {
+		t.Skip("PEBBLE_DIRECTORY_URL not set")
+	}
+	domain := os.Getenv("ACME_TEST_DOMAIN")
+	if domain == "" {
+		domain = "localhost"
+	}
+
+	m, err := NewManager(ACMEOptions{
+		Domains:      []string{domain},
+		Email:        "admin@example.com",
+		CacheDir:     t.TempDir(),
+		DirectoryURL: directory,
+		CAFile:       os.Getenv("PEBBLE_CA_FILE"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tlsListener, err := tls.Listen("tcp", ":5001", m.TLSConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpListener, err := net.Listen("tcp", ":5002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: m.HTTPHandler(nil)}
+	go srv.Serve(tlsListener)
+	challenges := &http.Server{Handler: m.HTTPHandler(nil)}
+	go challenges.Serve(httpListener)
+	t.Cleanup(func() {
+		srv.Close()
+		challenges.Close()
+	})
+
+	cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: domain})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(leaf.DNSNames, domain) {
+		t.Fatalf("certificate for %v, want %s", leaf.DNSNames, domain)
+	}
+}
@@ -0,0 +1,92 @@
+package certs
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Intervalle minimum entre deux vérifications des fichiers
+const reloadInterval = 5 * time.Second
+
+// Reloader sert une paire certificat / clé PEM et la relit quand l'un des fichiers change,
+// sans redémarrer l'API (renouvellement par certbot, secret Kubernetes monté...).
+type Reloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+// NewReloader charge la paire, qui doit être valide au démarrage
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	modTime, err := r.modified()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate s'utilise comme tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) >= reloadInterval {
+		r.checked = time.Now()
+		r.reload()
+	}
+	return r.cert, nil
+}
+
+// reload relit la paire si un fichier a changé ; en cas d'erreur (fichiers en cours d'écriture,
+// clé et certificat qui ne correspondent pas encore) l'ancien certificat reste servi
+func (r *Reloader) reload() {
+	modTime, err := r.modified()
+	if err != nil {
+		log.Warnf("tls: %v", err)
+		return
+	}
+	if !modTime.After(r.modTime) {
+		return
+	}
+	if err := r.load(modTime); err != nil {
+		log.Warnf("tls: keeping the current certificate: %v", err)
+		return
+	}
+	log.Infof("tls: certificate %s reloaded", r.certFile)
+}
+
+func (r *Reloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+// modified retourne la date de modification la plus récente des deux fichiers
+func (r *Reloader) modified() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
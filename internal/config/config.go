@@ -0,0 +1,465 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/database"
+	"gopkg.in/yaml.v3"
+)
+
+// Fichier lu au démarrage s'il existe et qu'aucun autre n'est donné
+const DefaultFile = "config.yaml"
+
+// Config est la configuration de l'API. Chaque source remplace la précédente :
+// valeurs par défaut, fichier YAML, variables d'environnement puis flags.
+type Config struct {
+	Listen    string    `yaml:"listen"`
+	TLS       TLS       `yaml:"tls"`
+	DataDir   string    `yaml:"data_dir"`
+	Database  Database  `yaml:"database"`
+	Cookies   Cookies   `yaml:"cookies"`
+	Sessions  Sessions  `yaml:"sessions"`
+	Proxy     Proxy     `yaml:"proxy"`
+	Languages Languages `yaml:"languages"`
+	Artifacts Artifacts `yaml:"artifacts"`
+	Logs      Logs      `yaml:"logs"`
+
+	Executions Executions `yaml:"executions"`
+
+	LegacyRoutes LegacyRoutes `yaml:"legacy_routes"`
+
+	// Attente max des requêtes et exécutions en cours à l'arrêt, avant de détacher les conteneurs
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// TLS est activé par une paire certificat / clé ou par des domaines ACME
+type TLS struct {
+	// Fichiers PEM, relus quand ils changent
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	ACME     ACME   `yaml:"acme"`
+	// Port HTTP en clair qui redirige vers HTTPS et répond aux challenges ACME http-01 ; vide pour aucun
+	RedirectListen string `yaml:"redirect_listen"`
+	HSTS           HSTS   `yaml:"hsts"`
+}
+
+// Enabled indique si l'API écoute en HTTPS
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || len(t.ACME.Domains) > 0
+}
+
+// ACME obtient et renouvelle les certificats (Let's Encrypt par défaut)
+type ACME struct {
+	Domains []string `yaml:"domains"`
+	Email   string   `yaml:"email"`
+	// Comptes et certificats obtenus ; par défaut <data_dir>/acme
+	CacheDir string `yaml:"cache_dir"`
+	// Annuaire du serveur ACME, par exemple celui de Pebble pour les tests
+	DirectoryURL string `yaml:"directory_url"`
+	// Autorité supplémentaire pour joindre le serveur ACME (certificat de Pebble)
+	CAFile string `yaml:"ca_file"`
+}
+
+// HSTS est envoyé sur les réponses HTTPS ; max_age à 0 le désactive
+type HSTS struct {
+	MaxAge            time.Duration `yaml:"max_age"`
+	IncludeSubdomains bool          `yaml:"include_subdomains"`
+}
+
+type Database struct {
+	Driver string `yaml:"driver"`
+	// Chemin du fichier SQLite ou URL postgres://, peut contenir un mot de passe
+	URL string `yaml:"url"`
+}
+
+type Cookies struct {
+	Secure bool `yaml:"secure"`
+}
+
+type Sessions struct {
+	// Durée de validité d'une session à partir de la connexion
+	TTL time.Duration `yaml:"ttl"`
+}
+
+type Proxy struct {
+	BaseDomain string `yaml:"base_domain"`
+	// URL publique de l'API : son hôte n'est jamais relayé, et les serveurs réservés aux
+	// utilisateurs connectés y renvoient leurs visiteurs pour ouvrir une session
+	APIURL string `yaml:"api_url"`
+	// /apps/<name>/ sur l'origine de l'API, servi avec Content-Security-Policy: sandbox
+	PathMode bool `yaml:"path_mode"`
+}
+
+type Languages struct {
+	File string `yaml:"file"`
+	// Image Docker à utiliser à la place de celle du fichier, par langage
+	Images map[string]string `yaml:"images"`
+}
+
+type Artifacts struct {
+	Retention time.Duration `yaml:"retention"`
+}
+
+type Logs struct {
+	Retention     time.Duration `yaml:"retention"`
+	KeepPerScript int           `yaml:"keep_per_script"`
+}
+
+type Executions struct {
+	// Durée max d'une exécution, build compris ; le conteneur est arrêté au-delà
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// LegacyRoutes garde les routes sans /api/v1 comme alias dépréciés
+type LegacyRoutes struct {
+	Enabled bool `yaml:"enabled"`
+	// Date de retrait annoncée dans l'en-tête Sunset
+	Sunset time.Time `yaml:"sunset"`
+}
+
+// Default retourne les valeurs par défaut, celles de handlers.DefaultConfig pour les réglages de l'API
+func Default() Config {
+	return Config{
+		Listen:          ":8000",
+		TLS:             TLS{HSTS: HSTS{MaxAge: 365 * 24 * time.Hour}},
+		DataDir:         "data",
+		ShutdownTimeout: 30 * time.Second,
+		Database:        Database{Driver: database.SQLite},
+		Sessions:        Sessions{TTL: 30 * 24 * time.Hour},
+		Languages:       Languages{File: "languages.json", Images: map[string]string{}},
+		Artifacts:       Artifacts{Retention: 7 * 24 * time.Hour},
+		Logs:            Logs{Retention: 30 * 24 * time.Hour, KeepPerScript: 100},
+		Executions:      Executions{Timeout: 15 * time.Minute},
+		LegacyRoutes:    LegacyRoutes{Enabled: true, Sunset: time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)},
+	}
+}
+
+// Load construit la configuration effective à partir des arguments de la ligne de commande
+// et retourne les arguments restants (sous-commande).
+// Le fichier est donné par --config ou CONFIG_FILE, sinon config.yaml est lu s'il existe.
+func Load(args []string) (Config, []string, error) {
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "configuration file (YAML)")
+	listen := fs.String("listen", "", "listen address")
+	dataDir := fs.String("data-dir", "", "data directory")
+	driver := fs.String("database-driver", "", "sqlite or postgres")
+	dsn := fs.String("database-url", "", "SQLite file or postgres:// URL")
+	languagesFile := fs.String("languages", "", "languages file")
+	baseDomain := fs.String("proxy-base-domain", "", "domain the servers are exposed under")
+	apiURL := fs.String("proxy-api-url", "", "public URL of the API, never proxied")
+	secure := fs.Bool("cookie-secure", false, "send the session cookie over HTTPS only (always on with TLS)")
+	certFile := fs.String("tls-cert", "", "TLS certificate file (PEM)")
+	keyFile := fs.String("tls-key", "", "TLS private key file (PEM)")
+	acmeDomains := fs.String("acme-domains", "", "comma separated domains to get ACME certificates for")
+	redirectListen := fs.String("tls-redirect-listen", "", "plain HTTP address redirecting to HTTPS")
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	cfg := Default()
+	path, explicit := *file, *file != ""
+	if !explicit {
+		path = DefaultFile
+	}
+	if err := cfg.readFile(path, explicit); err != nil {
+		return Config{}, nil, err
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return Config{}, nil, err
+	}
+
+	// Seuls les flags donnés remplacent les valeurs précédentes
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			cfg.Listen = *listen
+		case "data-dir":
+			cfg.DataDir = *dataDir
+		case "database-driver":
+			cfg.Database.Driver = *driver
+		case "database-url":
+			cfg.Database.URL = *dsn
+		case "languages":
+			cfg.Languages.File = *languagesFile
+		case "proxy-base-domain":
+			cfg.Proxy.BaseDomain = *baseDomain
+		case "proxy-api-url":
+			cfg.Proxy.APIURL = *apiURL
+		case "cookie-secure":
+			cfg.Cookies.Secure = *secure
+		case "tls-cert":
+			cfg.TLS.CertFile = *certFile
+		case "tls-key":
+			cfg.TLS.KeyFile = *keyFile
+		case "acme-domains":
+			cfg.TLS.ACME.Domains = splitList(*acmeDomains)
+		case "tls-redirect-listen":
+			cfg.TLS.RedirectListen = *redirectListen
+		}
+	})
+
+	if cfg.Database.Driver == database.SQLite && cfg.Database.URL == "" {
+		cfg.Database.URL = "app.db"
+	}
+	if cfg.TLS.ACME.CacheDir == "" {
+		cfg.TLS.ACME.CacheDir = filepath.Join(cfg.DataDir, "acme")
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+// readFile lit le fichier YAML ; un fichier absent n'est une erreur que s'il a été demandé
+func (c *Config) readFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv applique les variables d'environnement définies.
+// Une variable vide est ignorée comme une variable absente, quel que soit son type.
+func (c *Config) applyEnv() error {
+	strs := map[string]*string{
+		"LISTEN_ADDR":       &c.Listen,
+		"DATA_DIR":          &c.DataDir,
+		"DATABASE_DRIVER":   &c.Database.Driver,
+		"DATABASE_URL":      &c.Database.URL,
+		"LANGUAGES_FILE":    &c.Languages.File,
+		"PROXY_BASE_DOMAIN": &c.Proxy.BaseDomain,
+		"PROXY_API_URL":     &c.Proxy.APIURL,
+
+		"TLS_CERT_FILE":       &c.TLS.CertFile,
+		"TLS_KEY_FILE":        &c.TLS.KeyFile,
+		"TLS_REDIRECT_LISTEN": &c.TLS.RedirectListen,
+		"ACME_EMAIL":          &c.TLS.ACME.Email,
+		"ACME_CACHE_DIR":      &c.TLS.ACME.CacheDir,
+		"ACME_DIRECTORY_URL":  &c.TLS.ACME.DirectoryURL,
+		"ACME_CA_FILE":        &c.TLS.ACME.CAFile,
+	}
+	for name, field := range strs {
+		if v := os.Getenv(name); v != "" {
+			*field = v
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"SHUTDOWN_TIMEOUT":   &c.ShutdownTimeout,
+		"SESSION_TTL":        &c.Sessions.TTL,
+		"ARTIFACT_RETENTION": &c.Artifacts.Retention,
+		"LOG_RETENTION":      &c.Logs.Retention,
+		"EXECUTION_TIMEOUT":  &c.Executions.Timeout,
+		"HSTS_MAX_AGE":       &c.TLS.HSTS.MaxAge,
+	}
+	for name, field := range durations {
+		if v := os.Getenv(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s: invalid duration %q", name, v)
+			}
+			*field = d
+		}
+	}
+
+	if v := os.Getenv("LOG_KEEP_PER_SCRIPT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("LOG_KEEP_PER_SCRIPT: invalid number %q", v)
+		}
+		c.Logs.KeepPerScript = n
+	}
+	if v := os.Getenv("ACME_DOMAINS"); v != "" {
+		c.TLS.ACME.Domains = splitList(v)
+	}
+	if v := os.Getenv("LEGACY_ROUTES"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("LEGACY_ROUTES: invalid boolean %q", v)
+		}
+		c.LegacyRoutes.Enabled = b
+	}
+	if v := os.Getenv("LEGACY_ROUTES_SUNSET"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return fmt.Errorf("LEGACY_ROUTES_SUNSET: expected a YYYY-MM-DD date, got %q", v)
+		}
+		c.LegacyRoutes.Sunset = t
+	}
+	if v := os.Getenv("PROXY_PATH_MODE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("PROXY_PATH_MODE: invalid boolean %q", v)
+		}
+		c.Proxy.PathMode = b
+	}
+	if v := os.Getenv("COOKIE_SECURE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("COOKIE_SECURE: invalid boolean %q", v)
+		}
+		c.Cookies.Secure = b
+	}
+
+	// LANGUAGE_IMAGES=python=python:3.12-alpine,go=golang:1.23-alpine
+	if v := os.Getenv("LANGUAGE_IMAGES"); v != "" {
+		if c.Languages.Images == nil {
+			c.Languages.Images = map[string]string{}
+		}
+		for _, pair := range strings.Split(v, ",") {
+			name, image, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				return fmt.Errorf("LANGUAGE_IMAGES: expected language=image, got %q", pair)
+			}
+			c.Languages.Images[name] = image
+		}
+	}
+	return nil
+}
+
+// splitList découpe une liste séparée par des virgules
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Validate vérifie toute la configuration et retourne toutes les erreurs trouvées
+func (c Config) Validate() error {
+	var errs []error
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		errs = append(errs, fmt.Errorf("listen: invalid address %q", c.Listen))
+	}
+	errs = append(errs, c.TLS.validate()...)
+	if c.DataDir == "" {
+		errs = append(errs, errors.New("data_dir is required"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout must be positive"))
+	}
+	switch c.Database.Driver {
+	case database.SQLite, database.Postgres:
+		if c.Database.URL == "" {
+			errs = append(errs, fmt.Errorf("database.url is required for %s", c.Database.Driver))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("database.driver: unknown driver %q (supported: %s, %s)", c.Database.Driver, database.SQLite, database.Postgres))
+	}
+	if c.Languages.File == "" {
+		errs = append(errs, errors.New("languages.file is required"))
+	}
+	for name, image := range c.Languages.Images {
+		if image == "" {
+			errs = append(errs, fmt.Errorf("languages.images.%s: image is required", name))
+		}
+	}
+	if c.Sessions.TTL <= 0 {
+		errs = append(errs, errors.New("sessions.ttl must be positive"))
+	}
+	if c.Artifacts.Retention <= 0 {
+		errs = append(errs, errors.New("artifacts.retention must be positive"))
+	}
+	if c.Logs.Retention <= 0 {
+		errs = append(errs, errors.New("logs.retention must be positive"))
+	}
+	if c.Logs.KeepPerScript < 1 {
+		errs = append(errs, errors.New("logs.keep_per_script must be at least 1"))
+	}
+	if c.Executions.Timeout <= 0 {
+		errs = append(errs, errors.New("executions.timeout must be positive"))
+	}
+	if c.Proxy.APIURL != "" {
+		u, err := url.Parse(c.Proxy.APIURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || strings.Trim(u.Path, "/") != "" {
+			errs = append(errs, fmt.Errorf("proxy.api_url: expected an http(s):// URL without path, got %q", c.Proxy.APIURL))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t TLS) validate() []error {
+	var errs []error
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, errors.New("tls.cert_file and tls.key_file must be set together"))
+	}
+	if t.CertFile != "" && len(t.ACME.Domains) > 0 {
+		errs = append(errs, errors.New("tls: use either cert_file/key_file or acme.domains, not both"))
+	}
+	for _, domain := range t.ACME.Domains {
+		// Pas de joker : il faudrait un challenge DNS-01
+		if strings.ContainsAny(domain, "/:* ") {
+			errs = append(errs, fmt.Errorf("tls.acme.domains: invalid domain %q", domain))
+		}
+	}
+	if t.ACME.DirectoryURL != "" {
+		if u, err := url.Parse(t.ACME.DirectoryURL); err != nil || u.Scheme != "https" {
+			errs = append(errs, fmt.Errorf("tls.acme.directory_url: expected an https:// URL, got %q", t.ACME.DirectoryURL))
+		}
+	}
+	if t.RedirectListen != "" {
+		if !t.Enabled() {
+			errs = append(errs, errors.New("tls.redirect_listen requires a certificate or acme.domains"))
+		} else if _, _, err := net.SplitHostPort(t.RedirectListen); err != nil {
+			errs = append(errs, fmt.Errorf("tls.redirect_listen: invalid address %q", t.RedirectListen))
+		}
+	}
+	if t.HSTS.MaxAge < 0 {
+		errs = append(errs, errors.New("tls.hsts.max_age must not be negative"))
+	}
+	return errs
+}
+
+// SecureCookies indique si le cookie de session n'est envoyé qu'en HTTPS (toujours avec TLS)
+func (c Config) SecureCookies() bool {
+	return c.Cookies.Secure || c.TLS.Enabled()
+}
+
+// password=... dans un DSN PostgreSQL clé=valeur
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('[^']*'|\S+)`)
+
+// Redacted retourne une copie sans les secrets, pour l'affichage
+func (c Config) Redacted() Config {
+	if u, err := url.Parse(c.Database.URL); err == nil && u.User != nil {
+		c.Database.URL = u.Redacted()
+	} else {
+		c.Database.URL = dsnPassword.ReplaceAllString(c.Database.URL, "${1}xxxxx")
+	}
+	return c
+}
+
+// Print écrit la configuration effective en YAML, secrets masqués
+func (c Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/handlers"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+listen: ":9000"
+data_dir: /srv/data
+logs:
+  retention: 48h
+`)
+	t.Setenv("DATA_DIR", "/env/data")
+	t.Setenv("LANGUAGE_IMAGES", "python=python:3.13-alpine")
+	t.Setenv("SESSION_TTL", "12h")
+
+	cfg, args, err := Load([]string{"-config", path, "-listen", ":9100", "migrate", "status"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != ":9100" {
+		t.Errorf("listen = %q, want the flag", cfg.Listen)
+	}
+	if cfg.DataDir != "/env/data" {
+		t.Errorf("data_dir = %q, want the environment", cfg.DataDir)
+	}
+	if cfg.Logs.Retention != 48*time.Hour {
+		t.Errorf("logs.retention = %v, want the file", cfg.Logs.Retention)
+	}
+	if cfg.Sessions.TTL != 12*time.Hour {
+		t.Errorf("sessions.ttl = %v, want the environment", cfg.Sessions.TTL)
+	}
+	if cfg.Database.URL != "app.db" || cfg.Languages.Images["python"] != "python:3.13-alpine" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if strings.Join(args, " ") != "migrate status" {
+		t.Errorf("args = %q", args)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, _, err := Load([]string{"-config", writeFile(t, "unknown: 1\n")}); err == nil {
+		t.Error("unknown key accepted")
+	}
+	if _, _, err := Load([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Error("missing explicit file accepted")
+	}
+
+	_, _, err := Load([]string{"-config", writeFile(t, "listen: nope\ndatabase:\n  driver: mysql\nsessions:\n  ttl: 0s\n")})
+	if err == nil {
+		t.Fatal("invalid config accepted")
+	}
+	for _, want := range []string{"listen", "database.driver", "sessions.ttl"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	for _, url := range []string{
+		"postgres://app:hunter2@db:5432/app?sslmode=disable",
+		"host=db user=app password=hunter2 dbname=app",
+	} {
+		cfg := Default()
+		cfg.Database.URL = url
+		var b strings.Builder
+		if err := cfg.Print(&b); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(b.String(), "hunter2") {
+			t.Errorf("password printed:\n%s", b.String())
+		}
+		if cfg.Database.URL != url {
+			t.Error("Print modified the configuration")
+		}
+	}
+}
+
+func TestTLS(t *testing.T) {
+	cfg, _, err := Load([]string{"-config", writeFile(t, "{}\n"), "-tls-cert", "cert.pem", "-tls-key", "key.pem", "-tls-redirect-listen", ":80"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.TLS.Enabled() || !cfg.SecureCookies() {
+		t.Fatalf("TLS on: enabled %v, secure cookies %v", cfg.TLS.Enabled(), cfg.SecureCookies())
+	}
+
+	t.Setenv("ACME_DOMAINS", "api.example.com, app1.example.com")
+	cfg, _, err = Load([]string{"-config", writeFile(t, "{}\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.TLS.ACME.Domains) != 2 || cfg.TLS.ACME.CacheDir != filepath.Join("data", "acme") {
+		t.Fatalf("acme = %+v", cfg.TLS.ACME)
+	}
+
+	t.Setenv("ACME_DOMAINS", "")
+	for _, file := range []string{
+		"tls:\n  cert_file: cert.pem\n",
+		"tls:\n  cert_file: cert.pem\n  key_file: key.pem\n  acme:\n    domains: [api.example.com]\n",
+		"tls:\n  acme:\n    domains: [api.example.com]\n    directory_url: http://localhost:14000/dir\n",
+		"tls:\n  acme:\n    domains: [\"*.example.com\"]\n",
+	} {
+		if _, _, err := Load([]string{"-config", writeFile(t, file)}); err == nil {
+			t.Errorf("accepted:\n%s", file)
+		}
+	}
+	if _, _, err := Load([]string{"-config", writeFile(t, "tls:\n  redirect_listen: \":80\"\n")}); err == nil {
+		t.Error("redirect without TLS accepted")
+	}
+}
+
+func TestLegacyRoutes(t *testing.T) {
+	cfg, _, err := Load([]string{"-config", writeFile(t, "legacy_routes:\n  sunset: 2027-01-01T00:00:00Z\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.LegacyRoutes.Enabled || !cfg.LegacyRoutes.Sunset.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected legacy routes %+v", cfg.LegacyRoutes)
+	}
+
+	t.Setenv("LEGACY_ROUTES", "false")
+	t.Setenv("LEGACY_ROUTES_SUNSET", "2027-02-01")
+	if cfg, _, err = Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LegacyRoutes.Enabled || cfg.LegacyRoutes.Sunset.Format(time.DateOnly) != "2027-02-01" {
+		t.Errorf("environment ignored: %+v", cfg.LegacyRoutes)
+	}
+
+	t.Setenv("LEGACY_ROUTES_SUNSET", "soon")
+	if _, _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "LEGACY_ROUTES_SUNSET") {
+		t.Errorf("invalid sunset accepted: %v", err)
+	}
+}
+
+func TestExecutionTimeout(t *testing.T) {
+	cfg, _, err := Load([]string{"-config", writeFile(t, "executions:\n  timeout: 30m\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Executions.Timeout; got != 30*time.Minute {
+		t.Errorf("execution timeout = %v, want the file", got)
+	}
+
+	t.Setenv("EXECUTION_TIMEOUT", "0s")
+	if _, _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "executions.timeout") {
+		t.Errorf("zero timeout accepted: %v", err)
+	}
+}
+
+func TestEmptyEnvIgnored(t *testing.T) {
+	path := writeFile(t, `
+listen: ":9000"
+sessions:
+  ttl: 2h
+logs:
+  keep_per_script: 5
+`)
+	for _, name := range []string{"LISTEN_ADDR", "SESSION_TTL", "LOG_KEEP_PER_SCRIPT", "LEGACY_ROUTES"} {
+		t.Setenv(name, "")
+	}
+	cfg, _, err := Load([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != ":9000" || cfg.Sessions.TTL != 2*time.Hour || cfg.Logs.KeepPerScript != 5 || !cfg.LegacyRoutes.Enabled {
+		t.Errorf("empty variables overrode the file: %+v", cfg)
+	}
+}
+
+func TestDefaultMatchesHandlers(t *testing.T) {
+	d, h := Default(), handlers.DefaultConfig()
+	if d.DataDir != h.DataDir || d.Sessions.TTL != h.SessionTTL || d.Artifacts.Retention != h.ArtifactRetention ||
+		d.Logs.Retention != h.LogRetention || d.Logs.KeepPerScript != h.LogKeepPerScript ||
+		d.Executions.Timeout != h.ExecutionTimeout || d.LegacyRoutes.Enabled != h.LegacyRoutes ||
+		!d.LegacyRoutes.Sunset.Equal(h.LegacySunset) {
+		t.Errorf("defaults diverge: config %+v, handlers %+v", d, h)
+	}
+}
@@ -0,0 +1,211 @@
+package cron
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, expr string) *Schedule {
+	t.Helper()
+	s, err := Parse(expr)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", expr, err)
+	}
+	return s
+}
+
+// bits liste les valeurs d'un bitset
+func bits(set uint64) []int {
+	var values []int
+	for v := 0; v < 64; v++ {
+		if set&(1<<uint(v)) != 0 {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func TestParseFields(t *testing.T) {
+	for _, tc := range []struct {
+		f    field
+		spec string
+		want []int
+	}{
+		{minuteField, "*/15", []int{0, 15, 30, 45}},
+		{minuteField, "5", []int{5}},
+		{minuteField, "5/20", []int{5, 25, 45}},
+		{minuteField, "1,2,58-59", []int{1, 2, 58, 59}},
+		{hourField, "8-18/5", []int{8, 13, 18}},
+		{domField, "*/10", []int{1, 11, 21, 31}},
+		{monthField, "jan,MAR,jun-aug", []int{1, 3, 6, 7, 8}},
+		{dowField, "mon-fri", []int{1, 2, 3, 4, 5}},
+		{dowField, "sat,sun", []int{0, 6}},
+	} {
+		got, err := tc.f.parse(tc.spec)
+		if err != nil {
+			t.Errorf("%s %q: %v", tc.f.name, tc.spec, err)
+			continue
+		}
+		if g := bits(got); !reflect.DeepEqual(g, tc.want) {
+			t.Errorf("%s %q = %v, want %v", tc.f.name, tc.spec, g, tc.want)
+		}
+	}
+
+	// 7 est dimanche
+	s := mustParse(t, "0 0 * * 7")
+	if bits(s.dow)[0] != 0 {
+		t.Errorf("dow 7 = %v, want sunday (0)", bits(s.dow))
+	}
+}
+
+func TestParseMacros(t *testing.T) {
+	for macro, expr := range macros {
+		got, want := mustParse(t, strings.ToUpper(macro)), mustParse(t, expr)
+		if *got != *want {
+			t.Errorf("%s = %+v, want %+v", macro, got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct{ expr, want string }{
+		{"", "5 fields"},
+		{"* * * *", "5 fields"},
+		{"* * * * * *", "5 fields"},
+		{"@reboot", "5 fields"},
+		{"60 * * * *", "minute"},
+		{"* 24 * * *", "hour"},
+		{"* * 0 * *", "day of month"},
+		{"* * 32 * *", "day of month"},
+		{"* * * 13 *", "month"},
+		{"* * * foo *", "month"},
+		{"* * * * 8", "day of week"},
+		{"*/0 * * * *", "invalid step"},
+		{"*/x * * * *", "invalid step"},
+		{"10-5 * * * *", "invalid range"},
+		{"5- * * * *", "minute"},
+		{"1,,2 * * * *", "minute"},
+	} {
+		_, err := Parse(tc.expr)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Parse(%q) = %v, want an error about %q", tc.expr, err, tc.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Jeudi 1er janvier 2026, 10:07 UTC
+	from := time.Date(2026, time.January, 1, 10, 7, 30, 0, time.UTC)
+	for _, tc := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 1, 1, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 1, 1, 10, 15, 0, 0, time.UTC)},
+		{"7 10 * * *", time.Date(2026, 1, 2, 10, 7, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * sat", time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2,4 *", time.Time{}},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Jour du mois OU jour de la semaine quand les deux sont restreints
+		{"0 12 15 * mon", time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)},
+		{"0 12 2 * mon", time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)},
+		// Un seul champ restreint : l'autre "*" (même avec un pas) ne relâche pas la condition
+		{"0 12 15 * *", time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * mon", time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)},
+		{"0 12 */2 * mon", time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)},
+	} {
+		if got := mustParse(t, tc.expr).Next(from); !got.Equal(tc.want) {
+			t.Errorf("Next(%q) = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestNextTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	from := time.Date(2026, time.January, 1, 10, 0, 0, 0, time.UTC).In(tokyo) // 19:00 à Tokyo
+	got := mustParse(t, "0 9 * * *").Next(from)
+	if want := time.Date(2026, 1, 2, 9, 0, 0, 0, tokyo); !got.Equal(want) || got.Location() != tokyo {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	utc := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	for _, tc := range []struct {
+		name string
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{
+			// 29 mars : 02:00 CET -> 03:00 CEST, 02:30 n'existe pas et a lieu juste après le saut
+			name: "gap daily",
+			expr: "30 2 * * *",
+			from: utc(time.March, 28, 12, 0),
+			want: []time.Time{utc(time.March, 29, 1, 30), utc(time.March, 30, 0, 30)},
+		},
+		{
+			// Les occurrences de l'heure sautée ne font pas doublon avec celles d'après
+			name: "gap every 20 minutes",
+			expr: "*/20 * * * *",
+			from: utc(time.March, 29, 0, 45), // 01:45 CET
+			want: []time.Time{utc(time.March, 29, 1, 0), utc(time.March, 29, 1, 20), utc(time.March, 29, 1, 40)},
+		},
+		{
+			// 25 octobre : 03:00 CEST -> 02:00 CET, 02:30 n'a lieu qu'une fois
+			name: "overlap daily",
+			expr: "30 2 * * *",
+			from: utc(time.October, 24, 12, 0),
+			want: []time.Time{utc(time.October, 25, 1, 30), utc(time.October, 26, 1, 30)},
+		},
+		{
+			name: "overlap daily from the first 02:30",
+			expr: "30 2 * * *",
+			from: utc(time.October, 25, 0, 30), // 02:30 CEST
+			want: []time.Time{utc(time.October, 26, 1, 30)},
+		},
+		{
+			name: "overlap hourly",
+			expr: "0 * * * *",
+			from: utc(time.October, 24, 23, 30), // 01:30 CEST
+			want: []time.Time{utc(time.October, 25, 1, 0), utc(time.October, 25, 2, 0)},
+		},
+	} {
+		got := mustParse(t, tc.expr).Next(tc.from.In(paris))
+		for i, want := range tc.want {
+			if !got.Equal(want) {
+				t.Errorf("%s: occurrence %d = %v, want %v", tc.name, i, got.UTC(), want)
+				break
+			}
+			if !got.After(tc.from) {
+				t.Errorf("%s: occurrence %v is not after %v", tc.name, got, tc.from)
+			}
+			got = mustParse(t, tc.expr).Next(got)
+		}
+	}
+
+	// Depuis n'importe quelle minute du 25 octobre, Next avance toujours
+	s := mustParse(t, "*/5 * * * *")
+	for from := utc(time.October, 24, 22, 0); from.Before(utc(time.October, 25, 3, 0)); from = from.Add(time.Minute) {
+		next := s.Next(from.In(paris))
+		if !next.After(from) || next.Sub(from) > time.Hour+5*time.Minute {
+			t.Fatalf("Next(%v) = %v", from.In(paris), next)
+		}
+	}
+}
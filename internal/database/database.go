@@ -2,47 +2,96 @@ package database
 
 import (
 	"database/sql"
-	"log"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func Open(path string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", path)
+// Bases supportées (DATABASE_DRIVER)
+const (
+	SQLite   = "sqlite"
+	Postgres = "postgres"
+)
+
+// Base SQLite en mémoire, propre à chaque appel de Open
+const Memory = ":memory:"
+
+// Numérote les bases en mémoire pour qu'elles restent isolées les unes des autres
+var memorySeq atomic.Int64
+
+// Open ouvre la base du driver demandé : un chemin de fichier (ou Memory) pour SQLite,
+// une URL postgres://... pour PostgreSQL.
+// Pour SQLite les clés étrangères sont activées par le DSN, pour chaque connexion du pool.
+func Open(driver, dsn string) (*sql.DB, error) {
+	var db *sql.DB
+	var err error
+	switch driver {
+	case SQLite:
+		if dsn, err = sqliteDSN(dsn); err != nil {
+			return nil, err
+		}
+		db, err = sql.Open("sqlite3", dsn)
+	case Postgres:
+		db, err = sql.Open("postgres", dsn)
+	default:
+		return nil, fmt.Errorf("%w: %s (supported: %s, %s)", ErrUnknownDriver, driver, SQLite, Postgres)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	// Vérifie que la DB répond
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, err
 	}
 
 	return db, nil
 }
 
-func Init(db *sql.DB) error {
-	queries := []string{
-		`PRAGMA foreign_keys = ON;`,
-		
-		`CREATE TABLE IF NOT EXISTS users (
-			id INTEGER NOT NULL UNIQUE PRIMARY KEY AUTOINCREMENT,
-			username TEXT NOT NULL UNIQUE,
-			password TEXT NOT NULL
-		);`,
-
-		`CREATE TABLE IF NOT EXISTS sessions (
-			token TEXT PRIMARY KEY,
-			user_id INTEGER NOT NULL REFERENCES users(id)
-		);`,
+// sqliteDSN construit l'URI file: de SQLite en gardant les paramètres du DSN
+// (file.db?_busy_timeout=5000) et en forçant les clés étrangères
+func sqliteDSN(dsn string) (string, error) {
+	var params url.Values
+	if dsn == Memory {
+		// Cache partagé : toutes les connexions du pool voient la même base
+		dsn = fmt.Sprintf("memdb%d", memorySeq.Add(1))
+		params = url.Values{"mode": {"memory"}, "cache": {"shared"}}
+	} else {
+		dsn = strings.TrimPrefix(dsn, "file:")
+		path, query, _ := strings.Cut(dsn, "?")
+		dsn = path
+		var err error
+		if params, err = url.ParseQuery(query); err != nil {
+			return "", fmt.Errorf("invalid SQLite DSN parameters %q: %w", query, err)
+		}
+		// _fk est l'alias de _foreign_keys côté go-sqlite3
+		params.Del("_fk")
 	}
+	params.Set("_foreign_keys", "on")
+	return "file:" + dsn + "?" + params.Encode(), nil
+}
 
-	for _, q := range queries {
-		if _, err := db.Exec(q); err != nil {
-			return err
+// Rebind remplace les paramètres ? par $1, $2... pour PostgreSQL.
+// Les requêtes ne doivent pas contenir de ? littéral.
+func Rebind(driver, query string) string {
+	if driver != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
 		}
+		b.WriteRune(c)
 	}
-
-	log.Println("Database initialized")
-	return nil
+	return b.String()
 }
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenSQLiteParams(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		dsn         string
+		busyTimeout int
+	}{
+		{filepath.Join(dir, "plain.db"), 5000},
+		{filepath.Join(dir, "app.db") + "?_busy_timeout=1234", 1234},
+		{"file:" + filepath.Join(dir, "uri.db") + "?_busy_timeout=4321&_fk=off", 4321},
+		{Memory, 5000},
+	} {
+		db, err := Open(SQLite, tc.dsn)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.dsn, err)
+		}
+		var fk, timeout int
+		if err := db.QueryRow(`PRAGMA foreign_keys`).Scan(&fk); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.QueryRow(`PRAGMA busy_timeout`).Scan(&timeout); err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+		if fk != 1 || timeout != tc.busyTimeout {
+			t.Errorf("%s: foreign_keys = %d, busy_timeout = %d, want 1 and %d", tc.dsn, fk, timeout, tc.busyTimeout)
+		}
+	}
+
+	if _, err := Open(SQLite, filepath.Join(dir, "bad.db")+"?_busy_timeout=%zz"); err == nil {
+		t.Error("malformed DSN parameters accepted")
+	}
+}
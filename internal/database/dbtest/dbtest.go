@@ -0,0 +1,146 @@
+// Package dbtest fournit des bases PostgreSQL jetables aux tests.
+//
+// POSTGRES_TEST_URL (URL d'un utilisateur pouvant créer des bases), sinon une instance
+// locale démarrée avec initdb/pg_ctl (POSTGRES_BIN ou PATH). Sans l'un ni l'autre,
+// les tests PostgreSQL sont ignorés.
+package dbtest
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+var (
+	postgresOnce sync.Once
+	postgresURL  string
+	postgresErr  error
+	postgresStop func()
+	databaseSeq  atomic.Int64
+)
+
+// Available démarre l'instance au premier appel ; erreur si PostgreSQL est indisponible
+func Available() error {
+	postgresOnce.Do(func() {
+		postgresURL = os.Getenv("POSTGRES_TEST_URL")
+		if postgresURL == "" {
+			postgresURL, postgresStop, postgresErr = startPostgres()
+		}
+	})
+	return postgresErr
+}
+
+// Stop arrête l'instance démarrée par le paquet, à appeler depuis TestMain
+func Stop() {
+	if postgresStop != nil {
+		postgresStop()
+	}
+}
+
+// Postgres crée une base vide pour le test et retourne son URL ; le test est ignoré sans PostgreSQL
+func Postgres(t testing.TB) string {
+	t.Helper()
+	if err := Available(); err != nil {
+		t.Skipf("postgres unavailable: %v", err)
+	}
+
+	admin, err := sql.Open("postgres", postgresURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+
+	name := fmt.Sprintf("goapi_test_%d_%d", os.Getpid(), databaseSeq.Add(1))
+	if _, err := admin.Exec(`CREATE DATABASE ` + name); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin, err := sql.Open("postgres", postgresURL)
+		if err != nil {
+			return
+		}
+		defer admin.Close()
+		admin.Exec(`DROP DATABASE IF EXISTS ` + name + ` WITH (FORCE)`)
+	})
+
+	u, err := withDatabase(postgresURL, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// startPostgres initialise et démarre une instance jetable dans un dossier temporaire
+func startPostgres() (string, func(), error) {
+	initdb, err := postgresBinary("initdb")
+	if err != nil {
+		return "", nil, err
+	}
+	pgCtl, err := postgresBinary("pg_ctl")
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "goapi-postgres-")
+	if err != nil {
+		return "", nil, err
+	}
+	data := filepath.Join(dir, "data")
+	if out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("initdb: %v: %s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off", port, dir)
+	cmd := exec.Command(pgCtl, "-D", data, "-o", options, "-l", filepath.Join(dir, "postgres.log"), "-w", "start")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("pg_ctl start: %v: %s", err, out)
+	}
+
+	stop := func() {
+		exec.Command(pgCtl, "-D", data, "-m", "immediate", "stop").Run()
+		os.RemoveAll(dir)
+	}
+	return fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port), stop, nil
+}
+
+// withDatabase remplace la base de l'URL postgres://
+func withDatabase(rawURL, name string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u.Path = "/" + name
+	return u.String(), nil
+}
+
+func postgresBinary(name string) (string, error) {
+	if dir := os.Getenv("POSTGRES_BIN"); dir != "" {
+		return filepath.Join(dir, name), nil
+	}
+	return exec.LookPath(name)
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Colonnes ajoutées par ALTER TABLE avant le système de migrations
+var legacyColumns = []struct{ table, column, definition string }{
+	{"users", "is_admin", "INTEGER NOT NULL DEFAULT 0"},
+	{"users", "plan", "TEXT NOT NULL DEFAULT 'default'"},
+	{"executions", "trigger", "TEXT NOT NULL DEFAULT 'manual'"},
+	{"executions", "schedule_id", "TEXT"},
+	{"executions", "trigger_id", "TEXT"},
+	{"executions", "duration_ms", "INTEGER"},
+	{"executions", "peak_memory_bytes", "INTEGER"},
+	{"executions", "cpu_time_ms", "INTEGER"},
+	{"executions", "net_rx_bytes", "INTEGER"},
+	{"executions", "net_tx_bytes", "INTEGER"},
+	{"executions", "block_read_bytes", "INTEGER"},
+	{"executions", "block_write_bytes", "INTEGER"},
+	{"servers", "port", "INTEGER NOT NULL DEFAULT 8080"},
+	{"servers", "access", "TEXT NOT NULL DEFAULT 'public'"},
+	{"servers", "address", "TEXT"},
+	{"servers", "restart_policy", "TEXT NOT NULL DEFAULT 'never'"},
+	{"servers", "max_retries", "INTEGER NOT NULL DEFAULT 3"},
+	{"servers", "restart_count", "INTEGER NOT NULL DEFAULT 0"},
+	{"servers", "health_check", "TEXT"},
+	{"servers", "health_status", "TEXT"},
+	{"servers", "mounts", "TEXT NOT NULL DEFAULT '[]'"},
+	{"scripts", "mounts", "TEXT NOT NULL DEFAULT '[]'"},
+	{"scripts", "last_run_at", "DATETIME"},
+	{"logs", "seq", "INTEGER NOT NULL DEFAULT 0"},
+	{"logs", "chunk", "INTEGER NOT NULL DEFAULT 0"},
+	{"logs", "size", "INTEGER NOT NULL DEFAULT 0"},
+	{"logs", "compressed", "INTEGER NOT NULL DEFAULT 0"},
+	{"logs", "data", "BLOB"},
+}
+
+// baselineLegacy amène une base créée par l'ancien initDB au schéma de la migration 1 :
+// colonnes manquantes, tables et index manquants, valeurs calculées, puis version 1 enregistrée.
+func baselineLegacy(db *sql.DB) error {
+	for _, c := range legacyColumns {
+		if err := addColumn(db, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	migrations, err := Migrations(SQLite)
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 || migrations[0].Version != 1 {
+		return fmt.Errorf("migration 1 not found")
+	}
+	initial := migrations[0]
+
+	return apply(db, initial.Up+`
+		-- Scripts lancés avant l'ajout de last_run_at
+		UPDATE scripts SET last_run_at = (SELECT MAX(started_at) FROM executions e WHERE e.script_id = scripts.id)
+		WHERE last_run_at IS NULL;
+		-- Logs stockés avant leur découpage
+		UPDATE logs SET size = LENGTH(CAST(content AS BLOB)) WHERE size = 0 AND compressed = 0;`,
+		`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, initial.Version, initial.Name,
+	)
+}
+
+// addColumn ajoute une colonne si la table existe et ne l'a pas encore
+func addColumn(db *sql.DB, table, column, definition string) error {
+	var tables, count int
+	db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&tables)
+	if tables == 0 {
+		return nil
+	}
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed reading %s columns: %w", table, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed adding column %s.%s: %w", table, column, err)
+	}
+	fmt.Printf("Column '%s.%s' added succesfully\n", table, column)
+	return nil
+}
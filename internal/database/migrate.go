@@ -0,0 +1,281 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// Migrations numérotées par base : migrations/<driver>/<version>_<nom>.up.sql et .down.sql.
+// SQLite et PostgreSQL ont les mêmes versions.
+//
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+var migrationPattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var (
+	ErrUnknownVersion = errors.New("unknown migration version")
+	ErrUnknownDriver  = errors.New("unknown database driver")
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus indique si une migration est appliquée, et quand
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt string // vide si non appliquée
+}
+
+// Migrations retourne les migrations embarquées pour le driver, triées par version
+func Migrations(driver string) ([]Migration, error) {
+	dir := "migrations/" + driver
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDriver, driver)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		m := migrationPattern.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		content, err := fs.ReadFile(migrationFiles, dir+"/"+e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(content)
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+	return migrations, nil
+}
+
+// Table de suivi des versions appliquées
+var versionTables = map[string]string{
+	SQLite: `
+		CREATE TABLE schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+	Postgres: `
+		CREATE TABLE schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+		);`,
+}
+
+// ensureVersionTable crée la table de suivi. Une base SQLite créée avant les migrations
+// (tables présentes sans schema_migrations) est d'abord mise à niveau puis marquée en version 1.
+func ensureVersionTable(db *sql.DB, driver string) error {
+	exists, err := tableExists(db, driver, "schema_migrations")
+	if err != nil || exists {
+		return err
+	}
+	ddl, ok := versionTables[driver]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownDriver, driver)
+	}
+
+	legacy, err := tableExists(db, driver, "users")
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(ddl); err != nil {
+		return err
+	}
+	if legacy && driver == SQLite {
+		return baselineLegacy(db)
+	}
+	return nil
+}
+
+func tableExists(db *sql.DB, driver, table string) (bool, error) {
+	var exists bool
+	var err error
+	switch driver {
+	case SQLite:
+		err = db.QueryRow(`SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&exists)
+	case Postgres:
+		err = db.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, table).Scan(&exists)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownDriver, driver)
+	}
+	return exists, err
+}
+
+// Current retourne la dernière version appliquée (0 pour une base vide)
+func Current(db *sql.DB, driver string) (int, error) {
+	if err := ensureVersionTable(db, driver); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// Status liste les migrations embarquées avec leur date d'application
+func Status(db *sql.DB, driver string) ([]MigrationStatus, error) {
+	migrations, err := Migrations(driver)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureVersionTable(db, driver); err != nil {
+		return nil, err
+	}
+
+	applied := map[int]string{}
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+
+	status := []MigrationStatus{}
+	for _, m := range migrations {
+		status = append(status, MigrationStatus{Version: m.Version, Name: m.Name, AppliedAt: applied[m.Version]})
+	}
+	return status, rows.Err()
+}
+
+// Migrate applique toutes les migrations en attente
+func Migrate(db *sql.DB, driver string) error {
+	migrations, err := Migrations(driver)
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
+		return nil
+	}
+	return MigrateTo(db, driver, migrations[len(migrations)-1].Version)
+}
+
+// Rollback annule les steps dernières migrations appliquées
+func Rollback(db *sql.DB, driver string, steps int) error {
+	current, err := Current(db, driver)
+	if err != nil {
+		return err
+	}
+	migrations, err := Migrations(driver)
+	if err != nil {
+		return err
+	}
+
+	target := 0
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if migrations[i].Version > current {
+			continue
+		}
+		if steps == 0 {
+			target = migrations[i].Version
+			break
+		}
+		steps--
+	}
+	return MigrateTo(db, driver, target)
+}
+
+// MigrateTo monte ou descend jusqu'à la version demandée (0 = base vide).
+// Chaque migration s'exécute dans sa propre transaction.
+func MigrateTo(db *sql.DB, driver string, target int) error {
+	migrations, err := Migrations(driver)
+	if err != nil {
+		return err
+	}
+	if target != 0 && !slices.ContainsFunc(migrations, func(m Migration) bool { return m.Version == target }) {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+	current, err := Current(db, driver)
+	if err != nil {
+		return err
+	}
+
+	if target >= current {
+		for _, m := range migrations {
+			if m.Version <= current || m.Version > target {
+				continue
+			}
+			err := apply(db, m.Up, Rebind(driver, `INSERT INTO schema_migrations (version, name) VALUES (?, ?)`), m.Version, m.Name)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+			}
+			fmt.Printf("Migration '%04d_%s' applied succesfully\n", m.Version, m.Name)
+		}
+		return nil
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version > current || m.Version <= target {
+			continue
+		}
+		if m.Down == "" {
+			return fmt.Errorf("migration %04d_%s can't be reverted: no down file", m.Version, m.Name)
+		}
+		err := apply(db, m.Down, Rebind(driver, `DELETE FROM schema_migrations WHERE version = ?`), m.Version)
+		if err != nil {
+			return fmt.Errorf("reverting migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		fmt.Printf("Migration '%04d_%s' reverted succesfully\n", m.Version, m.Name)
+	}
+	return nil
+}
+
+// apply exécute le script et met à jour schema_migrations dans la même transaction
+func apply(db *sql.DB, script, track string, args ...any) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(track, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
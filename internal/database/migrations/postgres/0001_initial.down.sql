@@ -0,0 +1,16 @@
+-- Les tables enfants d'abord
+DROP TABLE IF EXISTS script_tags;
+DROP TABLE IF EXISTS plans;
+DROP TABLE IF EXISTS artifacts;
+DROP TABLE IF EXISTS volumes;
+DROP TABLE IF EXISTS servers;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS script_triggers;
+DROP TABLE IF EXISTS schedules;
+DROP TABLE IF EXISTS image_allowlist;
+DROP TABLE IF EXISTS logs;
+DROP TABLE IF EXISTS executions;
+DROP TABLE IF EXISTS scripts;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,212 @@
+-- Schéma initial, identique à la version SQLite.
+-- Les dates restent en TEXT (RFC3339 ou 'YYYY-MM-DD HH:MM:SS' comme CURRENT_TIMESTAMP de SQLite)
+-- pour que les deux bases renvoient les mêmes valeurs.
+
+CREATE TABLE IF NOT EXISTS users (
+	id       INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	username TEXT NOT NULL UNIQUE,
+	password TEXT NOT NULL,
+	is_admin INTEGER NOT NULL DEFAULT 0,
+	plan     TEXT NOT NULL DEFAULT 'default'
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	token   TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS scripts (
+	id           TEXT PRIMARY KEY,
+	user_id      INTEGER NOT NULL,
+	name         TEXT NOT NULL,
+	description  TEXT DEFAULT '',
+	language     TEXT NOT NULL,
+	docker_image TEXT NOT NULL,
+	file_path    TEXT NOT NULL,
+	mounts       TEXT NOT NULL DEFAULT '[]',
+	last_run_at  TEXT,
+	created_at   TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS executions (
+	id                TEXT PRIMARY KEY,
+	script_id         TEXT NOT NULL,
+	user_id           INTEGER NOT NULL,
+	status            TEXT NOT NULL DEFAULT 'pending',
+	trigger           TEXT NOT NULL DEFAULT 'manual',
+	schedule_id       TEXT,
+	trigger_id        TEXT,
+	exit_code         INTEGER,
+	started_at        TEXT,
+	finished_at       TEXT,
+	duration_ms       BIGINT,
+	peak_memory_bytes BIGINT,
+	cpu_time_ms       BIGINT,
+	net_rx_bytes      BIGINT,
+	net_tx_bytes      BIGINT,
+	block_read_bytes  BIGINT,
+	block_write_bytes BIGINT,
+	created_at        TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+	FOREIGN KEY(script_id) REFERENCES scripts(id) ON DELETE CASCADE,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Logs découpés en lignes bornées (seq = ordre, chunk > 0 = suite de la même sortie), compressés en gzip dans data
+CREATE TABLE IF NOT EXISTS logs (
+	id           TEXT PRIMARY KEY,
+	execution_id TEXT NOT NULL,
+	stream       TEXT NOT NULL,
+	content      TEXT NOT NULL,
+	seq          INTEGER NOT NULL DEFAULT 0,
+	chunk        INTEGER NOT NULL DEFAULT 0,
+	size         BIGINT NOT NULL DEFAULT 0,
+	compressed   INTEGER NOT NULL DEFAULT 0,
+	data         BYTEA,
+	created_at   TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+	FOREIGN KEY(execution_id) REFERENCES executions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS image_allowlist (
+	id             INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	pattern        TEXT NOT NULL UNIQUE,
+	require_digest INTEGER NOT NULL DEFAULT 0,
+	created_at     TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+);
+
+CREATE TABLE IF NOT EXISTS schedules (
+	id             TEXT PRIMARY KEY,
+	script_id      TEXT NOT NULL,
+	user_id        INTEGER NOT NULL,
+	cron_expr      TEXT NOT NULL,
+	timezone       TEXT NOT NULL DEFAULT 'UTC',
+	enabled        INTEGER NOT NULL DEFAULT 1,
+	overlap_policy TEXT NOT NULL DEFAULT 'skip',
+	next_run_at    TEXT,
+	last_run_at    TEXT,
+	created_at     TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+	FOREIGN KEY(script_id) REFERENCES scripts(id) ON DELETE CASCADE,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS script_triggers (
+	id          TEXT PRIMARY KEY,
+	script_id   TEXT NOT NULL,
+	user_id     INTEGER NOT NULL,
+	auth_mode   TEXT NOT NULL DEFAULT 'token',
+	token_hash  TEXT,
+	hmac_secret TEXT,
+	headers     TEXT NOT NULL DEFAULT '[]',
+	sync        INTEGER NOT NULL DEFAULT 0,
+	created_at  TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+	FOREIGN KEY(script_id) REFERENCES scripts(id) ON DELETE CASCADE,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+	id         TEXT PRIMARY KEY,
+	user_id    INTEGER NOT NULL,
+	url        TEXT NOT NULL,
+	secret     TEXT NOT NULL,
+	events     TEXT NOT NULL DEFAULT '[]',
+	enabled    INTEGER NOT NULL DEFAULT 1,
+	created_at TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id               TEXT PRIMARY KEY,
+	webhook_id       TEXT NOT NULL,
+	event            TEXT NOT NULL,
+	payload          TEXT NOT NULL,
+	status           TEXT NOT NULL DEFAULT 'pending',
+	attempts         INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at  TEXT,
+	last_status_code INTEGER,
+	last_error       TEXT,
+	created_at       TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+	delivered_at     TEXT,
+	FOREIGN KEY(webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS servers (
+	id             TEXT PRIMARY KEY,
+	user_id        INTEGER NOT NULL,
+	name           TEXT NOT NULL UNIQUE,
+	script_id      TEXT NOT NULL,
+	state          INTEGER NOT NULL DEFAULT 0,
+	last_error     TEXT,
+	space          INTEGER NOT NULL,
+	container_id   TEXT,
+	port           INTEGER NOT NULL DEFAULT 8080,
+	access         TEXT NOT NULL DEFAULT 'public',
+	address        TEXT,
+	restart_policy TEXT NOT NULL DEFAULT 'never',
+	max_retries    INTEGER NOT NULL DEFAULT 3,
+	restart_count  INTEGER NOT NULL DEFAULT 0,
+	health_check   TEXT,
+	health_status  TEXT,
+	mounts         TEXT NOT NULL DEFAULT '[]',
+	started_at     TEXT,
+	created_at     TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+	FOREIGN KEY(script_id) REFERENCES scripts(id) ON DELETE CASCADE,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS volumes (
+	id         TEXT PRIMARY KEY,
+	user_id    INTEGER NOT NULL,
+	name       TEXT NOT NULL,
+	space      INTEGER NOT NULL,
+	created_at TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+	UNIQUE(user_id, name),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS artifacts (
+	id           TEXT PRIMARY KEY,
+	execution_id TEXT NOT NULL,
+	user_id      INTEGER NOT NULL,
+	path         TEXT NOT NULL,
+	size         BIGINT NOT NULL,
+	created_at   TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+	expires_at   TEXT NOT NULL,
+	FOREIGN KEY(execution_id) REFERENCES executions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS plans (
+	name                TEXT PRIMARY KEY,
+	max_scripts         INTEGER NOT NULL DEFAULT 0,
+	max_storage         BIGINT NOT NULL DEFAULT 0,
+	max_concurrent      INTEGER NOT NULL DEFAULT 0,
+	max_compute_seconds INTEGER NOT NULL DEFAULT 0,
+	created_at          TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+);
+
+CREATE TABLE IF NOT EXISTS script_tags (
+	script_id TEXT NOT NULL,
+	user_id   INTEGER NOT NULL,
+	tag       TEXT NOT NULL,
+	PRIMARY KEY(script_id, tag),
+	FOREIGN KEY(script_id) REFERENCES scripts(id) ON DELETE CASCADE
+);
+
+-- Plan par défaut : 100 scripts, 100MB, 2 exécutions simultanées, 10h de calcul par mois
+INSERT INTO plans (name, max_scripts, max_storage, max_concurrent, max_compute_seconds)
+VALUES ('default', 100, 104857600, 2, 36000)
+ON CONFLICT (name) DO NOTHING;
+
+-- Liste des scripts (tri par nom, date de création ou dernière exécution, filtre par tag)
+CREATE INDEX IF NOT EXISTS idx_scripts_user_created ON scripts(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_scripts_user_name ON scripts(user_id, name, id);
+CREATE INDEX IF NOT EXISTS idx_scripts_user_last_run ON scripts(user_id, last_run_at, id);
+CREATE INDEX IF NOT EXISTS idx_script_tags_user_tag ON script_tags(user_id, tag);
+
+-- Historique des exécutions et recherches par statut du scheduler et des quotas
+CREATE INDEX IF NOT EXISTS idx_executions_user_created ON executions(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_executions_script_created ON executions(script_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_executions_user_status ON executions(user_id, status);
+CREATE INDEX IF NOT EXISTS idx_executions_schedule_status ON executions(schedule_id, status);
+
+CREATE INDEX IF NOT EXISTS idx_logs_execution ON logs(execution_id, seq);
@@ -0,0 +1 @@
+-- Irréversible : les lignes supprimées n'avaient plus de parent
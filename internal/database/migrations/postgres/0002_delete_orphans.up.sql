@@ -0,0 +1,14 @@
+-- Lignes orphelines laissées tant que PRAGMA foreign_keys n'était pas activé :
+-- ON DELETE CASCADE ne s'appliquait pas à la suppression d'un script ou d'un utilisateur
+DELETE FROM sessions WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM scripts WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM executions WHERE script_id NOT IN (SELECT id FROM scripts) OR user_id NOT IN (SELECT id FROM users);
+DELETE FROM logs WHERE execution_id NOT IN (SELECT id FROM executions);
+DELETE FROM artifacts WHERE execution_id NOT IN (SELECT id FROM executions);
+DELETE FROM schedules WHERE script_id NOT IN (SELECT id FROM scripts) OR user_id NOT IN (SELECT id FROM users);
+DELETE FROM script_triggers WHERE script_id NOT IN (SELECT id FROM scripts) OR user_id NOT IN (SELECT id FROM users);
+DELETE FROM webhooks WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM webhook_deliveries WHERE webhook_id NOT IN (SELECT id FROM webhooks);
+DELETE FROM servers WHERE script_id NOT IN (SELECT id FROM scripts) OR user_id NOT IN (SELECT id FROM users);
+DELETE FROM volumes WHERE user_id NOT IN (SELECT id FROM users);
+DELETE FROM script_tags WHERE script_id NOT IN (SELECT id FROM scripts);
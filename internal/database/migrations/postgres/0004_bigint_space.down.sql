@@ -0,0 +1,2 @@
+ALTER TABLE servers ALTER COLUMN space TYPE INTEGER;
+ALTER TABLE volumes ALTER COLUMN space TYPE INTEGER;
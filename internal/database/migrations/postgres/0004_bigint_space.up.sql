@@ -0,0 +1,3 @@
+-- Quotas disque en octets : INTEGER (32 bits) plafonnait servers.space et volumes.space à 2GB
+ALTER TABLE servers ALTER COLUMN space TYPE BIGINT;
+ALTER TABLE volumes ALTER COLUMN space TYPE BIGINT;
@@ -0,0 +1,4 @@
+-- Limites des volumes par plan : taille max d'un volume et total réservé par utilisateur (0 = illimité)
+ALTER TABLE plans ADD COLUMN max_volume_space BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE plans ADD COLUMN max_volume_total BIGINT NOT NULL DEFAULT 0;
+UPDATE plans SET max_volume_space = 1073741824, max_volume_total = 5368709120 WHERE name = 'default';
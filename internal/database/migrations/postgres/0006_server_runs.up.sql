@@ -0,0 +1,16 @@
+-- Périodes de fonctionnement des serveurs, comptées dans les secondes de conteneur du plan.
+-- Gardées après la suppression du serveur : le mois en cours reste facturé.
+CREATE TABLE IF NOT EXISTS server_runs (
+	id         INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	server_id  TEXT NOT NULL,
+	user_id    INTEGER NOT NULL,
+	started_at TEXT NOT NULL,
+	stopped_at TEXT,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_server_runs_user ON server_runs(user_id, started_at);
+CREATE INDEX IF NOT EXISTS idx_server_runs_open ON server_runs(server_id, stopped_at);
+
+-- Serveurs déjà en marche
+INSERT INTO server_runs (server_id, user_id, started_at)
+SELECT id, user_id, started_at FROM servers WHERE state = 1 AND started_at IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_sessions_expires;
+ALTER TABLE sessions DROP COLUMN expires_at;
@@ -0,0 +1,5 @@
+-- Expiration des sessions (format des colonnes created_at). Les sessions ouvertes
+-- avant la migration reçoivent la durée par défaut à partir de maintenant.
+ALTER TABLE sessions ADD COLUMN expires_at TEXT;
+UPDATE sessions SET expires_at = to_char((now() AT TIME ZONE 'UTC') + interval '30 days', 'YYYY-MM-DD HH24:MI:SS');
+CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at);
@@ -0,0 +1,11 @@
+-- Étape en cours d'une exécution 'running', pour la reprendre après un redémarrage de l'API.
+-- Le conteneur est retrouvé par son nom (<id> ou <id>-build) ; env et stdin servent si l'exécution reprend au build.
+CREATE TABLE IF NOT EXISTS execution_checkpoints (
+	execution_id TEXT PRIMARY KEY,
+	phase        TEXT NOT NULL,
+	deadline     TEXT NOT NULL,
+	env          TEXT NOT NULL DEFAULT '[]',
+	stdin        BLOB,
+	created_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(execution_id) REFERENCES executions(id) ON DELETE CASCADE
+);
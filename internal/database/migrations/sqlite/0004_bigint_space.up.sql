@@ -0,0 +1,2 @@
+-- Rien à faire : les INTEGER de SQLite sont déjà sur 64 bits (version gardée alignée sur PostgreSQL)
+SELECT 1;
@@ -0,0 +1,2 @@
+ALTER TABLE plans DROP COLUMN max_volume_total;
+ALTER TABLE plans DROP COLUMN max_volume_space;
@@ -0,0 +1,5 @@
+-- Expiration des sessions (format des colonnes created_at). Les sessions ouvertes
+-- avant la migration reçoivent la durée par défaut à partir de maintenant.
+ALTER TABLE sessions ADD COLUMN expires_at TEXT;
+UPDATE sessions SET expires_at = datetime('now', '+30 days');
+CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at);
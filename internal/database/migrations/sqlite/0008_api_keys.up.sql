@@ -0,0 +1,13 @@
+-- Clés d'API : authentifient un client sans session, par Authorization: Bearer <clé>.
+-- La clé n'est gardée que hachée ; prefix en garde le début pour la reconnaître.
+CREATE TABLE IF NOT EXISTS api_keys (
+	id           TEXT PRIMARY KEY,
+	user_id      INTEGER NOT NULL,
+	name         TEXT NOT NULL,
+	prefix       TEXT NOT NULL,
+	key_hash     TEXT NOT NULL UNIQUE,
+	created_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_used_at TEXT,
+	UNIQUE(user_id, name),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
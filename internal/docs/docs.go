@@ -0,0 +1,50 @@
+package docs
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"gopkg.in/yaml.v3"
+)
+
+// La spécification est écrite à la main en YAML (ancres pour les routes répétées)
+// et servie en JSON ; openapi_test.go dans handlers vérifie qu'elle couvre chaque route.
+//
+//go:embed openapi.yaml
+var specYAML []byte
+
+// Page autonome qui affiche /openapi.json, sans dépendance externe
+//
+//go:embed index.html
+var indexHTML []byte
+
+// Spec retourne la spécification OpenAPI au format JSON
+var Spec = sync.OnceValues(func() ([]byte, error) {
+	var doc any
+	if err := yaml.Unmarshal(specYAML, &doc); err != nil {
+		return nil, fmt.Errorf("openapi.yaml: %w", err)
+	}
+	return json.Marshal(doc)
+})
+
+// SpecHandler — GET /openapi.json
+func SpecHandler(w http.ResponseWriter, r *http.Request) {
+	spec, err := Spec()
+	if err != nil {
+		api.InternalErrorHandler(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec)
+}
+
+// UIHandler — GET /docs
+func UIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	w.Write(indexHTML)
+}
@@ -0,0 +1,91 @@
+package docs
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// refs collecte les $ref du document
+func refs(node any, found map[string]bool) {
+	switch n := node.(type) {
+	case map[string]any:
+		if ref, ok := n["$ref"].(string); ok {
+			found[ref] = true
+		}
+		for _, v := range n {
+			refs(v, found)
+		}
+	case []any:
+		for _, v := range n {
+			refs(v, found)
+		}
+	}
+}
+
+// lookup suit un pointeur JSON local (#/components/...)
+func lookup(doc map[string]any, ref string) any {
+	var node any = doc
+	for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = m[key]
+	}
+	return node
+}
+
+func TestSpec(t *testing.T) {
+	spec, err := Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	found := map[string]bool{}
+	refs(doc, found)
+	for ref := range found {
+		if lookup(doc, ref) == nil {
+			t.Errorf("unresolved $ref %s", ref)
+		}
+	}
+
+	// Chaque paramètre du chemin est déclaré, au niveau du chemin ou de l'opération
+	for p, item := range doc["paths"].(map[string]any) {
+		item := item.(map[string]any)
+		for method, op := range item {
+			if method == "parameters" || method == "servers" {
+				continue
+			}
+			op := op.(map[string]any)
+			if _, ok := op["responses"]; !ok {
+				t.Errorf("%s %s: no responses", method, p)
+			}
+			declared := map[string]bool{}
+			for _, list := range []any{item["parameters"], op["parameters"]} {
+				params, _ := list.([]any)
+				for _, param := range params {
+					param := param.(map[string]any)
+					if ref, ok := param["$ref"].(string); ok {
+						param, _ = lookup(doc, ref).(map[string]any)
+					}
+					if param["in"] == "path" {
+						declared[param["name"].(string)] = true
+					}
+				}
+			}
+			for _, m := range pathParam.FindAllStringSubmatch(p, -1) {
+				if !declared[m[1]] {
+					t.Errorf("%s %s: path parameter %s is not declared", method, p, m[1])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Package egress limite les connexions sortantes vers des URLs choisies par les utilisateurs
+// (webhooks) aux adresses publiques : pas de loopback, de réseau privé, de link-local
+// (métadonnées cloud 169.254.169.254) ni de réseau interne Docker.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("address is not publicly routable")
+
+// Plages réservées non couvertes par les méthodes de netip.Addr
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // CGNAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 : peut traduire vers une adresse privée
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// Allowed indique si l'adresse est publique
+func Allowed(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, p := range reserved {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL vérifie une URL http(s) et que son hôte ne résout que vers des adresses publiques.
+// Le contrôle est refait à chaque connexion par Client, une résolution pouvant changer.
+func CheckURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("must be an absolute http(s) URL")
+	}
+
+	host := u.Hostname()
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !Allowed(ip) {
+			return fmt.Errorf("%s: %w", host, ErrForbiddenAddress)
+		}
+		return nil
+	}
+
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("%s can't be resolved", host)
+	}
+	for _, ip := range ips {
+		if !Allowed(ip) {
+			return fmt.Errorf("%s resolves to %s: %w", host, ip.Unmap(), ErrForbiddenAddress)
+		}
+	}
+	return nil
+}
+
+// control refuse la connexion si l'adresse effectivement contactée n'est pas publique
+func control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !Allowed(ip) {
+		return fmt.Errorf("%s: %w", host, ErrForbiddenAddress)
+	}
+	return nil
+}
+
+// Client retourne un client HTTP qui ne se connecte qu'à des adresses publiques,
+// redirections comprises, et sans passer par le proxy de l'environnement
+func Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: control}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: timeout,
+		},
+	}
+}
@@ -0,0 +1,87 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllowed(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.215.14":        true,
+		"2606:4700::1111":      true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"10.1.2.3":             false,
+		"172.17.0.1":           false, // bridge Docker
+		"192.168.1.10":         false,
+		"169.254.169.254":      false, // métadonnées cloud
+		"100.100.100.200":      false,
+		"0.0.0.0":              false,
+		"::":                   false,
+		"fd00::1":              false,
+		"fe80::1":              false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:93.184.215.14": true,
+		"64:ff9b::a00:1":       false,
+		"224.0.0.1":            false,
+		"255.255.255.255":      false,
+	} {
+		if got := Allowed(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("Allowed(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	ctx := context.Background()
+	for _, raw := range []string{
+		"https://93.184.215.14/hook",
+		"http://[2606:4700::1111]:8080/",
+	} {
+		if err := CheckURL(ctx, raw); err != nil {
+			t.Errorf("CheckURL(%s): %v", raw, err)
+		}
+	}
+	for _, raw := range []string{
+		"http://127.0.0.1:8000/",
+		"http://localhost/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/",
+		"http://10.0.0.1/",
+		"http://0x7f000001/",
+	} {
+		if err := CheckURL(ctx, raw); err == nil {
+			t.Errorf("CheckURL(%s) accepted", raw)
+		}
+	}
+	for _, raw := range []string{"ftp://93.184.215.14/", "/relative", "http://"} {
+		if err := CheckURL(ctx, raw); err == nil || !strings.Contains(err.Error(), "http(s) URL") {
+			t.Errorf("CheckURL(%s) = %v", raw, err)
+		}
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback server")
+	}))
+	defer target.Close()
+
+	// Résolution faite au moment de la connexion : une URL validée plus tôt ne suffit pas
+	_, err := Client(time.Second).Get(target.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("loopback: %v", err)
+	}
+
+	// Un nom d'hôte qui résout vers loopback aussi
+	_, err = Client(time.Second).Get(strings.Replace(target.URL, "127.0.0.1", "localhost", 1))
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("localhost: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/images"
+	"github.com/go-chi/chi"
+)
+
+// ListImageRulesHandler — GET /admin/images
+func (a *App) ListImageRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rules, err := a.images.ListRules()
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// AddImageRuleHandler — POST /admin/images
+// JSON : pattern (python, ghcr.io/my-org/*), require_digest
+func (a *App) AddImageRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Pattern       string `json:"pattern"`
+		RequireDigest bool   `json:"require_digest"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Pattern == "" {
+		api.RequestErrorHandler(w, errors.New("pattern is required"))
+		return
+	}
+
+	id, err := a.images.AddRule(req.Pattern, req.RequireDigest)
+	if errors.Is(err, images.ErrInvalidReference) {
+		api.RequestErrorHandler(w, err)
+		return
+	} else if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int{"id": id})
+}
+
+// DeleteImageRuleHandler — DELETE /admin/images/{id}
+func (a *App) DeleteImageRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Rule not found")
+		return
+	}
+
+	deleted, err := a.images.DeleteRule(id)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+	if !deleted {
+		api.NotFoundErrorHandler(w, "Rule not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
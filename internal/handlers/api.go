@@ -1,39 +1,237 @@
 package handlers
 
 import (
-	"database/sql"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/auth"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/docs"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/images"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/languages"
 	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/quotas"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
 	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
 )
 
+// App est une instance de l'API : sa base, sa configuration, son logger
+// et l'état en mémoire de ses exécutions et serveurs.
+// Plusieurs instances peuvent coexister (tests httptest avec SQLite en mémoire).
+type App struct {
+	store *store.Store
 
-var db *sql.DB
+	auth      *auth.Service
+	quotas    *quotas.Service
+	images    *images.AllowList
+	languages *languages.Registry
+	// Conteneurs des exécutions, builds et serveurs
+	runtime Runtime
 
-func Setup(database *sql.DB) {
-	db = database
+	config Config
+	log    *log.Logger
+	// config.ProxyAPIURL analysée, nil si absente
+	apiURL *url.URL
+
+	// Tickets et sessions des serveurs réservés aux utilisateurs connectés (proxy_auth.go)
+	appAuthMu   sync.Mutex
+	appTickets  map[string]appGrant
+	appSessions map[string]appGrant
+
+	// Canaux fermés à la fin d'une exécution, pour ceux qui l'attendent
+	waitersMu sync.Mutex
+	waiters   map[string][]chan struct{}
+
+	// Avancement des health checks, par ID de conteneur
+	healthMu     sync.Mutex
+	healthProbes map[string]*healthProbe
+
+	// Raison de l'arrêt des conteneurs tués par le moniteur de santé, par ID de conteneur
+	killReasons sync.Map
+
+	// Exécutions dont le conteneur est suivi ; draining refuse les nouvelles pendant l'arrêt
+	runMu    sync.Mutex
+	draining bool
+	running  sync.WaitGroup
+	// Fermé quand Shutdown abandonne l'attente : les conteneurs restants sont détachés
+	detach     chan struct{}
+	detachOnce sync.Once
+}
+
+// New crée une instance sur un store déjà migré, qui lance ses conteneurs avec rt
+func New(st *store.Store, langs *languages.Registry, rt Runtime, config Config, logger *log.Logger) *App {
+	config.ProxyBaseDomain = strings.ToLower(strings.TrimPrefix(config.ProxyBaseDomain, "."))
+
+	a := &App{
+		store:        st,
+		auth:         auth.New(st),
+		quotas:       quotas.New(st),
+		images:       images.NewAllowList(st.ImageRules),
+		languages:    langs,
+		runtime:      rt,
+		config:       config,
+		log:          logger,
+		waiters:      map[string][]chan struct{}{},
+		healthProbes: map[string]*healthProbe{},
+		appTickets:   map[string]appGrant{},
+		appSessions:  map[string]appGrant{},
+		detach:       make(chan struct{}),
+	}
+	if u, err := url.Parse(config.ProxyAPIURL); err == nil && u.Host != "" {
+		a.apiURL = u
+	}
+	return a
 }
 
-func RegisterAPIRoutes(r chi.Router) {
+// internalError logue err avec l'identifiant de la requête et répond 500 sans la détailler
+func (a *App) internalError(w http.ResponseWriter, err error) {
+	a.log.WithField("request_id", w.Header().Get(api.RequestIDHeader)).Error(err)
+	api.InternalErrorHandler(w)
+}
+
+// Handler retourne le routeur de l'API
+func (a *App) Handler() http.Handler {
+	r := chi.NewRouter()
+	a.RegisterAPIRoutes(r)
+	return r
+}
+
+func (a *App) RegisterAPIRoutes(r chi.Router) {
+	r.Use(middleware.RequestID, middleware.Recover(a.log))
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		api.NotFoundErrorHandler(w, "Route not found")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		api.WriteError(w, http.StatusMethodNotAllowed, api.CodeMethodNotAllowed, "Method not allowed")
+	})
 
-	r.Post("/login", LoginHandler)
+	// Reverse proxy vers les serveurs hébergés : <name>.<domaine>, et /apps/<name>/ si activé
+	r.Use(a.ProxyHostMiddleware)
+	if a.config.ProxyPathMode {
+		r.HandleFunc("/apps/{name}", a.ProxyPathHandler)
+		r.HandleFunc("/apps/{name}/*", a.ProxyPathHandler)
+	}
+
+	r.Route(api.Prefix, func(v1 chi.Router) {
+		// Spécification OpenAPI (internal/docs/openapi.yaml) et page de documentation
+		v1.Get("/openapi.json", docs.SpecHandler)
+		v1.Get("/docs", docs.UIHandler)
+
+		a.registerRoutes(v1)
+	})
+
+	// Anciennes routes sans préfixe, gardées jusqu'à la date de retrait
+	if a.config.LegacyRoutes {
+		r.Group(func(legacy chi.Router) {
+			legacy.Use(middleware.Deprecated(legacyDeprecatedAt, a.config.LegacySunset, api.Prefix))
+			a.registerRoutes(legacy)
+		})
+	}
+}
+
+// legacyRequest indique une requête reçue sur une ancienne route sans /api/v1,
+// qui garde la forme de réponse d'avant le versionnement
+func legacyRequest(r *http.Request) bool {
+	return !strings.HasPrefix(r.URL.Path, api.Prefix+"/")
+}
+
+// registerRoutes déclare les routes de l'API, relatives à api.Prefix
+func (a *App) registerRoutes(r chi.Router) {
+	r.Post("/login", a.LoginHandler)
+	r.Get("/languages", a.ListLanguagesHandler)
+
+	// Déclencheurs entrants (authentifiés par token ou signature)
+	r.Post("/hooks/{id}", a.TriggerWebhookHandler)
 
 	// routes protégées
 	r.Group(func(protected chi.Router) {
-		protected.Use(middleware.AuthMiddleware(db)) // middleware prend db
+		protected.Use(middleware.AuthMiddleware(a.auth, a.log))
 		
-		protected.Post("/logout", LogoutHandler)
+		protected.Post("/logout", a.LogoutHandler)
+		protected.Get("/usage", a.UsageHandler)
+
+		// Clés d'API
+		protected.Get("/api_keys", a.ListAPIKeysHandler)
+		protected.Post("/api_keys", a.CreateAPIKeyHandler)
+		protected.Delete("/api_keys/{id}", a.DeleteAPIKeyHandler)
 
 		// Scripts
-		protected.Post("/scripts/upload", UploadScriptHandler)
-		protected.Get("/scripts", ListScriptsHandler)
-		protected.Get("/scripts/{id}", GetScriptHandler)
-		protected.Delete("/scripts/{id}", DeleteScriptHandler)
+		protected.Post("/scripts/upload", a.UploadScriptHandler)
+		protected.Get("/scripts", a.ListScriptsHandler)
+		protected.Get("/scripts/{id}", a.GetScriptHandler)
+		protected.Delete("/scripts/{id}", a.DeleteScriptHandler)
+		protected.Put("/scripts/{id}/mounts", a.SetScriptMountsHandler)
+		protected.Put("/scripts/{id}/tags", a.SetScriptTagsHandler)
+		protected.Get("/tags", a.ListTagsHandler)
+		protected.Get("/scripts/{id}/metrics", a.GetScriptMetricsHandler)
 
 		// Exécutions
-		protected.Post("/scripts/{id}/run", RunScriptHandler)
-		protected.Get("/executions/{id}", GetExecutionHandler)
-		protected.Get("/executions/{id}/logs", GetExecutionLogsHandler)
+		protected.Post("/scripts/{id}/run", a.RunScriptHandler)
+		protected.Get("/executions", a.ListExecutionsHandler)
+		protected.Get("/scripts/{id}/executions", a.ListScriptExecutionsHandler)
+		protected.Get("/executions/{id}", a.GetExecutionHandler)
+		protected.Get("/executions/{id}/logs", a.GetExecutionLogsHandler)
+		protected.Get("/executions/{id}/artifacts", a.ListArtifactsHandler)
+		protected.Get("/executions/{id}/artifacts/{artifactID}", a.DownloadArtifactHandler)
+		protected.Delete("/executions/{id}/artifacts", a.DeleteArtifactsHandler)
+
+		// Plannings
+		protected.Get("/scripts/{id}/schedules", a.ListSchedulesHandler)
+		protected.Post("/scripts/{id}/schedules", a.CreateScheduleHandler)
+		protected.Patch("/schedules/{id}", a.UpdateScheduleHandler)
+		protected.Delete("/schedules/{id}", a.DeleteScheduleHandler)
+
+		// Déclencheurs webhook
+		protected.Get("/scripts/{id}/triggers", a.ListTriggersHandler)
+		protected.Post("/scripts/{id}/triggers", a.CreateTriggerHandler)
+		protected.Delete("/triggers/{id}", a.DeleteTriggerHandler)
+
+		// Serveurs longue durée
+		protected.Get("/server_list", a.ServerListHandler)
+		protected.Get("/server", a.ServerInfoHandler)
+		protected.Post("/servers", a.CreateServerHandler)
+		protected.Patch("/servers/{id}", a.UpdateServerHandler)
+		protected.Delete("/servers/{id}", a.DeleteServerHandler)
+		protected.Post("/servers/{id}/start", a.StartServerHandler)
+		protected.Post("/servers/{id}/stop", a.StopServerHandler)
+		protected.Post("/servers/{id}/restart", a.RestartServerHandler)
+		protected.Post("/servers/{id}/stdin", a.ServerStdinHandler)
+		protected.Get("/servers/{id}/logs", a.ServerLogsHandler)
+		protected.Get("/servers/{id}/signin", a.ServerSignInHandler)
+
+		// Volumes persistants
+		protected.Get("/volumes", a.ListVolumesHandler)
+		protected.Post("/volumes", a.CreateVolumeHandler)
+		protected.Patch("/volumes/{id}", a.UpdateVolumeHandler)
+		protected.Delete("/volumes/{id}", a.DeleteVolumeHandler)
+		protected.Get("/volumes/{id}/files", a.GetVolumeFileHandler)
+		protected.Get("/volumes/{id}/files/*", a.GetVolumeFileHandler)
+		protected.Put("/volumes/{id}/files/*", a.PutVolumeFileHandler)
+		protected.Delete("/volumes/{id}/files/*", a.DeleteVolumeFileHandler)
+
+		// Webhooks sortants
+		protected.Get("/webhooks", a.ListWebhooksHandler)
+		protected.Post("/webhooks", a.CreateWebhookHandler)
+		protected.Patch("/webhooks/{id}", a.UpdateWebhookHandler)
+		protected.Delete("/webhooks/{id}", a.DeleteWebhookHandler)
+		protected.Get("/webhooks/{id}/deliveries", a.ListWebhookDeliveriesHandler)
+		protected.Post("/webhooks/{id}/deliveries/{deliveryID}/redeliver", a.RedeliverWebhookHandler)
+
+		// Administration
+		protected.Group(func(admin chi.Router) {
+			admin.Use(middleware.AdminMiddleware(a.auth))
+
+			admin.Get("/admin/images", a.ListImageRulesHandler)
+			admin.Post("/admin/images", a.AddImageRuleHandler)
+			admin.Delete("/admin/images/{id}", a.DeleteImageRuleHandler)
 
+			admin.Get("/admin/plans", a.ListPlansHandler)
+			admin.Put("/admin/plans/{name}", a.SavePlanHandler)
+			admin.Delete("/admin/plans/{name}", a.DeletePlanHandler)
+			admin.Put("/admin/users/{id}/plan", a.SetUserPlanHandler)
+		})
 	})
 }
\ No newline at end of file
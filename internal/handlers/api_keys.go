@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/auth"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+const (
+	// Longueur max du nom d'une clé d'API
+	maxAPIKeyName = 64
+	// Caractères de la clé gardés lisibles pour la reconnaître (whk_ compris)
+	apiKeyPrefixLen = 8
+)
+
+type APIKey struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Prefix     string  `json:"prefix"`
+	CreatedAt  string  `json:"created_at"`
+	LastUsedAt *string `json:"last_used_at"`
+	// Key n'est retournée qu'à la création
+	Key string `json:"key,omitempty"`
+}
+
+func apiKey(k store.APIKey) APIKey {
+	return APIKey{ID: k.ID, Name: k.Name, Prefix: k.Prefix, CreatedAt: k.CreatedAt, LastUsedAt: k.LastUsedAt}
+}
+
+// ListAPIKeysHandler — GET /api_keys
+func (a *App) ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	stored, err := a.store.APIKeys.List(userID)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	keys := []APIKey{}
+	for _, k := range stored {
+		keys = append(keys, apiKey(k))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// CreateAPIKeyHandler — POST /api_keys
+// JSON : name ; la clé n'est retournée qu'une fois, seul son hash est gardé
+func (a *App) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.RequestErrorHandler(w, errors.New("invalid request"))
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > maxAPIKeyName {
+		api.RequestErrorHandler(w, api.Invalid("name", "is required (max %d characters)", maxAPIKeyName))
+		return
+	}
+
+	key, err := auth.GenerateAPIKey()
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+	k := store.APIKey{
+		ID:      uuid.New().String(),
+		UserID:  userID,
+		Name:    req.Name,
+		Prefix:  key[:apiKeyPrefixLen],
+		KeyHash: auth.HashAPIKey(key),
+	}
+	if err := a.store.APIKeys.Create(k); errors.Is(err, store.ErrConflict) {
+		api.ConflictErrorHandler(w, fmt.Errorf("API key %s already exists", req.Name))
+		return
+	} else if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	created, err := a.store.APIKeys.GetByHash(k.KeyHash)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+	resp := apiKey(created)
+	resp.Key = key
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// DeleteAPIKeyHandler — DELETE /api_keys/{id}
+// La clé est refusée dès la requête suivante
+func (a *App) DeleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	err := a.store.APIKeys.Delete(chi.URLParam(r, "id"), userID)
+	if errors.Is(err, store.ErrNotFound) {
+		api.NotFoundErrorHandler(w, "API key not found")
+		return
+	}
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
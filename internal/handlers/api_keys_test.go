@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+)
+
+func TestAPIKeys(t *testing.T) {
+	srv, st := newTestServer(t)
+	createUser(t, st, "alice", "secret")
+	createUser(t, st, "bob", "secret")
+	c, _ := login(t, srv, "alice", "secret")
+	keys := srv.URL + api.Prefix + "/api_keys"
+
+	// withKey envoie GET /scripts avec la clé, sans cookie
+	withKey := func(key string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+api.Prefix+"/scripts", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	resp, err := c.Post(keys, "application/json", strings.NewReader(`{"name":"ci"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created APIKey
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || !strings.HasPrefix(created.Key, "whk_") || created.Prefix != created.Key[:apiKeyPrefixLen] || created.CreatedAt == "" {
+		t.Fatalf("create: %d %+v", resp.StatusCode, created)
+	}
+
+	if code := withKey(created.Key); code != http.StatusOK {
+		t.Fatalf("GET /scripts with the key: %d", code)
+	}
+	for _, key := range []string{"whk_unknown", created.Key[4:], ""} {
+		if code := withKey(key); code != http.StatusUnauthorized {
+			t.Errorf("GET /scripts with key %q: %d, want 401", key, code)
+		}
+	}
+
+	// La liste ne contient jamais la clé, et note sa dernière utilisation
+	resp, err = c.Get(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []APIKey
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if len(list) != 1 || list[0].ID != created.ID || list[0].Key != "" || list[0].LastUsedAt == nil {
+		t.Fatalf("list: %+v", list)
+	}
+
+	for body, want := range map[string]int{
+		`{"name":"ci"}`: http.StatusConflict,
+		`{"name":"  "}`: http.StatusBadRequest,
+		`{"name":"` + strings.Repeat("k", 65) + `"}`: http.StatusBadRequest,
+		`name=ci`: http.StatusBadRequest,
+	} {
+		resp, err := c.Post(keys, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("POST /api_keys %s: %d, want %d", body, resp.StatusCode, want)
+		}
+	}
+
+	remove := func(c *http.Client) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodDelete, keys+"/"+created.ID, nil)
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	// Une autre utilisatrice ne supprime pas la clé
+	bob, _ := login(t, srv, "bob", "secret")
+	if code := remove(bob); code != http.StatusNotFound {
+		t.Fatalf("bob deletes alice's key: %d, want 404", code)
+	}
+	if code := remove(c); code != http.StatusNoContent {
+		t.Fatalf("delete: %d", code)
+	}
+	if code := withKey(created.Key); code != http.StatusUnauthorized {
+		t.Errorf("GET /scripts with a revoked key: %d, want 401", code)
+	}
+}
+
+// brokenAPIKeys simule une base en panne pour la lecture ou l'écriture des clés
+type brokenAPIKeys struct {
+	store.APIKeys
+	get, touch error
+}
+
+func (b brokenAPIKeys) GetByHash(keyHash string) (store.APIKey, error) {
+	if b.get != nil {
+		return store.APIKey{}, b.get
+	}
+	return b.APIKeys.GetByHash(keyHash)
+}
+
+func (b brokenAPIKeys) Touch(id string, at time.Time) error {
+	if b.touch != nil {
+		return b.touch
+	}
+	return b.APIKeys.Touch(id, at)
+}
+
+func TestAPIKeyStoreErrors(t *testing.T) {
+	srv, st := newTestServer(t)
+	createUser(t, st, "alice", "secret")
+	c, _ := login(t, srv, "alice", "secret")
+	resp, err := c.Post(srv.URL+api.Prefix+"/api_keys", "application/json", strings.NewReader(`{"name":"ci"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created APIKey
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+
+	withKey := func() int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+api.Prefix+"/scripts", nil)
+		req.Header.Set("Authorization", "Bearer "+created.Key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	keys := st.APIKeys
+	// La date de dernière utilisation non enregistrée ne refuse pas la clé
+	st.APIKeys = brokenAPIKeys{APIKeys: keys, touch: errors.New("database is locked")}
+	if code := withKey(); code != http.StatusOK {
+		t.Errorf("touch failure: %d, want 200", code)
+	}
+	// La base indisponible n'est pas une clé invalide
+	st.APIKeys = brokenAPIKeys{APIKeys: keys, get: errors.New("database is locked")}
+	if code := withKey(); code != http.StatusInternalServerError {
+		t.Errorf("lookup failure: %d, want 500", code)
+	}
+}
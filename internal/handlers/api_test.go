@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/database"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/database/dbtest"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/languages"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testDriver est la base des tests en cours : la suite tourne sur SQLite, puis sur PostgreSQL s'il est disponible
+var testDriver = database.SQLite
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if code == 0 && dbtest.Available() == nil {
+		testDriver = database.Postgres
+		code = m.Run()
+	}
+	dbtest.Stop()
+	os.Exit(code)
+}
+
+// newTestApp crée une instance isolée sur une base vide (SQLite en mémoire ou base PostgreSQL jetable),
+// dont les conteneurs tournent dans un fakeRuntime (voir fakeDocker)
+func newTestApp(t *testing.T) (*App, *store.Store) {
+	t.Helper()
+	url := database.Memory
+	if testDriver == database.Postgres {
+		url = dbtest.Postgres(t)
+	}
+	st, err := store.Open(testDriver, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	langs, err := languages.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	t.Cleanup(func() { st.Close() })
+	return New(st, langs, newFakeRuntime(), DefaultConfig(), logger), st
+}
+
+// fakeDocker retourne le faux démon Docker de l'instance
+func fakeDocker(app *App) *fakeRuntime {
+	return app.runtime.(*fakeRuntime)
+}
+
+// newTestServer démarre une instance isolée avec httptest
+func newTestServer(t *testing.T) (*httptest.Server, *store.Store) {
+	t.Helper()
+	app, st := newTestApp(t)
+	srv := httptest.NewServer(app.Handler())
+	t.Cleanup(srv.Close)
+	return srv, st
+}
+
+func createUser(t *testing.T, st *store.Store, username, password string) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Users.Create(username, string(hash), false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// createScript enregistre un script sans fichier pour username.
+// Avec un langage absent de l'instance (cobol), ses exécutions échouent sans lancer de conteneur.
+func createScript(t *testing.T, st *store.Store, username, id, language string) {
+	t.Helper()
+	user, err := st.Users.GetByUsername(username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.Scripts.Create(store.Script{ID: id, UserID: user.ID, Name: id, Language: language, DockerImage: language, FilePath: id, Mounts: "[]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func login(t *testing.T, srv *httptest.Server, username, password string) (*http.Client, int) {
+	t.Helper()
+	jar, _ := cookiejar.New(nil)
+	c := &http.Client{Jar: jar}
+	body := `{"username":"` + username + `","password":"` + password + `"}`
+	resp, err := c.Post(srv.URL+api.Prefix+"/login", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return c, resp.StatusCode
+}
+
+func TestInstancesAreIsolated(t *testing.T) {
+	first, firstStore := newTestServer(t)
+	second, _ := newTestServer(t)
+	createUser(t, firstStore, "alice", "secret")
+
+	c, status := login(t, first, "alice", "secret")
+	if status != http.StatusOK {
+		t.Fatalf("login on first instance: status %d", status)
+	}
+	if _, status := login(t, second, "alice", "secret"); status != http.StatusUnauthorized {
+		t.Fatalf("login on second instance: status %d, want 401", status)
+	}
+
+	resp, err := c.Get(first.URL + api.Prefix + "/scripts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != `{"scripts":[],"next_cursor":null}` {
+		t.Fatalf("GET /scripts: %d %s", resp.StatusCode, body)
+	}
+
+	// La session de la première instance n'existe pas dans la seconde
+	req, _ := http.NewRequest(http.MethodGet, second.URL+api.Prefix+"/scripts", nil)
+	for _, cookie := range c.Jar.Cookies(resp.Request.URL) {
+		req.AddCookie(cookie)
+	}
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		t.Fatal("first instance's session accepted by the second")
+	}
+}
+
+func TestLoginLogoutJSON(t *testing.T) {
+	srv, st := newTestServer(t)
+	createUser(t, st, "alice", "secret")
+
+	jar, _ := cookiejar.New(nil)
+	c := &http.Client{Jar: jar}
+	resp, err := c.Post(srv.URL+api.Prefix+"/login", "application/json", strings.NewReader(`{"username":"alice","password":"secret"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var session api.Session
+	err = json.NewDecoder(resp.Body).Decode(&session)
+	resp.Body.Close()
+	if err != nil || session.Token == "" || session.User.Username != "alice" || session.ExpiresAt == nil {
+		t.Fatalf("login: %+v %v", session, err)
+	}
+	if d := time.Until(*session.ExpiresAt) - defaultSessionTTL; d < -time.Minute || d > time.Minute {
+		t.Errorf("session expires at %v, want in %v", session.ExpiresAt, defaultSessionTTL)
+	}
+
+	resp, err = c.Post(srv.URL+api.Prefix+"/logout", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session = api.Session{}
+	err = json.NewDecoder(resp.Body).Decode(&session)
+	resp.Body.Close()
+	if err != nil || session.Token != "" || session.User.ID == 0 || session.ExpiresAt == nil {
+		t.Fatalf("logout: %+v %v", session, err)
+	}
+
+	// La session n'existe plus
+	resp, err = c.Get(srv.URL + api.Prefix + "/scripts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("GET /scripts after logout: %d", resp.StatusCode)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	srv, st := newTestServer(t)
+	createUser(t, st, "alice", "secret")
+	c, _ := login(t, srv, "alice", "secret")
+
+	get := func() int {
+		t.Helper()
+		resp, err := c.Get(srv.URL + api.Prefix + "/scripts")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("GET /scripts with a valid session: %d", code)
+	}
+
+	// Session arrivée à expiration : refusée comme une session inconnue
+	parsed, _ := url.Parse(srv.URL)
+	cookies := c.Jar.Cookies(parsed)
+	if len(cookies) != 1 {
+		t.Fatalf("cookies %v", cookies)
+	}
+	user, _ := st.Users.GetByUsername("alice")
+	if err := st.Sessions.Save(cookies[0].Value, user.ID, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if code := get(); code != http.StatusUnauthorized {
+		t.Fatalf("GET /scripts with an expired session: %d, want 401", code)
+	}
+}
+
+func TestLegacyLogin(t *testing.T) {
+	srv, st := newTestServer(t)
+	createUser(t, st, "alice", "secret")
+
+	resp, err := http.Post(srv.URL+"/login", "application/json", strings.NewReader(`{"username":"alice","password":"secret"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	cookie := cookieNamed(resp, "session_token")
+	if resp.StatusCode != http.StatusOK || cookie == nil || string(body) != "Logged in : "+cookie.Value {
+		t.Fatalf("POST /login: %d %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get("Deprecation") == "" {
+		t.Error("legacy /login not marked deprecated")
+	}
+}
+
+func TestLegacyRoutes(t *testing.T) {
+	srv, st := newTestServer(t)
+	createUser(t, st, "alice", "secret")
+	c, _ := login(t, srv, "alice", "secret")
+
+	createScript(t, st, "alice", "s1", "python")
+	createScript(t, st, "alice", "s2", "python")
+
+	// L'alias garde sa réponse d'origine : un tableau, la page suivante dans X-Next-Cursor
+	resp, err := c.Get(srv.URL + "/scripts?limit=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scripts []struct {
+		ID string `json:"id"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&scripts)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || err != nil || len(scripts) != 1 || resp.Header.Get("X-Next-Cursor") == "" {
+		t.Fatalf("GET /scripts: %d %v %v, X-Next-Cursor %q", resp.StatusCode, scripts, err, resp.Header.Get("X-Next-Cursor"))
+	}
+	if got, want := resp.Header.Get("Deprecation"), fmt.Sprintf("@%d", legacyDeprecatedAt.Unix()); got != want {
+		t.Errorf("Deprecation %q, want %q", got, want)
+	}
+	if got, want := resp.Header.Get("Sunset"), DefaultConfig().LegacySunset.Format(http.TimeFormat); got != want {
+		t.Errorf("Sunset %q, want %q", got, want)
+	}
+	if got, want := resp.Header.Get("Link"), `</api/v1/scripts>; rel="successor-version"`; got != want {
+		t.Errorf("Link %q, want %q", got, want)
+	}
+
+	resp, err = c.Get(srv.URL + api.Prefix + "/scripts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Deprecation") != "" || resp.Header.Get("Sunset") != "" {
+		t.Errorf("versioned route marked deprecated: %v", resp.Header)
+	}
+
+	// Sans les alias, seule /api/v1 répond
+	app, _ := newTestApp(t)
+	app.config.LegacyRoutes = false
+	noLegacy := httptest.NewServer(app.Handler())
+	defer noLegacy.Close()
+	for path, want := range map[string]int{"/languages": http.StatusNotFound, api.Prefix + "/languages": http.StatusOK} {
+		resp, err := http.Get(noLegacy.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s without legacy routes: %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
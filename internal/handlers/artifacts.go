@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// Dossier en écriture monté dans chaque exécution, collecté à la fin
+const artifactMount = "/app/output"
+
+const (
+	// Limites de collecte par exécution
+	maxArtifactFiles = 100
+	maxArtifactBytes = 100 << 20
+	// Taille max de /app/output pendant l'exécution, au-delà le conteneur est tué ;
+	// le double de la collecte, pour que les fichiers en trop soient signalés plutôt que fatals
+	maxOutputBytes = 2 * maxArtifactBytes
+	// Durée de conservation par défaut (ARTIFACT_RETENTION)
+	defaultArtifactRetention = 7 * 24 * time.Hour
+	// Nombre d'exécutions par script dont les artifacts sont gardés
+	maxArtifactExecutions = 20
+	// Fréquence du nettoyage
+	artifactJanitorInterval = time.Hour
+)
+
+type Artifact struct {
+	ID        string `json:"id"`
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	URL       string `json:"url"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// outputDir reçoit les fichiers écrits par le conteneur pendant l'exécution
+func (a *App) outputDir(executionID string) string {
+	return filepath.Join(a.config.DataDir, "outputs", executionID)
+}
+
+// artifactDir contient les fichiers collectés après l'exécution
+func (a *App) artifactDir(executionID string) string {
+	return filepath.Join(a.config.DataDir, "artifacts", executionID)
+}
+
+// prepareOutputDir crée le dossier monté sur /app/output, réservé à l'utilisateur du conteneur
+func (a *App) prepareOutputDir(executionID string) (string, error) {
+	dir, err := filepath.Abs(a.outputDir(executionID))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	uid, gid := executionUser()
+	if err := os.Chown(dir, uid, gid); err != nil {
+		return "", err
+	}
+	// MkdirAll applique l'umask, et ne change pas un dossier laissé par une exécution détachée
+	return dir, os.Chmod(dir, 0700)
+}
+
+// collectArtifacts copie les fichiers réguliers de /app/output dans data/artifacts
+// en respectant les limites, puis supprime le dossier monté.
+// Les liens symboliques et fichiers spéciaux sont ignorés.
+func (a *App) collectArtifacts(executionID string, userID int) {
+	src := a.outputDir(executionID)
+	defer os.RemoveAll(src)
+
+	var skipped []string
+	var count int
+	var total int64
+	expiresAt := time.Now().Add(a.config.ArtifactRetention).UTC().Format(time.RFC3339)
+
+	filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(src, p)
+		rel = filepath.ToSlash(rel)
+
+		if count >= maxArtifactFiles || total+info.Size() > maxArtifactBytes {
+			skipped = append(skipped, rel)
+			return nil
+		}
+
+		dst := filepath.Join(a.artifactDir(executionID), filepath.FromSlash(rel))
+		size, err := copyArtifact(p, dst)
+		if err != nil {
+			skipped = append(skipped, rel)
+			return nil
+		}
+
+		err = a.store.Artifacts.Create(store.Artifact{
+			ID:          uuid.New().String(),
+			ExecutionID: executionID,
+			UserID:      userID,
+			Path:        rel,
+			Size:        size,
+			ExpiresAt:   expiresAt,
+		})
+		if err != nil {
+			os.Remove(dst)
+			return nil
+		}
+		count++
+		total += size
+		return nil
+	})
+
+	if len(skipped) > 0 {
+		a.storeLogs(executionID, "stderr", fmt.Sprintf(
+			"artifacts: %d file(s) not collected (limits: %d files, %d bytes): %s",
+			len(skipped), maxArtifactFiles, maxArtifactBytes, strings.Join(skipped, ", "),
+		))
+	}
+}
+
+// copyArtifact copie un fichier sans suivre de lien symbolique (O_NOFOLLOW via os.Root)
+func copyArtifact(src, dst string) (int64, error) {
+	root, err := os.OpenRoot(filepath.Dir(src))
+	if err != nil {
+		return 0, err
+	}
+	defer root.Close()
+	in, err := root.Open(filepath.Base(src))
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	if info, err := in.Stat(); err != nil || !info.Mode().IsRegular() {
+		return 0, fmt.Errorf("%s is not a regular file", src)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return 0, err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	// Le fichier a pu grossir depuis le parcours
+	return io.Copy(out, io.LimitReader(in, maxArtifactBytes))
+}
+
+// executionBelongsTo vérifie que l'exécution appartient à l'utilisateur
+func (a *App) executionBelongsTo(executionID string, userID int) bool {
+	_, err := a.store.Executions.GetForUser(executionID, userID)
+	return err == nil
+}
+
+// ListArtifactsHandler — GET /executions/{id}/artifacts
+func (a *App) ListArtifactsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	executionID := chi.URLParam(r, "id")
+
+	if !a.executionBelongsTo(executionID, userID) {
+		api.NotFoundErrorHandler(w, "Execution not found")
+		return
+	}
+
+	stored, err := a.store.Artifacts.List(executionID)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	artifacts := []Artifact{}
+	for _, st := range stored {
+		artifacts = append(artifacts, Artifact{
+			ID:        st.ID,
+			Path:      st.Path,
+			Size:      st.Size,
+			URL:       api.Prefix + "/executions/" + executionID + "/artifacts/" + st.ID,
+			CreatedAt: st.CreatedAt,
+			ExpiresAt: st.ExpiresAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(artifacts)
+}
+
+// DownloadArtifactHandler — GET /executions/{id}/artifacts/{artifactID}
+func (a *App) DownloadArtifactHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	executionID := chi.URLParam(r, "id")
+
+	if !a.executionBelongsTo(executionID, userID) {
+		api.NotFoundErrorHandler(w, "Execution not found")
+		return
+	}
+
+	artifact, err := a.store.Artifacts.Get(chi.URLParam(r, "artifactID"), executionID)
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Artifact not found")
+		return
+	}
+	p := artifact.Path
+
+	f, err := os.Open(filepath.Join(a.artifactDir(executionID), filepath.FromSlash(p)))
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Artifact not found")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(p)))
+	http.ServeContent(w, r, path.Base(p), info.ModTime(), f)
+}
+
+// DeleteArtifactsHandler — DELETE /executions/{id}/artifacts
+func (a *App) DeleteArtifactsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	executionID := chi.URLParam(r, "id")
+
+	if !a.executionBelongsTo(executionID, userID) {
+		api.NotFoundErrorHandler(w, "Execution not found")
+		return
+	}
+
+	a.store.Artifacts.DeleteByExecution(executionID)
+	os.RemoveAll(a.artifactDir(executionID))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StartArtifactJanitor supprime régulièrement les artifacts expirés
+func (a *App) StartArtifactJanitor(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(artifactJanitorInterval)
+		defer ticker.Stop()
+
+		for {
+			a.cleanArtifacts(time.Now().UTC())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// cleanArtifacts applique la rétention : durée max, nombre d'exécutions gardées par script,
+// et artifacts dont l'exécution a été supprimée
+func (a *App) cleanArtifacts(now time.Time) {
+	expired, err := a.store.Artifacts.Expired(now.Format(time.RFC3339), maxArtifactExecutions)
+	if err != nil {
+		a.log.Errorf("artifacts: %v", err)
+		return
+	}
+
+	for _, executionID := range expired {
+		a.store.Artifacts.DeleteByExecution(executionID)
+		os.RemoveAll(a.artifactDir(executionID))
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestPrepareOutputDir(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.config.DataDir = t.TempDir()
+
+	// Dossier laissé ouvert à tous par une version précédente
+	if err := os.MkdirAll(app.outputDir("e1"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(app.outputDir("e1"), 0777)
+
+	dir, err := app.prepareOutputDir("e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("mode %v, want 0700", info.Mode().Perm())
+	}
+	uid, gid := executionUser()
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && (int(st.Uid) != uid || int(st.Gid) != gid) {
+		t.Errorf("owner %d:%d, want %d:%d", st.Uid, st.Gid, uid, gid)
+	}
+}
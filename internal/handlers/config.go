@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"time"
+)
+
+// Config regroupe les réglages d'une instance de l'API
+type Config struct {
+	// Dossier des scripts, serveurs, volumes, builds et artefacts
+	DataDir string
+	// Cookie de session réservé à HTTPS
+	SecureCookies bool
+	// Durée de validité d'une session à partir de la connexion
+	SessionTTL time.Duration
+	// Domaine sous lequel les serveurs sont exposés (<name>.<domaine>), vide = désactivé
+	ProxyBaseDomain string
+	// URL publique de l'API (https://api.example.com) : jamais relayée, et page de connexion aux serveurs
+	ProxyAPIURL string
+	// Serveurs aussi exposés sur /apps/<name>/, dans un bac à sable CSP
+	ProxyPathMode bool
+	// Durée de conservation des artefacts
+	ArtifactRetention time.Duration
+	// Rétention des logs : durée et nombre d'exécutions terminées gardées par script
+	LogRetention     time.Duration
+	LogKeepPerScript int
+	// Durée max d'une exécution (build compris) et du build d'un serveur
+	ExecutionTimeout time.Duration
+	// Routes sans /api/v1, dépréciées, et date de retrait annoncée (zéro = pas d'en-tête Sunset)
+	LegacyRoutes bool
+	LegacySunset time.Time
+}
+
+// Les routes sans préfixe sont dépréciées depuis la sortie de /api/v1
+var legacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+func DefaultConfig() Config {
+	return Config{
+		DataDir:           "data",
+		SessionTTL:        defaultSessionTTL,
+		ArtifactRetention: defaultArtifactRetention,
+		LogRetention:      defaultLogRetention,
+		LogKeepPerScript:  defaultLogKeepPerScript,
+		ExecutionTimeout:  defaultExecutionTimeout,
+		LegacyRoutes:      true,
+		LegacySunset:      legacyDeprecatedAt.AddDate(0, 6, 0),
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+)
+
+func decodeError(t *testing.T, resp *http.Response) api.Error {
+	t.Helper()
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type %q, want application/json", ct)
+	}
+	var body api.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error.RequestID == "" || body.Error.RequestID != resp.Header.Get(api.RequestIDHeader) {
+		t.Fatalf("request_id %q, header %q", body.Error.RequestID, resp.Header.Get(api.RequestIDHeader))
+	}
+	return body.Error
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	srv, st := newTestServer(t)
+	createUser(t, st, "alice", "secret")
+	c, _ := login(t, srv, "alice", "secret")
+
+	tests := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/nope", http.StatusNotFound, api.CodeNotFound},
+		{api.Prefix + "/nope", http.StatusNotFound, api.CodeNotFound},
+		{api.Prefix + "/scripts/missing", http.StatusNotFound, api.CodeNotFound},
+		{api.Prefix + "/executions?limit=0&status=lost", http.StatusBadRequest, api.CodeValidationFailed},
+	}
+	for _, tt := range tests {
+		resp, err := c.Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e := decodeError(t, resp); resp.StatusCode != tt.status || e.Code != tt.code {
+			t.Errorf("GET %s: %d %s, want %d %s", tt.path, resp.StatusCode, e.Code, tt.status, tt.code)
+		}
+	}
+
+	// Sans session, et avec l'identifiant fourni par le client
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+api.Prefix+"/scripts", nil)
+	req.Header.Set(api.RequestIDHeader, "trace-42")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := decodeError(t, resp); resp.StatusCode != http.StatusUnauthorized || e.Code != api.CodeUnauthorized || e.RequestID != "trace-42" {
+		t.Errorf("GET /scripts without session: %d %+v", resp.StatusCode, e)
+	}
+
+	resp, err = c.Post(srv.URL+api.Prefix+"/scripts", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := decodeError(t, resp); resp.StatusCode != http.StatusMethodNotAllowed || e.Code != api.CodeMethodNotAllowed {
+		t.Errorf("POST /scripts: %d %+v", resp.StatusCode, e)
+	}
+}
+
+func TestValidationDetails(t *testing.T) {
+	srv, st := newTestServer(t)
+	createUser(t, st, "alice", "secret")
+	c, _ := login(t, srv, "alice", "secret")
+
+	resp, err := c.Get(srv.URL + api.Prefix + "/executions?sort=size")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := decodeError(t, resp)
+	if e.Code != api.CodeValidationFailed || len(e.Details) != 1 || e.Details[0].Field != "sort" {
+		t.Fatalf("GET /executions?sort=size: %+v", e)
+	}
+}
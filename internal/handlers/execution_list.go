@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/go-chi/chi"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+var (
+	executionStatuses = []string{"queued", "running", "success", "failed", "timed_out"}
+	executionTriggers = []string{TriggerManual, TriggerSchedule, TriggerWebhook}
+)
+
+// Tris possibles pour ?sort=
+var executionSorts = []string{"created_at", "duration_ms"}
+
+type ExecutionSummary struct {
+	ID         string  `json:"id"`
+	ScriptID   string  `json:"script_id"`
+	Status     string  `json:"status"`
+	Trigger    string  `json:"trigger"`
+	ExitCode   *int    `json:"exit_code"`
+	DurationMs *int64  `json:"duration_ms"`
+	StartedAt  *string `json:"started_at"`
+	FinishedAt *string `json:"finished_at"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+// pageCursor repère la dernière ligne renvoyée : valeur de tri puis id pour départager
+type pageCursor struct {
+	Key string `json:"k"`
+	ID  string `json:"id"`
+}
+
+func encodeCursor(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil || c.ID == "" {
+		return c, api.Invalid("cursor", "is invalid")
+	}
+	return c, nil
+}
+
+// pageSize lit ?limit= (50 par défaut, 200 max)
+func pageSize(r *http.Request) (int, error) {
+	limit := defaultPageSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			return 0, api.Invalid("limit", "must be between 1 and %d", maxPageSize)
+		}
+		limit = n
+	}
+	return limit, nil
+}
+
+// listValues découpe un filtre "a,b" et vérifie chaque valeur
+func listValues(param, value string, allowed []string) ([]string, error) {
+	values := strings.Split(value, ",")
+	for _, v := range values {
+		if !slices.Contains(allowed, v) {
+			return nil, api.Invalid(param, "%s is not supported (supported: %s)", v, strings.Join(allowed, ", "))
+		}
+	}
+	return values, nil
+}
+
+// ListExecutionsHandler — GET /executions
+// ?status=failed,timed_out&trigger=schedule&exit_code=1&since=&until=&sort=-created_at&limit=50&cursor=
+func (a *App) ListExecutionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	a.listExecutions(w, r, userID, r.URL.Query().Get("script_id"))
+}
+
+// ListScriptExecutionsHandler — GET /scripts/{id}/executions
+// Mêmes filtres que GET /executions
+func (a *App) ListScriptExecutionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	scriptID := chi.URLParam(r, "id")
+
+	if _, err := a.loadScript(scriptID, userID); err != nil {
+		api.NotFoundErrorHandler(w, "Script not found")
+		return
+	}
+	a.listExecutions(w, r, userID, scriptID)
+}
+
+func (a *App) listExecutions(w http.ResponseWriter, r *http.Request, userID int, scriptID string) {
+	query := r.URL.Query()
+	f := store.ExecutionFilter{UserID: userID, ScriptID: scriptID}
+
+	if v := query.Get("status"); v != "" {
+		statuses, err := listValues("status", v, executionStatuses)
+		if err != nil {
+			api.RequestErrorHandler(w, err)
+			return
+		}
+		f.Statuses = statuses
+	}
+	if v := query.Get("trigger"); v != "" {
+		triggers, err := listValues("trigger", v, executionTriggers)
+		if err != nil {
+			api.RequestErrorHandler(w, err)
+			return
+		}
+		f.Triggers = triggers
+	}
+	if v := query.Get("exit_code"); v != "" {
+		code, err := strconv.Atoi(v)
+		if err != nil {
+			api.RequestErrorHandler(w, api.Invalid("exit_code", "must be a number"))
+			return
+		}
+		f.ExitCode = &code
+	}
+	for _, bound := range []struct {
+		param string
+		t     *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		v := query.Get(bound.param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			api.RequestErrorHandler(w, api.Invalid(bound.param, "must be an RFC3339 date"))
+			return
+		}
+		*bound.t = t
+	}
+
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = "-created_at"
+	}
+	f.Sort, f.Desc = strings.CutPrefix(sort, "-")
+	if !slices.Contains(executionSorts, f.Sort) {
+		api.RequestErrorHandler(w, api.Invalid("sort", "%s is not supported (supported: created_at, duration_ms, prefixed with - for descending)", sort))
+		return
+	}
+
+	limit, err := pageSize(r)
+	if err != nil {
+		api.RequestErrorHandler(w, err)
+		return
+	}
+	f.Limit = limit
+	if v := query.Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+		if err != nil {
+			api.RequestErrorHandler(w, err)
+			return
+		}
+		f.After = (*store.Cursor)(&c)
+	}
+
+	stored, cursor, err := a.store.Executions.List(f)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		api.RequestErrorHandler(w, api.Invalid("cursor", "is invalid"))
+		return
+	}
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	executions := []ExecutionSummary{}
+	for _, e := range stored {
+		summary := ExecutionSummary{
+			ID: e.ID, ScriptID: e.ScriptID, Status: e.Status, Trigger: e.Trigger, ExitCode: e.ExitCode,
+			StartedAt: e.StartedAt, FinishedAt: e.FinishedAt, CreatedAt: e.CreatedAt,
+		}
+		if e.Metrics != nil {
+			summary.DurationMs = &e.Metrics.DurationMs
+		}
+		executions = append(executions, summary)
+	}
+
+	var next *string
+	if cursor != nil {
+		c := encodeCursor(pageCursor(*cursor))
+		next = &c
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Executions []ExecutionSummary `json:"executions"`
+		NextCursor *string            `json:"next_cursor"`
+	}{executions, next})
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+)
+
+// executionPage lit une page de GET /executions : ids dans l'ordre, curseur suivant et statut
+func executionPage(t *testing.T, c *http.Client, target string) ([]string, *string, int) {
+	t.Helper()
+	resp, err := c.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var page struct {
+		Executions []ExecutionSummary `json:"executions"`
+		NextCursor *string            `json:"next_cursor"`
+	}
+	json.NewDecoder(resp.Body).Decode(&page)
+	ids := []string{}
+	for _, e := range page.Executions {
+		ids = append(ids, e.ID)
+	}
+	return ids, page.NextCursor, resp.StatusCode
+}
+
+// Les exécutions sont créées dans l'ordre de leurs ids, le plus souvent dans la même seconde : à égalité de created_at, l'id départage
+func seedExecutions(t *testing.T, st *store.Store) {
+	t.Helper()
+	createUser(t, st, "alice", "secret")
+	createUser(t, st, "bob", "secret")
+	createScript(t, st, "alice", "s1", "python")
+	createScript(t, st, "alice", "s2", "python")
+	createScript(t, st, "bob", "b1", "python")
+	alice, _ := st.Users.GetByUsername("alice")
+	bob, _ := st.Users.GetByUsername("bob")
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, e := range []struct {
+		id, script, trigger, status string
+		userID, exitCode            int
+		durationMs                  int64
+	}{
+		{"e1", "s1", TriggerManual, "success", alice.ID, 0, 300},
+		{"e2", "s1", TriggerSchedule, "failed", alice.ID, 1, 100},
+		{"e3", "s2", TriggerWebhook, "failed", alice.ID, 2, 200},
+		{"e4", "s1", TriggerManual, "queued", alice.ID, 0, 0},
+		{"e5", "s2", TriggerManual, "timed_out", alice.ID, 137, 100},
+		{"b-1", "b1", TriggerManual, "failed", bob.ID, 1, 100},
+	} {
+		if e.status == "queued" {
+			if err := st.Executions.Create(store.Execution{ID: e.id, ScriptID: e.script, UserID: e.userID, Status: "queued", Trigger: e.trigger}); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := st.Executions.Create(store.Execution{ID: e.id, ScriptID: e.script, UserID: e.userID, Status: "running", Trigger: e.trigger, StartedAt: &now}); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Executions.Finish(e.id, e.status, e.exitCode, now); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Executions.SetMetrics(e.id, store.Metrics{DurationMs: e.durationMs}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListExecutionsFilters(t *testing.T) {
+	srv, st := newTestServer(t)
+	seedExecutions(t, st)
+	c, _ := login(t, srv, "alice", "secret")
+
+	hour := url.QueryEscape(time.Now().UTC().Add(-time.Hour).Format(time.RFC3339))
+	later := url.QueryEscape(time.Now().UTC().Add(time.Hour).Format(time.RFC3339))
+	for query, want := range map[string][]string{
+		"":                          {"e5", "e4", "e3", "e2", "e1"},
+		"?status=failed":            {"e3", "e2"},
+		"?status=failed,timed_out":  {"e5", "e3", "e2"},
+		"?trigger=schedule,webhook": {"e3", "e2"},
+		"?exit_code=1":              {"e2"},
+		"?script_id=s2":             {"e5", "e3"},
+		"?since=" + hour:            {"e5", "e4", "e3", "e2", "e1"},
+		"?since=" + later:           {},
+		"?until=" + hour:            {},
+		"?until=" + later:           {"e5", "e4", "e3", "e2", "e1"},
+		"?sort=created_at":          {"e1", "e2", "e3", "e4", "e5"},
+		// Sans durée (-1) en premier ; e2 et e5 à égalité, départagés par id
+		"?sort=duration_ms":  {"e4", "e2", "e5", "e3", "e1"},
+		"?sort=-duration_ms": {"e1", "e3", "e5", "e2", "e4"},
+	} {
+		ids, next, code := executionPage(t, c, srv.URL+api.Prefix+"/executions"+query)
+		if code != http.StatusOK || !slices.Equal(ids, want) || next != nil {
+			t.Errorf("GET /executions%s: %d %v (next %v), want %v", query, code, ids, next, want)
+		}
+	}
+
+	if ids, _, code := executionPage(t, c, srv.URL+api.Prefix+"/scripts/s1/executions?trigger=manual"); code != http.StatusOK || !slices.Equal(ids, []string{"e4", "e1"}) {
+		t.Errorf("GET /scripts/s1/executions: %d %v", code, ids)
+	}
+	if _, _, code := executionPage(t, c, srv.URL+api.Prefix+"/scripts/b1/executions"); code != http.StatusNotFound {
+		t.Errorf("another user's script: %d, want 404", code)
+	}
+	for _, query := range []string{"?status=lost", "?trigger=cron", "?exit_code=one", "?since=yesterday", "?sort=name", "?limit=201"} {
+		if _, _, code := executionPage(t, c, srv.URL+api.Prefix+"/executions"+query); code != http.StatusBadRequest {
+			t.Errorf("GET /executions%s: %d, want 400", query, code)
+		}
+	}
+}
+
+func TestListExecutionsPaging(t *testing.T) {
+	srv, st := newTestServer(t)
+	seedExecutions(t, st)
+	c, _ := login(t, srv, "alice", "secret")
+	list := srv.URL + api.Prefix + "/executions"
+
+	for _, sort := range []string{"created_at", "-created_at", "duration_ms", "-duration_ms"} {
+		all, _, _ := executionPage(t, c, list+"?sort="+sort)
+
+		var got []string
+		pages := 0
+		for cursor := ""; ; {
+			ids, next, code := executionPage(t, c, list+"?limit=2&sort="+sort+cursor)
+			if code != http.StatusOK {
+				t.Fatalf("sort %s, page %d: %d", sort, pages, code)
+			}
+			pages++
+			got = append(got, ids...)
+			if next == nil {
+				break
+			}
+			cursor = "&cursor=" + *next
+		}
+		// 5 exécutions par 2 : la dernière page est incomplète et sans suite
+		if !slices.Equal(got, all) || pages != 3 {
+			t.Errorf("sort %s: %v in %d pages, want %v in 3", sort, got, pages, all)
+		}
+	}
+
+	// Page exactement pleine : pas de page suivante vide
+	if ids, next, _ := executionPage(t, c, list+"?limit=5"); len(ids) != 5 || next != nil {
+		t.Errorf("full page: %v, next %v", ids, next)
+	}
+	if ids, next, _ := executionPage(t, c, list+"?limit=4"); len(ids) != 4 || next == nil {
+		t.Errorf("page before the last: %v, next %v", ids, next)
+	} else if ids, next, _ := executionPage(t, c, list+"?limit=4&cursor="+*next); !slices.Equal(ids, []string{"e1"}) || next != nil {
+		t.Errorf("last page: %v, next %v", ids, next)
+	}
+}
+
+func TestListExecutionsInvalidCursor(t *testing.T) {
+	srv, st := newTestServer(t)
+	seedExecutions(t, st)
+	c, _ := login(t, srv, "alice", "secret")
+
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	for name, query := range map[string]string{
+		"not base64":        "?cursor=***",
+		"not json":          "?cursor=" + raw("e1"),
+		"no id":             "?cursor=" + raw(`{"k":"2026-01-02 03:04:05"}`),
+		"malformed date":    "?cursor=" + raw(`{"k":"yesterday","id":"e1"}`),
+		"RFC3339 date":      "?cursor=" + raw(`{"k":"2026-01-02T03:04:05Z","id":"e1"}`),
+		"date for duration": "?sort=duration_ms&cursor=" + raw(`{"k":"2026-01-02 03:04:05","id":"e1"}`),
+	} {
+		if _, _, code := executionPage(t, c, srv.URL+api.Prefix+"/executions"+query); code != http.StatusBadRequest {
+			t.Errorf("%s: %d, want 400", name, code)
+		}
+	}
+	if _, _, code := executionPage(t, c, srv.URL+api.Prefix+"/executions?cursor="+raw(`{"k":"2026-01-02 03:04:05","id":"e1"}`)); code != http.StatusOK {
+		t.Errorf("valid cursor: %d", code)
+	}
+}
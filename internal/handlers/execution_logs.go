@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/google/uuid"
+)
+
+const (
+	// Taille max des logs stockés par exécution, au-delà un marqueur de troncature est ajouté
+	maxExecutionLogBytes = 10 << 20
+	// Taille max d'une ligne de la table logs
+	logChunkBytes = 64 << 10
+	// Les logs des exécutions terminées depuis plus longtemps sont compressés
+	logCompressAfter = 24 * time.Hour
+	// Rétention par défaut (LOG_RETENTION, LOG_KEEP_PER_SCRIPT)
+	defaultLogRetention     = 30 * 24 * time.Hour
+	defaultLogKeepPerScript = 100
+	// Fréquence du nettoyage et nombre de lignes compressées par passage
+	logJanitorInterval = time.Hour
+	logCompressBatch   = 500
+)
+
+// logCapture garde le début et la fin d'une sortie de conteneur dans une mémoire bornée
+type logCapture struct {
+	limit int
+	head  []byte
+	tail  []byte
+	total int64
+}
+
+func newLogCapture(limit int) *logCapture {
+	return &logCapture{limit: limit}
+}
+
+func (c *logCapture) Write(p []byte) (int, error) {
+	n := len(p)
+	c.total += int64(n)
+	half := c.limit / 2
+
+	if room := half - len(c.head); room > 0 {
+		room = min(room, len(p))
+		c.head = append(c.head, p[:room]...)
+		p = p[room:]
+	}
+	c.tail = append(c.tail, p...)
+	// Recopie seulement quand le tampon a doublé
+	if len(c.tail) > 2*(c.limit-half) {
+		c.tail = append([]byte(nil), c.tail[len(c.tail)-(c.limit-half):]...)
+	}
+	return n, nil
+}
+
+// String retourne la sortie, avec un marqueur à la place de la partie coupée
+func (c *logCapture) String() string {
+	tail := c.tail
+	if keep := c.limit - len(c.head); len(tail) > keep {
+		tail = tail[len(tail)-keep:]
+	}
+	omitted := c.total - int64(len(c.head)) - int64(len(tail))
+	if omitted <= 0 {
+		return string(c.head) + string(tail)
+	}
+	return strings.ToValidUTF8(string(c.head), "") +
+		fmt.Sprintf("\n[... %d bytes truncated ...]\n", omitted) +
+		strings.ToValidUTF8(string(tail), "")
+}
+
+// cutUTF8 coupe s à n octets au plus sans couper de caractère
+func cutUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// storeLogs ajoute une sortie aux logs de l'exécution, découpée en lignes de logChunkBytes.
+// Au-delà de maxExecutionLogBytes par exécution, la sortie est tronquée avec un marqueur.
+func (a *App) storeLogs(executionID, stream, content string) {
+	if content == "" {
+		return
+	}
+	used, seq, err := a.store.Logs.Usage(executionID)
+	if err != nil {
+		return
+	}
+
+	remaining := maxExecutionLogBytes - used
+	if remaining <= 0 {
+		return
+	}
+	if int64(len(content)) > remaining {
+		marker := fmt.Sprintf("\n[... log limit of %d bytes reached, %d bytes dropped ...]", maxExecutionLogBytes, int64(len(content))-remaining)
+		content = cutUTF8(content, int(max(remaining-int64(len(marker)), 0))) + marker
+	}
+
+	// chunk > 0 : suite de la même sortie, recollée à la lecture
+	var chunks []store.LogChunk
+	for chunk := 0; content != ""; chunk++ {
+		part := cutUTF8(content, logChunkBytes)
+		if part == "" {
+			part = content[:min(len(content), logChunkBytes)]
+		}
+		content = content[len(part):]
+		seq++
+
+		chunks = append(chunks, store.LogChunk{
+			ID:          uuid.New().String(),
+			ExecutionID: executionID,
+			Stream:      stream,
+			Content:     part,
+			Seq:         seq,
+			Chunk:       chunk,
+			Size:        int64(len(part)),
+		})
+	}
+	if err := a.store.Logs.Append(chunks); err != nil {
+		a.log.Errorf("logs: execution %s: %v", executionID, err)
+	}
+}
+
+// LogEntry est une sortie d'exécution ; Seq est le numéro de son dernier morceau,
+// à repasser dans ?after= pour ne relire que les sorties suivantes
+type LogEntry struct {
+	Stream    string `json:"stream"`
+	Content   string `json:"content"`
+	CreatedAt string `json:"created_at"`
+	Seq       int64  `json:"seq"`
+}
+
+// readLogs relit les logs d'une exécution (un stream ou tous si vide) après afterSeq,
+// décompresse les lignes compressées et recolle les morceaux d'une même sortie
+func (a *App) readLogs(executionID, stream string, afterSeq int64) ([]LogEntry, error) {
+	chunks, err := a.store.Logs.List(executionID, stream, afterSeq)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := []LogEntry{}
+	for _, c := range chunks {
+		l := LogEntry{Stream: c.Stream, Content: c.Content, CreatedAt: c.CreatedAt, Seq: c.Seq}
+		if c.Compressed {
+			if l.Content, err = gunzip(c.Data); err != nil {
+				return nil, err
+			}
+		}
+
+		if last := len(logs) - 1; c.Chunk > 0 && last >= 0 && logs[last].Stream == l.Stream {
+			logs[last].Content += l.Content
+			logs[last].Seq = l.Seq
+			continue
+		}
+		logs = append(logs, l)
+	}
+	return logs, nil
+}
+
+func gzipString(s string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(zw, s); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzip(data []byte) (string, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+	content, err := io.ReadAll(zr)
+	return string(content), err
+}
+
+// StartLogJanitor applique régulièrement la rétention des logs et compresse les anciens
+func (a *App) StartLogJanitor(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(logJanitorInterval)
+		defer ticker.Stop()
+
+		for {
+			a.cleanLogs(time.Now().UTC())
+			a.compressLogs(time.Now().UTC())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// cleanLogs supprime les logs trop anciens, ceux des exécutions terminées au-delà des logKeepPerScript
+// dernières de leur script, et ceux des exécutions supprimées
+func (a *App) cleanLogs(now time.Time) {
+	err := a.store.Logs.DeleteExpired(now.Add(-a.config.LogRetention).Format(time.RFC3339), a.config.LogKeepPerScript)
+	if err != nil {
+		a.log.Errorf("logs: %v", err)
+	}
+}
+
+// compressLogs compresse (gzip) les logs des exécutions terminées depuis logCompressAfter
+func (a *App) compressLogs(now time.Time) {
+	for {
+		chunks, err := a.store.Logs.Uncompressed(now.Add(-logCompressAfter).Format(time.RFC3339), logCompressBatch)
+		if err != nil {
+			a.log.Errorf("logs: %v", err)
+			return
+		}
+
+		for _, c := range chunks {
+			// Une ligne mal compressée reste en clair : elle sera reprise au passage suivant
+			data, err := gzipString(c.Content)
+			if err != nil {
+				a.log.Errorf("logs: compress %s: %v", c.ID, err)
+				return
+			}
+			if err := a.store.Logs.Compress(c.ID, data); err != nil {
+				a.log.Errorf("logs: %v", err)
+				return
+			}
+		}
+		if len(chunks) < logCompressBatch {
+			return
+		}
+	}
+}
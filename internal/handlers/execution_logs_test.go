@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+)
+
+func TestLogCapture(t *testing.T) {
+	c := newLogCapture(10)
+	c.Write([]byte("0123"))
+	if got := c.String(); got != "0123" {
+		t.Errorf("under the limit: %q", got)
+	}
+	c.Write([]byte("456789"))
+	if got := c.String(); got != "0123456789" {
+		t.Errorf("at the limit: %q", got)
+	}
+
+	// Écritures nombreuses : seuls le début et la fin sont gardés, en mémoire bornée
+	for i := 0; i < 1000; i++ {
+		c.Write([]byte("abcdefghij"))
+	}
+	if got, want := c.String(), "01234\n[... 10000 bytes truncated ...]\nfghij"; got != want {
+		t.Errorf("over the limit: %q, want %q", got, want)
+	}
+	if len(c.tail) > 2*5 {
+		t.Errorf("tail buffer of %d bytes for a limit of 10", len(c.tail))
+	}
+
+	// La coupure ne laisse pas de caractère incomplet
+	c = newLogCapture(6)
+	c.Write([]byte("ééééééé"))
+	got := c.String()
+	if !utf8.ValidString(got) || !strings.HasPrefix(got, "é\n[... ") || !strings.HasSuffix(got, "\né") {
+		t.Errorf("multibyte output: %q", got)
+	}
+}
+
+func TestCutUTF8(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello", 3, "hel"},
+		{"héllo", 2, "h"},
+		{"héllo", 3, "hé"},
+		{"日本", 4, "日"},
+		{"日本", 2, ""},
+		{"", 0, ""},
+	} {
+		if got := cutUTF8(tc.in, tc.n); got != tc.want {
+			t.Errorf("cutUTF8(%q, %d) = %q, want %q", tc.in, tc.n, got, tc.want)
+		}
+	}
+}
+
+// newLogExecution enregistre une exécution terminée à finishedAt pour le script "logs"
+func newLogExecution(t *testing.T, st *store.Store, id string, finishedAt time.Time) {
+	t.Helper()
+	user, _ := st.Users.GetByUsername("alice")
+	at := finishedAt.UTC().Format(time.RFC3339)
+	if err := st.Executions.Create(store.Execution{ID: id, ScriptID: "logs", UserID: user.ID, Status: "running", Trigger: TriggerManual, StartedAt: &at}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Executions.Finish(id, "success", 0, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLogChunks(t *testing.T) {
+	app, st := newTestApp(t)
+	createUser(t, st, "alice", "secret")
+	createScript(t, st, "alice", "logs", "python")
+	newLogExecution(t, st, "e1", time.Now())
+
+	// Plus de deux lignes de la table, coupées au milieu d'un caractère de 2 octets
+	stdout := strings.Repeat("é", logChunkBytes+10)
+	app.storeLogs("e1", "stdout", stdout)
+	app.storeLogs("e1", "stderr", "warning")
+	app.storeLogs("e1", "stdout", "done")
+
+	chunks, err := st.Logs.List("e1", "", 0)
+	if err != nil || len(chunks) != 5 {
+		t.Fatalf("%d chunks, %v", len(chunks), err)
+	}
+	for _, c := range chunks {
+		if c.Size > logChunkBytes || !utf8.ValidString(c.Content) {
+			t.Errorf("chunk %d of %d bytes, valid UTF-8 %v", c.Seq, c.Size, utf8.ValidString(c.Content))
+		}
+	}
+
+	check := func(step string) {
+		t.Helper()
+		logs, err := app.readLogs("e1", "", 0)
+		if err != nil || len(logs) != 3 || logs[0].Content != stdout || logs[1].Content != "warning" || logs[2].Content != "done" {
+			t.Fatalf("%s: %d entries, %v", step, len(logs), err)
+		}
+		logs, err = app.readLogs("e1", "stdout", 0)
+		if err != nil || len(logs) != 2 || logs[0].Content != stdout {
+			t.Fatalf("%s, stdout only: %d entries, %v", step, len(logs), err)
+		}
+	}
+	check("plain")
+
+	// Compressées le lendemain, les lignes se relisent à l'identique
+	app.compressLogs(time.Now().Add(logCompressAfter + time.Minute))
+	chunks, _ = st.Logs.List("e1", "", 0)
+	for _, c := range chunks {
+		if !c.Compressed || c.Content != "" {
+			t.Fatalf("chunk %d not compressed", c.Seq)
+		}
+	}
+	check("compressed")
+}
+
+func TestLogLimit(t *testing.T) {
+	app, st := newTestApp(t)
+	createUser(t, st, "alice", "secret")
+	createScript(t, st, "alice", "logs", "python")
+	newLogExecution(t, st, "e1", time.Now())
+
+	app.storeLogs("e1", "stdout", strings.Repeat("x", maxExecutionLogBytes-10))
+	app.storeLogs("e1", "stderr", strings.Repeat("y", 1000))
+	app.storeLogs("e1", "stderr", "after the limit")
+
+	// Seul le marqueur de troncature peut dépasser la limite
+	used, _, err := st.Logs.Usage("e1")
+	if err != nil || used < maxExecutionLogBytes-10 || used > maxExecutionLogBytes+100 {
+		t.Fatalf("stored %d bytes, %v", used, err)
+	}
+	logs, err := app.readLogs("e1", "stderr", 0)
+	if err != nil || len(logs) != 1 || !strings.Contains(logs[0].Content, "log limit of") || strings.Contains(logs[0].Content, "after the limit") {
+		t.Fatalf("stderr: %+v, %v", logs, err)
+	}
+}
+
+func TestLogRetention(t *testing.T) {
+	app, st := newTestApp(t)
+	app.config.LogRetention = 30 * 24 * time.Hour
+	app.config.LogKeepPerScript = 3
+	createUser(t, st, "alice", "secret")
+	createScript(t, st, "alice", "logs", "python")
+	now := time.Now()
+
+	// Par âge : terminée il y a 31 jours
+	newLogExecution(t, st, "e0", now.Add(-31*24*time.Hour))
+	// Par nombre : les 3 dernières terminées du script sont gardées
+	for _, id := range []string{"e1", "e2", "e3", "e4"} {
+		newLogExecution(t, st, id, now.Add(-time.Hour))
+	}
+	// En cours : jamais supprimée, et ne compte pas parmi les 3
+	user, _ := st.Users.GetByUsername("alice")
+	at := now.UTC().Format(time.RFC3339)
+	if err := st.Executions.Create(store.Execution{ID: "e5", ScriptID: "logs", UserID: user.ID, Status: "running", Trigger: TriggerManual, StartedAt: &at}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"e0", "e1", "e2", "e3", "e4", "e5"} {
+		app.storeLogs(id, "stdout", "output of "+id)
+	}
+
+	app.cleanLogs(now)
+	for id, kept := range map[string]bool{"e0": false, "e1": false, "e2": true, "e3": true, "e4": true, "e5": true} {
+		logs, err := app.readLogs(id, "", 0)
+		if err != nil || (len(logs) == 1) != kept {
+			t.Errorf("%s: %d entries, %v, want kept %v", id, len(logs), err, kept)
+		}
+	}
+	// Les exécutions restent, seuls leurs logs sont supprimés
+	if _, err := st.Executions.Get("e0"); err != nil {
+		t.Errorf("execution e0: %v", err)
+	}
+}
+
+func TestLogsAfter(t *testing.T) {
+	app, st := newTestApp(t)
+	srv := httptest.NewServer(app.Handler())
+	defer srv.Close()
+	createUser(t, st, "alice", "secret")
+	createScript(t, st, "alice", "logs", "python")
+	newLogExecution(t, st, "e1", time.Now())
+	c, _ := login(t, srv, "alice", "secret")
+
+	read := func(query string) ([]LogEntry, int) {
+		t.Helper()
+		resp, err := c.Get(srv.URL + api.Prefix + "/executions/e1/logs" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var logs []LogEntry
+		json.NewDecoder(resp.Body).Decode(&logs)
+		return logs, resp.StatusCode
+	}
+
+	// Sortie en plusieurs morceaux : son seq est celui du dernier
+	app.storeLogs("e1", "build", "step 1")
+	app.storeLogs("e1", "stdout", strings.Repeat("x", logChunkBytes+1))
+	logs, code := read("")
+	if code != http.StatusOK || len(logs) != 2 || logs[0].Seq != 1 || logs[1].Seq != 3 {
+		t.Fatalf("all logs: %d %d entries", code, len(logs))
+	}
+	if logs, _ := read("?after=3"); len(logs) != 0 {
+		t.Errorf("nothing new: %d entries", len(logs))
+	}
+
+	app.storeLogs("e1", "stderr", "done")
+	logs, _ = read("?after=3")
+	if len(logs) != 1 || logs[0].Content != "done" || logs[0].Seq != 4 {
+		t.Fatalf("after 3: %+v", logs)
+	}
+	if logs, _ := read("?after=1"); len(logs) != 2 || len(logs[0].Content) != logChunkBytes+1 {
+		t.Errorf("after 1: %d entries", len(logs))
+	}
+	for _, query := range []string{"?after=-1", "?after=last"} {
+		if _, code := read(query); code != http.StatusBadRequest {
+			t.Errorf("GET /executions/e1/logs%s: %d, want 400", query, code)
+		}
+	}
+}
@@ -3,56 +3,115 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/images"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/languages"
 	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
 )
 
+// Origine d'une exécution
+const (
+	TriggerManual   = "manual"
+	TriggerSchedule = "schedule"
+	TriggerWebhook  = "webhook"
+)
+
+// executionRequest décrit l'origine et les entrées d'une exécution
+type executionRequest struct {
+	Trigger    string
+	ScheduleID string
+	TriggerID  string
+	Stdin      []byte
+	Env        []string
+}
+
+// scriptRun regroupe ce qu'il faut pour lancer un script
+type scriptRun struct {
+	ID          string
+	UserID      int
+	DockerImage string
+	FilePath    string
+	Language    string
+	Mounts      []VolumeMount
+}
+
+func (a *App) loadScript(scriptID string, userID int) (scriptRun, error) {
+	s, err := a.store.Scripts.Get(scriptID, userID)
+	return scriptRun{
+		ID:          scriptID,
+		UserID:      userID,
+		DockerImage: s.DockerImage,
+		FilePath:    s.FilePath,
+		Language:    s.Language,
+		Mounts:      parseMounts(s.Mounts),
+	}, err
+}
+
+// startExecution crée l'exécution en base dans les quotas du plan puis lance le conteneur en arrière-plan
+func (a *App) startExecution(script scriptRun, req executionRequest) (string, error) {
+	if !a.track() {
+		return "", errShuttingDown
+	}
+
+	executionID := uuid.New().String()
+	startedAt := time.Now().UTC().Format(time.RFC3339)
+	err := a.quotas.StartExecution(store.Execution{
+		ID:         executionID,
+		ScriptID:   script.ID,
+		UserID:     script.UserID,
+		Trigger:    req.Trigger,
+		ScheduleID: optional(req.ScheduleID),
+		TriggerID:  optional(req.TriggerID),
+		StartedAt:  &startedAt,
+	})
+	if err != nil {
+		a.running.Done()
+		return "", err
+	}
+	a.store.Scripts.SetLastRun(script.ID, startedAt)
+	a.emitExecutionEvent(executionID, EventExecutionStarted)
+
+	go a.runContainer(executionID, script, req, nil)
+	return executionID, nil
+}
+
 // RunScriptHandler — POST /scripts/{id}/run
-func RunScriptHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) RunScriptHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(int)
 	scriptID := chi.URLParam(r, "id")
 
 	// Récupérer le script
-	type Script struct {
-		DockerImage string
-		FilePath    string
-		Language    string
-	}
-	var script Script
-	err := db.QueryRow(
-		`SELECT docker_image, file_path, language FROM scripts WHERE id = ? AND user_id = ?`,
-		scriptID, userID,
-	).Scan(&script.DockerImage, &script.FilePath, &script.Language)
+	script, err := a.loadScript(scriptID, userID)
 	if err != nil {
-		http.Error(w, "Script not found", http.StatusNotFound)
+		api.NotFoundErrorHandler(w, "Script not found")
 		return
 	}
 
-	// Créer l'exécution en base
-	executionID := uuid.New().String()
-	_, err = db.Exec(
-		`INSERT INTO executions (id, script_id, user_id, status, started_at) VALUES (?, ?, ?, 'running', ?)`,
-		executionID, scriptID, userID, time.Now().UTC().Format(time.RFC3339),
-	)
-	if err != nil {
-		api.InternalErrorHandler(w)
+	// Créer l'exécution et lancer le conteneur
+	executionID, err := a.startExecution(script, executionRequest{Trigger: TriggerManual})
+	if quotaError(w, err) {
+		return
+	} else if err != nil {
+		a.internalError(w, err)
 		return
 	}
 
-	// Lancer le conteneur en arrière-plan
-	go runContainer(executionID, script.DockerImage, script.FilePath, script.Language)
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{
@@ -61,116 +120,443 @@ func RunScriptHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// runContainer lance le script dans Docker et stocke les logs
-func runContainer(executionID, dockerImage, filePath, language string) {
+// runContainer lance le script dans Docker et stocke les logs.
+// Avec resume, reprend l'exécution à l'étape enregistrée avant l'arrêt de l'API.
+func (a *App) runContainer(executionID string, script scriptRun, req executionRequest, resume *store.Checkpoint) {
+	defer a.running.Done()
 	ctx := context.Background()
 
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	lang, ok := a.languages.Get(script.Language)
+	if !ok {
+		a.storeLogs(executionID, "stderr", fmt.Sprintf("language %s is not supported anymore", script.Language))
+		a.updateExecution(executionID, "failed", -1)
+		return
+	}
+
+	cli := a.runtime
+
+	// Le build et l'exécution partagent la même durée max
+	deadline := time.Now().Add(a.config.ExecutionTimeout)
+
+	// Consommation cumulée du build et de l'exécution
+	var metrics ExecutionMetrics
+
+	if resume != nil {
+		deadline, _ = time.Parse(time.RFC3339, resume.Deadline)
+		if e, err := a.store.Executions.Get(executionID); err == nil && e.Metrics != nil {
+			metrics = ExecutionMetrics(*e.Metrics)
+		}
+	}
+
+	// Les images des langages sont téléchargées au premier lancement
+	pullCtx, cancel := context.WithDeadline(ctx, deadline)
+	err := images.Ensure(pullCtx, cli, script.DockerImage)
+	cancel()
 	if err != nil {
-		updateExecution(executionID, "failed", -1)
+		a.storeLogs(executionID, "stderr", fmt.Sprintf("image %s can't be pulled: %v", script.DockerImage, err))
+		a.updateExecution(executionID, "failed", -1)
 		return
 	}
-	defer cli.Close()
 
-	// Commande selon le langage
-	ext := filepath.Ext(filePath)
-	var cmd []string
-	switch language {
-	case "python":
-		cmd = []string{"python", "/app/script" + ext}
-	case "bash":
-		cmd = []string{"bash", "/app/script" + ext}
-	case "nodejs", "js":
-		cmd = []string{"node", "/app/script" + ext}
-	default:
-		cmd = []string{"sh", "/app/script" + ext}
+	// Lance le conteneur de l'étape, ou retrouve celui laissé à l'arrêt de l'API
+	start := func(phase containerPhase) (int64, phaseOutput, error) {
+		if resume != nil && resume.Phase == phase.Phase {
+			exitCode, logs, err := resumePhase(ctx, cli, phase)
+			// Un build peut être relancé, pas une exécution qui a peut-être déjà eu des effets
+			retry := errors.Is(err, errContainerNotStarted) || (errors.Is(err, errContainerGone) && phase.Phase == phaseBuild)
+			if !retry {
+				return exitCode, logs, err
+			}
+		}
+		err := a.store.Executions.SaveCheckpoint(store.Checkpoint{
+			ExecutionID: executionID,
+			Phase:       phase.Phase,
+			Deadline:    deadline.UTC().Format(time.RFC3339),
+			Env:         req.Env,
+			Stdin:       req.Stdin,
+		})
+		if err != nil {
+			return -1, phaseOutput{}, err
+		}
+		return runPhase(ctx, cli, phase)
 	}
 
+	// Le dossier de build et les fichiers produits restent en place si le conteneur est détaché
+	detached := false
+
 	// Chemin absolu pour le bind mount
-	absPath, _ := filepath.Abs(filePath)
+	absPath, _ := filepath.Abs(script.FilePath)
+	binds := []string{absPath + ":" + lang.ScriptPath() + ":ro"}
+
+	// Volumes persistants, montés seulement pour l'exécution (pas pour le build)
+	volumeBinds, volumeLimits, err := a.mountBinds(script.UserID, script.Mounts)
+	if err != nil {
+		a.storeLogs(executionID, "stderr", err.Error())
+		a.updateExecution(executionID, "failed", -1)
+		return
+	}
+
+	// Phase de build pour les langages compilés
+	if lang.Compiled() {
+		buildDir, err := filepath.Abs(filepath.Join(a.config.DataDir, "builds", executionID))
+		if err == nil {
+			err = os.MkdirAll(buildDir, 0755)
+		}
+		if err != nil {
+			a.updateExecution(executionID, "failed", -1)
+			return
+		}
+		defer func() {
+			if !detached {
+				os.RemoveAll(buildDir)
+			}
+		}()
+
+		// Déjà construit si l'exécution reprend au conteneur du script
+		if resume == nil || resume.Phase == phaseBuild {
+			exitCode, logs, err := start(containerPhase{
+				Phase:    phaseBuild,
+				Name:     executionID + "-build",
+				Image:    script.DockerImage,
+				Cmd:      lang.CompileCommand(),
+				Binds:    append(binds, buildDir+":"+languages.BuildDir),
+				Deadline: deadline,
+				Metrics:  &metrics,
+				Detach:   a.detach,
+			})
+			if errors.Is(err, errExecutionDetached) {
+				detached = true
+				return
+			}
+			a.storeLogs(executionID, "build", logs.String())
+			a.storeMetrics(executionID, metrics)
+			if errors.Is(err, errExecutionTimeout) {
+				a.updateExecution(executionID, "timed_out", int(exitCode))
+				return
+			} else if err != nil || exitCode != 0 {
+				a.updateExecution(executionID, "failed", int(exitCode))
+				return
+			}
+		}
+
+		binds = append(binds, buildDir+":"+languages.BuildDir+":ro")
+	}
+
+	// Fichiers produits par le script, collectés comme artifacts après l'exécution
+	output, err := a.prepareOutputDir(executionID)
+	if err != nil {
+		a.updateExecution(executionID, "failed", -1)
+		return
+	}
+	binds = append(binds, output+":"+artifactMount)
+	uid, gid := executionUser()
+
+	exitCode, logs, err := start(containerPhase{
+		Phase:    phaseRun,
+		Name:     executionID,
+		Image:    script.DockerImage,
+		Cmd:      lang.RunCommand(),
+		Binds:    append(binds, volumeBinds...),
+		Env:      req.Env,
+		Stdin:    req.Stdin,
+		User:     fmt.Sprintf("%d:%d", uid, gid),
+		Deadline: deadline,
+		Limits:   append(volumeLimits, diskLimit{Name: "output directory", Dir: output, Space: maxOutputBytes}),
+		Metrics:  &metrics,
+		Detach:   a.detach,
+	})
+	if errors.Is(err, errExecutionDetached) {
+		detached = true
+		return
+	}
+	a.storeMetrics(executionID, metrics)
+	a.storeLogs(executionID, "stdout", logs.Stdout)
+	a.storeLogs(executionID, "stderr", logs.Stderr)
+	if errors.Is(err, errContainerGone) || errors.Is(err, errDiskQuota) {
+		a.storeLogs(executionID, "stderr", err.Error())
+	}
+	a.collectArtifacts(executionID, script.UserID)
+
+	status := "success"
+	if errors.Is(err, errExecutionTimeout) {
+		status = "timed_out"
+	} else if err != nil || exitCode != 0 {
+		status = "failed"
+	}
+	a.updateExecution(executionID, status, int(exitCode))
+}
+
+// Durée max par défaut d'une exécution, build compris
+const defaultExecutionTimeout = 15 * time.Minute
+
+var errExecutionTimeout = errors.New("execution timed out")
+
+// executionUser retourne l'utilisateur des conteneurs d'exécution : celui de l'API, propriétaire
+// de /app/output et des volumes, qui n'ont ainsi pas à être ouverts en écriture à tous
+func executionUser() (int, int) {
+	return os.Getuid(), os.Getgid()
+}
+
+// Fréquence de vérification des quotas disque pendant une exécution ou un serveur
+const diskCheckInterval = 10 * time.Second
+
+var (
+	// Conteneur laissé à Docker à l'arrêt de l'API, repris au démarrage suivant
+	errExecutionDetached = errors.New("execution detached")
+	// Conteneur d'une étape enregistrée introuvable à la reprise
+	errContainerGone = errors.New("container disappeared while the API was down")
+	// Conteneur créé mais jamais démarré : l'étape peut être relancée
+	errContainerNotStarted = errors.New("container was never started")
+)
 
+// Étapes d'une exécution, enregistrées dans son point de reprise
+const (
+	phaseBuild = "build"
+	phaseRun   = "run"
+)
+
+// containerPhase décrit un conteneur éphémère (build ou exécution)
+type containerPhase struct {
+	Phase    string
+	Name     string
+	Image    string
+	Cmd      []string
+	Binds    []string
+	Env      []string
+	Stdin    []byte
+	User     string // uid:gid du processus, vide pour l'utilisateur de l'image
+	Deadline time.Time
+	// Dossiers en écriture (volumes, /app/output) revérifiés toutes les diskCheckInterval : le conteneur est tué au-delà
+	Limits []diskLimit
+	// Si renseigné, reçoit la consommation mesurée du conteneur
+	Metrics *ExecutionMetrics
+	// Fermé à l'arrêt de l'API : on cesse d'attendre le conteneur sans le supprimer
+	Detach <-chan struct{}
+}
+
+// phaseOutput est la sortie d'un conteneur, stdout et stderr gardés séparément
+type phaseOutput struct {
+	Stdout string
+	Stderr string
+}
+
+// String retourne stdout puis stderr, pour les sorties de build
+func (o phaseOutput) String() string {
+	if o.Stdout != "" && o.Stderr != "" && !strings.HasSuffix(o.Stdout, "\n") {
+		return o.Stdout + "\n" + o.Stderr
+	}
+	return o.Stdout + o.Stderr
+}
+
+// captureOutput sépare le flux multiplexé des logs Docker en stdout et stderr,
+// chacun dans la limite de taille des logs
+func captureOutput(out io.Reader) phaseOutput {
+	stdout, stderr := newLogCapture(maxExecutionLogBytes), newLogCapture(maxExecutionLogBytes)
+	stdcopy.StdCopy(stdout, stderr, out)
+	return phaseOutput{Stdout: stdout.String(), Stderr: stderr.String()}
+}
+
+// runPhase crée un conteneur, attend sa fin puis récupère ses logs avant de le supprimer
+func runPhase(ctx context.Context, cli Runtime, phase containerPhase) (int64, phaseOutput, error) {
+	withStdin := len(phase.Stdin) > 0
 	resp, err := cli.ContainerCreate(ctx,
 		&container.Config{
-			Image: dockerImage,
-			Cmd:   cmd,
+			Image:       phase.Image,
+			Cmd:         phase.Cmd,
+			Env:         phase.Env,
+			User:        phase.User,
+			AttachStdin: withStdin,
+			OpenStdin:   withStdin,
+			StdinOnce:   withStdin,
 		},
 		&container.HostConfig{
-			Binds:      []string{absPath + ":/app/script" + ext + ":ro"},
+			Binds:      phase.Binds,
 			AutoRemove: false, // on veut lire les logs après
 		},
-		nil, nil, executionID,
+		nil, nil, phase.Name,
 	)
 	if err != nil {
-		updateExecution(executionID, "failed", -1)
-		return
+		return -1, phaseOutput{}, err
+	}
+
+	// S'attacher avant le démarrage pour ne rien perdre de stdin
+	if withStdin {
+		hijacked, err := cli.ContainerAttach(ctx, resp.ID, container.AttachOptions{Stream: true, Stdin: true})
+		if err != nil {
+			cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{})
+			return -1, phaseOutput{}, err
+		}
+		defer hijacked.Close()
+
+		go func() {
+			hijacked.Conn.Write(phase.Stdin)
+			hijacked.CloseWrite()
+		}()
 	}
 
-	cli.ContainerStart(ctx, resp.ID, container.StartOptions{})
+	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{})
+		return -1, phaseOutput{}, err
+	}
+	return awaitPhase(ctx, cli, resp.ID, time.Now(), phase)
+}
+
+// resumePhase retrouve par son nom le conteneur d'une étape détachée et attend sa fin
+func resumePhase(ctx context.Context, cli Runtime, phase containerPhase) (int64, phaseOutput, error) {
+	inspect, err := cli.ContainerInspect(ctx, phase.Name)
+	if client.IsErrNotFound(err) {
+		return -1, phaseOutput{}, errContainerGone
+	} else if err != nil {
+		return -1, phaseOutput{}, err
+	}
+	if inspect.State.Status == "created" {
+		cli.ContainerRemove(ctx, inspect.ID, container.RemoveOptions{})
+		return -1, phaseOutput{}, errContainerNotStarted
+	}
+
+	started, err := time.Parse(time.RFC3339Nano, inspect.State.StartedAt)
+	if err != nil {
+		started = time.Now()
+	}
+	return awaitPhase(ctx, cli, inspect.ID, started, phase)
+}
+
+// awaitPhase attend la fin du conteneur, en le tuant s'il dépasse la durée max,
+// puis récupère ses logs avant de le supprimer
+func awaitPhase(ctx context.Context, cli Runtime, containerID string, started time.Time, phase containerPhase) (int64, phaseOutput, error) {
+	// Échantillonner les stats Docker pendant toute la durée du conteneur
+	var statsDone <-chan ExecutionMetrics
+	stopStats := func() {}
+	if phase.Metrics != nil {
+		var statsCtx context.Context
+		statsCtx, stopStats = context.WithCancel(ctx)
+		defer stopStats()
+		statsDone = sampleStats(statsCtx, cli, containerID)
+	}
+
+	// Attendre la fin, en tuant le conteneur s'il dépasse la durée max
+	waitCtx, cancel := context.WithDeadline(ctx, phase.Deadline)
+	defer cancel()
+	statusCh, errCh := cli.ContainerWait(waitCtx, containerID, container.WaitConditionNotRunning)
+	var diskTick <-chan time.Time
+	if len(phase.Limits) > 0 {
+		ticker := time.NewTicker(diskCheckInterval)
+		defer ticker.Stop()
+		diskTick = ticker.C
+	}
 
-	// Attendre la fin
-	statusCh, errCh := cli.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
 	var exitCode int64
-	select {
-	case status := <-statusCh:
-		exitCode = status.StatusCode
-	case <-errCh:
-		exitCode = -1
+	var waitErr error
+wait:
+	for {
+		select {
+		case status := <-statusCh:
+			exitCode = status.StatusCode
+			break wait
+		case <-errCh:
+			exitCode = -1
+			if waitCtx.Err() != nil {
+				cli.ContainerKill(ctx, containerID, "KILL")
+				waitErr = errExecutionTimeout
+			}
+			break wait
+		case <-diskTick:
+			// Quota dépassé : le conteneur est tué, on attend sa fin pour récupérer les logs
+			if err := checkDisks(phase.Limits); err != nil {
+				cli.ContainerKill(ctx, containerID, "KILL")
+				waitErr, diskTick = err, nil
+			}
+		case <-phase.Detach:
+			return -1, phaseOutput{}, errExecutionDetached
+		}
+	}
+
+	// Nettoyer le conteneur
+	defer cli.ContainerRemove(ctx, containerID, container.RemoveOptions{})
+
+	if phase.Metrics != nil {
+		m := collectStats(statsDone, stopStats)
+		m.DurationMs = time.Since(started).Milliseconds()
+		phase.Metrics.add(m)
 	}
 
 	// Récupérer les logs
-	out, err := cli.ContainerLogs(ctx, resp.ID, container.LogsOptions{
+	out, err := cli.ContainerLogs(ctx, containerID, container.LogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
 	})
-	if err == nil {
-		defer out.Close()
-		content, _ := io.ReadAll(out)
-		// Docker préfixe chaque ligne avec 8 bytes de header stream
-		// On sépare stdout/stderr simplement en stockant tout
-		storeLogs(executionID, "stdout", cleanDockerLogs(string(content)))
+	if err != nil {
+		return exitCode, phaseOutput{}, waitErr
 	}
+	defer out.Close()
+	return exitCode, captureOutput(out), waitErr
+}
 
-	// Nettoyer le conteneur
-	cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{})
+func (a *App) updateExecution(executionID, status string, exitCode int) {
+	a.store.Executions.Finish(executionID, status, exitCode, time.Now().UTC().Format(time.RFC3339))
+	a.notifyWaiters(executionID)
+	a.emitExecutionEvent(executionID, executionEvent(status))
+}
 
-	status := "success"
-	if exitCode != 0 {
-		status = "failed"
+func (a *App) notifyWaiters(executionID string) {
+	a.waitersMu.Lock()
+	defer a.waitersMu.Unlock()
+	for _, ch := range a.waiters[executionID] {
+		close(ch)
 	}
-	updateExecution(executionID, status, int(exitCode))
+	delete(a.waiters, executionID)
 }
 
-// cleanDockerLogs retire les 8 bytes de header de chaque ligne Docker
-func cleanDockerLogs(raw string) string {
-	lines := strings.Split(raw, "\n")
-	var cleaned []string
-	for _, line := range lines {
-		if len(line) > 8 {
-			cleaned = append(cleaned, line[8:])
-		} else if len(line) > 0 {
-			cleaned = append(cleaned, line)
+// waitExecution bloque jusqu'à la fin de l'exécution ou l'expiration du contexte
+// et retourne le dernier statut connu
+func (a *App) waitExecution(ctx context.Context, executionID string) (string, error) {
+	ch := make(chan struct{})
+	a.waitersMu.Lock()
+	a.waiters[executionID] = append(a.waiters[executionID], ch)
+	a.waitersMu.Unlock()
+
+	defer func() {
+		a.waitersMu.Lock()
+		defer a.waitersMu.Unlock()
+		list := a.waiters[executionID]
+		for i, c := range list {
+			if c == ch {
+				a.waiters[executionID] = append(list[:i], list[i+1:]...)
+				break
+			}
+		}
+		if len(a.waiters[executionID]) == 0 {
+			delete(a.waiters, executionID)
 		}
+	}()
+
+	// L'exécution a pu se terminer avant l'enregistrement du canal
+	status, err := a.store.Executions.Status(executionID)
+	if err != nil {
+		return "", err
+	}
+	if status != "running" && status != "queued" {
+		return status, nil
 	}
-	return strings.Join(cleaned, "\n")
-}
 
-func updateExecution(executionID, status string, exitCode int) {
-	db.Exec(
-		`UPDATE executions SET status = ?, exit_code = ?, finished_at = ? WHERE id = ?`,
-		status, exitCode, time.Now().UTC().Format(time.RFC3339), executionID,
-	)
+	select {
+	case <-ch:
+		return a.store.Executions.Status(executionID)
+	case <-ctx.Done():
+		return status, ctx.Err()
+	}
 }
 
-func storeLogs(executionID, stream, content string) {
-	logID := uuid.New().String()
-	db.Exec(
-		`INSERT INTO logs (id, execution_id, stream, content) VALUES (?, ?, ?, ?)`,
-		logID, executionID, stream, content,
-	)
+// optional retourne nil pour une chaîne vide
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
 }
 
 // GetExecutionHandler — GET /executions/{id}
-func GetExecutionHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) GetExecutionHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(int)
 	executionID := chi.URLParam(r, "id")
 
@@ -178,68 +564,69 @@ func GetExecutionHandler(w http.ResponseWriter, r *http.Request) {
 		ID         string  `json:"id"`
 		ScriptID   string  `json:"script_id"`
 		Status     string  `json:"status"`
+		Trigger    string  `json:"trigger"`
+		ScheduleID *string `json:"schedule_id"`
+		TriggerID  *string `json:"trigger_id"`
 		ExitCode   *int    `json:"exit_code"`
-		StartedAt  string  `json:"started_at"`
+		StartedAt  *string `json:"started_at"`
 		FinishedAt *string `json:"finished_at"`
+		// null tant que le conteneur n'a pas été mesuré
+		Metrics *ExecutionMetrics `json:"metrics"`
 	}
 
-	var e Execution
-	err := db.QueryRow(
-		`SELECT e.id, e.script_id, e.status, e.exit_code, e.started_at, e.finished_at
-		 FROM executions e
-		 JOIN scripts s ON e.script_id = s.id
-		 WHERE e.id = ? AND s.user_id = ?`,
-		executionID, userID,
-	).Scan(&e.ID, &e.ScriptID, &e.Status, &e.ExitCode, &e.StartedAt, &e.FinishedAt)
-
+	found, err := a.store.Executions.GetForUser(executionID, userID)
 	if err != nil {
-		http.Error(w, "Execution not found", http.StatusNotFound)
+		api.NotFoundErrorHandler(w, "Execution not found")
 		return
 	}
 
+	e := Execution{
+		ID:         found.ID,
+		ScriptID:   found.ScriptID,
+		Status:     found.Status,
+		Trigger:    found.Trigger,
+		ScheduleID: found.ScheduleID,
+		TriggerID:  found.TriggerID,
+		ExitCode:   found.ExitCode,
+		StartedAt:  found.StartedAt,
+		FinishedAt: found.FinishedAt,
+	}
+	if found.Metrics != nil {
+		m := ExecutionMetrics(*found.Metrics)
+		e.Metrics = &m
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(e)
 }
 
 // GetExecutionLogsHandler — GET /executions/{id}/logs
-func GetExecutionLogsHandler(w http.ResponseWriter, r *http.Request) {
+// ?after=<seq> ne retourne que les sorties enregistrées après celle de ce numéro
+func (a *App) GetExecutionLogsHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(int)
 	executionID := chi.URLParam(r, "id")
 
+	var after int64
+	if v := r.URL.Query().Get("after"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			api.RequestErrorHandler(w, api.Invalid("after", "must be a positive number"))
+			return
+		}
+		after = n
+	}
+
 	// Vérifier que l'exécution appartient à l'user
-	var count int
-	db.QueryRow(
-		`SELECT COUNT(*) FROM executions e JOIN scripts s ON e.script_id = s.id WHERE e.id = ? AND s.user_id = ?`,
-		executionID, userID,
-	).Scan(&count)
-
-	if count == 0 {
-		http.Error(w, "Execution not found", http.StatusNotFound)
+	if _, err := a.store.Executions.GetForUser(executionID, userID); err != nil {
+		api.NotFoundErrorHandler(w, "Execution not found")
 		return
 	}
 
-	rows, err := db.Query(
-		`SELECT stream, content, created_at FROM logs WHERE execution_id = ? ORDER BY created_at ASC`,
-		executionID,
-	)
+	logs, err := a.readLogs(executionID, "", after)
 	if err != nil {
-		api.InternalErrorHandler(w)
+		a.internalError(w, err)
 		return
 	}
-	defer rows.Close()
-
-	type LogEntry struct {
-		Stream    string `json:"stream"`
-		Content   string `json:"content"`
-		CreatedAt string `json:"created_at"`
-	}
-
-	logs := []LogEntry{}
-	for rows.Next() {
-		var l LogEntry
-		rows.Scan(&l.Stream, &l.Content, &l.CreatedAt)
-		logs = append(logs, l)
-	}
 
 	// Affichage lisible si ?format=text
 	if r.URL.Query().Get("format") == "text" {
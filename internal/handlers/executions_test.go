@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/docker/docker/api/types"
+)
+
+// newRunApp crée une instance dont les conteneurs tournent dans le fakeRuntime, avec alice
+// et son script id ; les conteneurs démarrés sont passés à exit
+func newRunApp(t *testing.T, id, language string, exit func(c *fakeContainer) fakeExit) (*App, *store.Store, scriptRun) {
+	t.Helper()
+	app, st := newTestApp(t)
+	app.config.DataDir = t.TempDir()
+	fakeDocker(app).exit = exit
+	createUser(t, st, "alice", "secret")
+	createScript(t, st, "alice", id, language)
+	user, _ := st.Users.GetByUsername("alice")
+	script, err := app.loadScript(id, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app, st, script
+}
+
+// runToEnd lance l'exécution et attend son statut final
+func runToEnd(t *testing.T, app *App, script scriptRun, req executionRequest) (string, store.Execution) {
+	t.Helper()
+	id, err := app.startExecution(script, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := app.waitExecution(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	app.running.Wait()
+	e, err := app.store.Executions.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id, e
+}
+
+// executionLogs retourne le contenu des logs d'un flux
+func executionLogs(t *testing.T, st *store.Store, executionID, stream string) string {
+	t.Helper()
+	chunks, err := st.Logs.List(executionID, stream, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	for _, c := range chunks {
+		b.WriteString(c.Content)
+	}
+	return b.String()
+}
+
+func TestRunExecution(t *testing.T) {
+	var mu sync.Mutex
+	var started []*fakeContainer
+	app, st, script := newRunApp(t, "hello", "python", func(c *fakeContainer) fakeExit {
+		mu.Lock()
+		started = append(started, c)
+		mu.Unlock()
+		return fakeExit{Stdout: "hello " + c.stdin.String() + "\n", Stderr: "warning\n"}
+	})
+	docker := fakeDocker(app)
+	var sample types.StatsJSON
+	sample.Read = time.Now()
+	sample.CPUStats.CPUUsage.TotalUsage = uint64(3 * time.Millisecond)
+	sample.MemoryStats.Usage = 4096
+	stats, _ := json.Marshal(sample)
+	docker.stats = string(stats)
+
+	id, e := runToEnd(t, app, script, executionRequest{Trigger: TriggerManual, Stdin: []byte("bob"), Env: []string{"GREETING=hi"}})
+	if e.Status != "success" || e.ExitCode == nil || *e.ExitCode != 0 {
+		t.Fatalf("execution %s: status %s, exit code %v", id, e.Status, e.ExitCode)
+	}
+	if got := executionLogs(t, st, id, "stdout"); got != "hello bob\n" {
+		t.Errorf("stdout %q", got)
+	}
+	if got := executionLogs(t, st, id, "stderr"); got != "warning\n" {
+		t.Errorf("stderr %q", got)
+	}
+	if e.Metrics == nil || e.Metrics.PeakMemoryBytes != 4096 || e.Metrics.CPUTimeMs != 3 {
+		t.Errorf("metrics %+v", e.Metrics)
+	}
+
+	// Un seul conteneur, à l'image du script, supprimé après lecture des logs
+	if len(started) != 1 {
+		t.Fatalf("%d containers started", len(started))
+	}
+	c := started[0]
+	if c.Name != id || c.Config.Image != "python" || !slices.Contains(c.Config.Env, "GREETING=hi") || c.Config.User == "" {
+		t.Errorf("container %s: %+v", c.Name, c.Config)
+	}
+	if !slices.ContainsFunc(c.Host.Binds, func(b string) bool { return strings.HasSuffix(b, ":"+artifactMount) }) {
+		t.Errorf("binds %v without %s", c.Host.Binds, artifactMount)
+	}
+	if left := docker.created(); len(left) != 0 {
+		t.Errorf("containers left: %v", left)
+	}
+
+	// L'image téléchargée au premier lancement est réutilisée
+	runToEnd(t, app, script, executionRequest{Trigger: TriggerManual})
+	if !slices.Equal(docker.pulls, []string{"python"}) {
+		t.Errorf("pulls %v", docker.pulls)
+	}
+}
+
+func TestRunExecutionBuild(t *testing.T) {
+	var mu sync.Mutex
+	var started []*fakeContainer
+	buildFails := true
+	app, st, script := newRunApp(t, "compiled", "go", func(c *fakeContainer) fakeExit {
+		mu.Lock()
+		defer mu.Unlock()
+		started = append(started, c)
+		if strings.HasSuffix(c.Name, "-build") && buildFails {
+			return fakeExit{Code: 1, Stderr: "syntax error\n"}
+		}
+		return fakeExit{Stdout: "built\n"}
+	})
+
+	// Build en échec : la sortie du compilateur est gardée, le script n'est pas lancé
+	id, e := runToEnd(t, app, script, executionRequest{Trigger: TriggerManual})
+	if e.Status != "failed" || e.ExitCode == nil || *e.ExitCode != 1 {
+		t.Fatalf("failed build: status %s, exit code %v", e.Status, e.ExitCode)
+	}
+	if got := executionLogs(t, st, id, "build"); got != "syntax error\n" {
+		t.Errorf("build logs %q", got)
+	}
+	if len(started) != 1 || started[0].Name != id+"-build" {
+		t.Fatalf("containers after a failed build: %d", len(started))
+	}
+
+	// Build réussi : le script tourne avec le dossier de build en lecture seule
+	mu.Lock()
+	buildFails, started = false, nil
+	mu.Unlock()
+	id, e = runToEnd(t, app, script, executionRequest{Trigger: TriggerManual})
+	if e.Status != "success" || executionLogs(t, st, id, "stdout") != "built\n" {
+		t.Fatalf("build and run: status %s", e.Status)
+	}
+	if len(started) != 2 || started[0].Name != id+"-build" || started[1].Name != id {
+		t.Fatalf("containers %d", len(started))
+	}
+	lang, _ := app.languages.Get("go")
+	if !slices.Equal(started[0].Config.Cmd, lang.CompileCommand()) || !slices.Equal(started[1].Config.Cmd, lang.RunCommand()) {
+		t.Errorf("commands %q then %q", started[0].Config.Cmd, started[1].Config.Cmd)
+	}
+	if !slices.ContainsFunc(started[1].Host.Binds, func(b string) bool { return strings.HasSuffix(b, ":/app/build:ro") }) {
+		t.Errorf("run binds %v without the build directory", started[1].Host.Binds)
+	}
+}
+
+func TestRunExecutionTimeout(t *testing.T) {
+	app, _, script := newRunApp(t, "loop", "python", func(*fakeContainer) fakeExit {
+		return fakeExit{Block: true}
+	})
+	app.config.ExecutionTimeout = 100 * time.Millisecond
+
+	_, e := runToEnd(t, app, script, executionRequest{Trigger: TriggerManual})
+	if e.Status != "timed_out" {
+		t.Fatalf("status %s, want timed_out", e.Status)
+	}
+	if left := fakeDocker(app).created(); len(left) != 0 {
+		t.Errorf("containers left: %v", left)
+	}
+}
@@ -1,7 +0,0 @@
-package handlers
-
-import "database/sql"
-
-type HandlerType struct {
-	DB *sql.DB
-}
\ No newline at end of file
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ListLanguagesHandler — GET /languages
+func (a *App) ListLanguagesHandler(w http.ResponseWriter, r *http.Request) {
+	type LanguageRow struct {
+		Name      string   `json:"name"`
+		Aliases   []string `json:"aliases"`
+		Image     string   `json:"image"`
+		Extension string   `json:"extension"`
+		Compiled  bool     `json:"compiled"`
+	}
+
+	list := []LanguageRow{}
+	for _, l := range a.languages.List() {
+		aliases := l.Aliases
+		if aliases == nil {
+			aliases = []string{}
+		}
+		list = append(list, LanguageRow{
+			Name:      l.Name,
+			Aliases:   aliases,
+			Image:     l.Image,
+			Extension: l.Extension,
+			Compiled:  l.Compiled(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
@@ -1,68 +1,67 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"fmt"
+	"time"
 
 	"github.com/Cryptowave2-0/webhosting-goapi/api"
 	"github.com/Cryptowave2-0/webhosting-goapi/internal/auth"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Durée de validité d'une session par défaut
+const defaultSessionTTL = 30 * 24 * time.Hour
+
 type loginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+// apiUser retourne l'utilisateur tel que le voit le client, sans son mot de passe
+func apiUser(u store.User) api.User {
+	return api.User{ID: u.ID, Username: u.Username, IsAdmin: u.IsAdmin, Plan: u.Plan}
+}
+
+// LoginHandler — POST /login
+// Pose le cookie de session et retourne le token, l'utilisateur et l'expiration de la session.
+// L'ancienne route sans /api/v1 garde sa réponse texte "Logged in : <token>".
+func (a *App) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	var req loginRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		api.RequestErrorHandler(w, errors.New("invalid request"))
 		return
 	}
 
-	var userID int
-	var hashedPassword string
-
-	err := db.QueryRow(
-		"SELECT id, password FROM users WHERE username = ?",
-		req.Username,
-	).Scan(&userID, &hashedPassword)
+	user, err := a.store.Users.GetByUsername(req.Username)
 
-	if err == sql.ErrNoRows {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	if errors.Is(err, store.ErrNotFound) {
+		api.UnauthorizedErrorHandler(w, "Invalid credentials")
 		return
 	} else if err != nil {
-		api.InternalErrorHandler(w)
-		fmt.Println(err.Error())
+		a.internalError(w, err)
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(req.Password))
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		api.UnauthorizedErrorHandler(w, "Invalid credentials")
 		return
 	}
 
 	token, err := auth.GenerateSessionToken()
 	if err != nil {
-		api.InternalErrorHandler(w)
-		fmt.Println(err.Error())
+		a.internalError(w, err)
 		return
 	}
 
-	err = auth.SaveSession(token, userID)
-	// if err != nil {
-	// 	api.InternalErrorHandler(w)
-	// 	fmt.Println(err.Error())
-	// 	return
-	// } 
-	if err != nil {
-    	http.Error(w, err.Error(), 500) // TEMPORAIRE DEBUG
-    	return
+	expiresAt := time.Now().UTC().Add(a.config.SessionTTL).Truncate(time.Second)
+	if err := a.auth.SaveSession(token, user.ID, expiresAt); err != nil {
+		a.internalError(w, err)
+		return
 	}
 
 	// 4. Envoyer au client via cookie
@@ -70,9 +69,17 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		Name:     "session_token",
 		Value:    token,
 		Path:     "/",
+		Expires:  expiresAt,
 		HttpOnly: true,
-		Secure:   false,
+		Secure:   a.config.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
 	})
 
-	w.Write([]byte("Logged in : "+ token))
+	if legacyRequest(r) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("Logged in : " + token))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.Session{Token: token, User: apiUser(user), ExpiresAt: &expiresAt})
 }
@@ -1,16 +1,31 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
+	"time"
 
-	"github.com/Cryptowave2-0/webhosting-goapi/internal/auth"
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
 )
 
-func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+// LogoutHandler — POST /logout
+// Supprime la session ; expires_at donne l'heure de la déconnexion
+func (a *App) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	user, err := a.store.Users.Get(userID)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
 
 	cookie, err := r.Cookie("session_token")
 	if err == nil {
-		_ = auth.DeleteSession(cookie.Value)
+		if err := a.auth.DeleteSession(cookie.Value); err != nil {
+			a.internalError(w, err)
+			return
+		}
 	}
 
 	http.SetCookie(w, &http.Cookie{
@@ -19,8 +34,10 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 		Path:     "/",
 		MaxAge:   -1,
 		HttpOnly: true,
-		Secure:   false,
+		Secure:   a.config.SecureCookies,
 	})
 
-	w.Write([]byte("Logged out"))
+	now := time.Now().UTC()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.Session{User: apiUser(user), ExpiresAt: &now})
 }
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/go-chi/chi"
+)
+
+// Attente max de la fin du flux de stats après l'arrêt du conteneur
+const statsDrainTimeout = 2 * time.Second
+
+// ExecutionMetrics cumule la consommation des conteneurs d'une exécution (build compris)
+type ExecutionMetrics struct {
+	DurationMs      int64 `json:"duration_ms"`
+	PeakMemoryBytes int64 `json:"peak_memory_bytes"`
+	CPUTimeMs       int64 `json:"cpu_time_ms"`
+	NetRxBytes      int64 `json:"net_rx_bytes"`
+	NetTxBytes      int64 `json:"net_tx_bytes"`
+	BlockReadBytes  int64 `json:"block_read_bytes"`
+	BlockWriteBytes int64 `json:"block_write_bytes"`
+}
+
+// add ajoute les compteurs d'un conteneur ; la mémoire garde le pic
+func (m *ExecutionMetrics) add(o ExecutionMetrics) {
+	m.DurationMs += o.DurationMs
+	m.PeakMemoryBytes = max(m.PeakMemoryBytes, o.PeakMemoryBytes)
+	m.CPUTimeMs += o.CPUTimeMs
+	m.NetRxBytes += o.NetRxBytes
+	m.NetTxBytes += o.NetTxBytes
+	m.BlockReadBytes += o.BlockReadBytes
+	m.BlockWriteBytes += o.BlockWriteBytes
+}
+
+// statsStreamer ouvre le flux de stats d'un conteneur (implémenté par le client Docker)
+type statsStreamer interface {
+	ContainerStats(ctx context.Context, containerID string, stream bool) (container.StatsResponse, error)
+}
+
+// sampleStats lit le flux de stats Docker jusqu'à l'arrêt du conteneur.
+// Les compteurs CPU, réseau et disque sont cumulés par Docker : la dernière valeur suffit.
+func sampleStats(ctx context.Context, cli statsStreamer, containerID string) <-chan ExecutionMetrics {
+	done := make(chan ExecutionMetrics, 1)
+	go func() {
+		var m ExecutionMetrics
+		defer func() { done <- m }()
+
+		resp, err := cli.ContainerStats(ctx, containerID, true)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+
+		dec := json.NewDecoder(resp.Body)
+		for {
+			var s types.StatsJSON
+			if err := dec.Decode(&s); err != nil {
+				return
+			}
+			// Un conteneur arrêté renvoie des stats vides
+			if s.Read.IsZero() || s.CPUStats.CPUUsage.TotalUsage == 0 {
+				continue
+			}
+
+			memory := s.MemoryStats.MaxUsage // cgroup v1 uniquement
+			if memory == 0 {
+				memory = s.MemoryStats.Usage
+			}
+			m.PeakMemoryBytes = max(m.PeakMemoryBytes, int64(memory))
+			m.CPUTimeMs = int64(s.CPUStats.CPUUsage.TotalUsage / uint64(time.Millisecond))
+
+			var rx, tx int64
+			for _, n := range s.Networks {
+				rx += int64(n.RxBytes)
+				tx += int64(n.TxBytes)
+			}
+			m.NetRxBytes, m.NetTxBytes = rx, tx
+
+			var read, write int64
+			for _, e := range s.BlkioStats.IoServiceBytesRecursive {
+				switch strings.ToLower(e.Op) {
+				case "read":
+					read += int64(e.Value)
+				case "write":
+					write += int64(e.Value)
+				}
+			}
+			m.BlockReadBytes, m.BlockWriteBytes = read, write
+		}
+	}()
+	return done
+}
+
+// collectStats attend la fin du flux ouvert par sampleStats, sans bloquer si Docker tarde
+func collectStats(done <-chan ExecutionMetrics, cancel context.CancelFunc) ExecutionMetrics {
+	select {
+	case m := <-done:
+		return m
+	case <-time.After(statsDrainTimeout):
+		cancel()
+		return <-done
+	}
+}
+
+func (a *App) storeMetrics(executionID string, m ExecutionMetrics) {
+	a.store.Executions.SetMetrics(executionID, store.Metrics(m))
+}
+
+// GetScriptMetricsHandler — GET /scripts/{id}/metrics?since=RFC3339
+// Agrégats de consommation des exécutions mesurées du script
+func (a *App) GetScriptMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	scriptID := chi.URLParam(r, "id")
+
+	if _, err := a.loadScript(scriptID, userID); err != nil {
+		api.NotFoundErrorHandler(w, "Script not found")
+		return
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			api.RequestErrorHandler(w, api.Invalid("since", "must be an RFC3339 date"))
+			return
+		}
+		since = t
+	}
+
+	stats, err := a.store.Executions.Stats(scriptID, since)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	m := struct {
+		Executions         int   `json:"executions"`
+		TotalDurationMs    int64 `json:"total_duration_ms"`
+		AvgDurationMs      int64 `json:"avg_duration_ms"`
+		MaxPeakMemoryBytes int64 `json:"max_peak_memory_bytes"`
+		AvgPeakMemoryBytes int64 `json:"avg_peak_memory_bytes"`
+		TotalCPUTimeMs     int64 `json:"total_cpu_time_ms"`
+		AvgCPUTimeMs       int64 `json:"avg_cpu_time_ms"`
+		TotalNetRxBytes    int64 `json:"total_net_rx_bytes"`
+		TotalNetTxBytes    int64 `json:"total_net_tx_bytes"`
+		TotalBlockRead     int64 `json:"total_block_read_bytes"`
+		TotalBlockWrite    int64 `json:"total_block_write_bytes"`
+	}(stats)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m)
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// fakeStats rejoue un flux de stats Docker ; body nil : le flux reste ouvert jusqu'à l'annulation
+type fakeStats struct {
+	body io.Reader
+	err  error
+}
+
+func (f fakeStats) ContainerStats(ctx context.Context, containerID string, stream bool) (container.StatsResponse, error) {
+	if f.err != nil {
+		return container.StatsResponse{}, f.err
+	}
+	if f.body != nil {
+		return container.StatsResponse{Body: io.NopCloser(f.body)}, nil
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		<-ctx.Done()
+		pw.CloseWithError(ctx.Err())
+	}()
+	return container.StatsResponse{Body: pr}, nil
+}
+
+func statsSample(cpuNs, memory, rx, read uint64) types.StatsJSON {
+	var s types.StatsJSON
+	s.Read = time.Now()
+	s.CPUStats.CPUUsage.TotalUsage = cpuNs
+	s.MemoryStats.Usage = memory
+	s.Networks = map[string]types.NetworkStats{
+		"eth0": {RxBytes: rx, TxBytes: 10},
+		"eth1": {RxBytes: 1, TxBytes: 1},
+	}
+	s.BlkioStats.IoServiceBytesRecursive = []types.BlkioStatEntry{
+		{Op: "Read", Value: read},
+		{Op: "Write", Value: 7},
+	}
+	return s
+}
+
+func TestSampleStats(t *testing.T) {
+	var stream strings.Builder
+	enc := json.NewEncoder(&stream)
+	enc.Encode(statsSample(2*uint64(time.Millisecond), 300, 100, 50))
+	enc.Encode(statsSample(5*uint64(time.Millisecond), 200, 400, 80))
+	// Le conteneur arrêté renvoie des stats vides, ignorées
+	enc.Encode(types.StatsJSON{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := collectStats(sampleStats(ctx, fakeStats{body: strings.NewReader(stream.String())}, "c1"), cancel)
+	want := ExecutionMetrics{
+		PeakMemoryBytes: 300, // pic sur les échantillons
+		CPUTimeMs:       5,   // compteurs cumulés : dernière valeur
+		NetRxBytes:      401,
+		NetTxBytes:      11,
+		BlockReadBytes:  80,
+		BlockWriteBytes: 7,
+	}
+	if m != want {
+		t.Fatalf("metrics = %+v, want %+v", m, want)
+	}
+
+	// Sans flux, les métriques restent vides
+	if m := collectStats(sampleStats(ctx, fakeStats{err: errors.New("no such container")}, "c1"), cancel); m != (ExecutionMetrics{}) {
+		t.Fatalf("metrics without stream = %+v", m)
+	}
+}
+
+func TestCollectStatsTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+	m := collectStats(sampleStats(ctx, fakeStats{}, "c1"), cancel)
+	if m != (ExecutionMetrics{}) {
+		t.Fatalf("metrics = %+v", m)
+	}
+	if elapsed := time.Since(start); elapsed < statsDrainTimeout || elapsed > statsDrainTimeout+time.Second {
+		t.Fatalf("collectStats returned after %s, want about %s", elapsed, statsDrainTimeout)
+	}
+}
+
+func TestScriptMetrics(t *testing.T) {
+	srv, st := newTestServer(t)
+	createUser(t, st, "alice", "secret")
+	createUser(t, st, "bob", "secret")
+	createScript(t, st, "alice", "logs", "python")
+	createScript(t, st, "alice", "empty", "python")
+	createScript(t, st, "bob", "other", "python")
+
+	old := time.Now().Add(-48 * time.Hour)
+	newLogExecution(t, st, "e1", old)
+	newLogExecution(t, st, "e2", time.Now())
+	newLogExecution(t, st, "e3", time.Now()) // sans métriques, ignorée
+	st.Executions.SetMetrics("e1", store.Metrics{DurationMs: 100, PeakMemoryBytes: 1000, CPUTimeMs: 10, NetRxBytes: 1, NetTxBytes: 2, BlockReadBytes: 3, BlockWriteBytes: 4})
+	st.Executions.SetMetrics("e2", store.Metrics{DurationMs: 300, PeakMemoryBytes: 3000, CPUTimeMs: 30, NetRxBytes: 1, NetTxBytes: 2, BlockReadBytes: 3, BlockWriteBytes: 4})
+
+	c, _ := login(t, srv, "alice", "secret")
+	get := func(target string) (map[string]int64, int) {
+		t.Helper()
+		resp, err := c.Get(srv.URL + api.Prefix + target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var m map[string]int64
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return m, resp.StatusCode
+	}
+
+	m, code := get("/scripts/logs/metrics")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	for field, want := range map[string]int64{
+		"executions":              2,
+		"total_duration_ms":       400,
+		"avg_duration_ms":         200,
+		"max_peak_memory_bytes":   3000,
+		"avg_peak_memory_bytes":   2000,
+		"total_cpu_time_ms":       40,
+		"avg_cpu_time_ms":         20,
+		"total_net_rx_bytes":      2,
+		"total_net_tx_bytes":      4,
+		"total_block_read_bytes":  6,
+		"total_block_write_bytes": 8,
+	} {
+		if m[field] != want {
+			t.Errorf("%s = %d, want %d", field, m[field], want)
+		}
+	}
+
+	since := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	if m, _ := get("/scripts/logs/metrics?since=" + since); m["executions"] != 1 || m["max_peak_memory_bytes"] != 3000 {
+		t.Fatalf("metrics since %s = %v", since, m)
+	}
+
+	// Script sans exécution mesurée : agrégats à zéro
+	m, code = get("/scripts/empty/metrics")
+	if code != http.StatusOK {
+		t.Fatalf("empty script: status = %d", code)
+	}
+	for field, v := range m {
+		if v != 0 {
+			t.Errorf("empty script: %s = %d", field, v)
+		}
+	}
+
+	if _, code := get("/scripts/other/metrics"); code != http.StatusNotFound {
+		t.Fatalf("other user's script: status = %d, want 404", code)
+	}
+	if _, code := get("/scripts/logs/metrics?since=yesterday"); code != http.StatusBadRequest {
+		t.Fatalf("invalid since: status = %d, want 400", code)
+	}
+}
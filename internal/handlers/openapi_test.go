@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/docs"
+	"github.com/go-chi/chi"
+)
+
+// Méthodes que la spécification ne décrit pas pour les routes qui les acceptent toutes
+var undocumentedMethods = map[string]bool{http.MethodConnect: true, http.MethodTrace: true}
+
+// specPath convertit un motif chi en chemin OpenAPI : le joker final devient {path}
+func specPath(pattern string) string {
+	if p, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return p + "/{path}"
+	}
+	return pattern
+}
+
+type specServer struct {
+	URL string `json:"url"`
+}
+
+// TestOpenAPICoversRoutes échoue quand une route est ajoutée sans être décrite
+// dans internal/docs/openapi.yaml, ou quand la spécification décrit une route disparue.
+// Les alias sans préfixe doivent correspondre à une route de /api/v1.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	app, _ := newTestApp(t)
+	// Les routes /apps/<name>/ ne sont déclarées qu'avec le mode chemin
+	app.config.ProxyPathMode = true
+	r := chi.NewRouter()
+	app.RegisterAPIRoutes(r)
+
+	spec, err := docs.Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Servers []specServer                          `json:"servers"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Servers) == 0 || doc.Servers[0].URL != api.Prefix {
+		t.Fatalf("servers: %+v, want %s", doc.Servers, api.Prefix)
+	}
+
+	documented := map[string]bool{}
+	for p, item := range doc.Paths {
+		// Un chemin peut être servi ailleurs que sous /api/v1 (reverse proxy)
+		servers := doc.Servers
+		if raw, ok := item["servers"]; ok {
+			servers = nil
+			if err := json.Unmarshal(raw, &servers); err != nil {
+				t.Fatal(err)
+			}
+		}
+		prefix := strings.TrimSuffix(servers[0].URL, "/")
+		for method := range item {
+			if method != "parameters" && method != "servers" {
+				documented[strings.ToUpper(method)+" "+prefix+p] = true
+			}
+		}
+	}
+
+	routed, legacy := map[string]bool{}, map[string]bool{}
+	err = chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		switch {
+		case undocumentedMethods[method]:
+		case strings.HasPrefix(route, api.Prefix+"/"), strings.HasPrefix(route, "/apps/"):
+			routed[method+" "+specPath(route)] = true
+		default:
+			legacy[method+" "+specPath(route)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for op := range legacy {
+		method, route, _ := strings.Cut(op, " ")
+		if !routed[method+" "+api.Prefix+route] {
+			t.Errorf("%s is routed without %s counterpart", op, api.Prefix)
+		}
+	}
+
+	for op := range routed {
+		if !documented[op] {
+			t.Errorf("%s is routed but missing from openapi.yaml", op)
+		}
+	}
+	for op := range documented {
+		if !routed[op] {
+			t.Errorf("%s is in openapi.yaml but not routed", op)
+		}
+	}
+}
+
+func TestDocsServed(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + api.Prefix + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	err = json.NewDecoder(resp.Body).Decode(&doc)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK || doc["openapi"] != "3.0.3" {
+		t.Fatalf("GET /openapi.json: %d %v", resp.StatusCode, err)
+	}
+
+	resp, err = http.Get(srv.URL + api.Prefix + "/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("GET /docs: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
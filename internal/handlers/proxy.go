@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/go-chi/chi"
+)
+
+// Préfixe des serveurs exposés par chemin (/apps/<name>/)
+const proxyPathPrefix = "/apps/"
+
+// Bac à sable des serveurs servis sur l'origine de l'API (/apps/<name>/) : sans allow-same-origin,
+// la page a une origine opaque et ne peut ni lire les cookies de l'API ni l'appeler en son nom
+const proxyPathSandbox = "sandbox allow-scripts allow-forms allow-popups allow-downloads allow-modals"
+
+// proxyPage affiche une page d'erreur HTML au visiteur du serveur hébergé
+func proxyPage(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head><title>%d %s</title></head>
+<body style="font-family: sans-serif; text-align: center; padding-top: 10%%">
+<h1>%d %s</h1>
+<p>%s</p>
+</body>
+</html>
+`, code, http.StatusText(code), code, http.StatusText(code), html.EscapeString(message))
+}
+
+// serverNameFromHost retourne le sous-domaine si l'hôte est <name>.<proxyBaseDomain>,
+// sauf pour l'hôte de l'API et les noms réservés
+func (a *App) serverNameFromHost(host string) (string, bool) {
+	if a.config.ProxyBaseDomain == "" || a.isAPIHost(host) {
+		return "", false
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	name, ok := strings.CutSuffix(strings.ToLower(host), "."+a.config.ProxyBaseDomain)
+	if !ok || !serverNamePattern.MatchString(name) || a.reservedServerName(name) {
+		return "", false
+	}
+	return name, true
+}
+
+// ProxyHostMiddleware envoie les requêtes <name>.<domaine> au serveur correspondant
+func (a *App) ProxyHostMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if name, ok := a.serverNameFromHost(r.Host); ok {
+			a.proxyToServer(w, r, name, "")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ProxyPathHandler — /apps/{name}/*
+// Seulement avec ProxyPathMode : la page est servie sur l'origine de l'API, dans un bac à sable CSP
+func (a *App) ProxyPathHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	w.Header().Set("Content-Security-Policy", proxyPathSandbox)
+
+	// /apps/<name> -> /apps/<name>/ pour que les chemins relatifs fonctionnent
+	prefix := proxyPathPrefix + name
+	if r.URL.Path == prefix {
+		target := prefix + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+
+	a.proxyToServer(w, r, name, prefix)
+}
+
+// proxyUser retourne l'utilisateur connecté : session de l'API sur /apps/<name>/ (même origine),
+// cookie propre au serveur sur son sous-domaine
+func (a *App) proxyUser(r *http.Request, s store.Server, pathMode bool) (int, bool) {
+	if !pathMode {
+		return a.appSessionUser(r, s)
+	}
+	cookie, err := r.Cookie("session_token")
+	if err != nil {
+		return 0, false
+	}
+	userID, err := a.auth.GetUserFromSession(cookie.Value)
+	return userID, err == nil
+}
+
+// proxyToServer vérifie l'accès puis relaie la requête (WebSocket compris) vers le conteneur
+func (a *App) proxyToServer(w http.ResponseWriter, r *http.Request, name, stripPrefix string) {
+	pathMode := stripPrefix != ""
+	s, err := a.store.Servers.GetByName(name)
+	if err != nil {
+		proxyPage(w, http.StatusNotFound, "No application is hosted here.")
+		return
+	}
+
+	if !pathMode && r.URL.Path == appSignInPath {
+		a.redeemAppTicket(w, r, s)
+		return
+	}
+
+	if s.Access != AccessPublic {
+		userID, ok := a.proxyUser(r, s, pathMode)
+		if !ok {
+			// Sur un sous-domaine, la session s'ouvre en passant par l'API
+			if target := a.signInURL(s, r); !pathMode && target != "" && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+				http.Redirect(w, r, target, http.StatusFound)
+				return
+			}
+			proxyPage(w, http.StatusUnauthorized, "You must be logged in to access this application.")
+			return
+		}
+		if s.Access == AccessOwner && userID != s.UserID {
+			proxyPage(w, http.StatusForbidden, "This application is private.")
+			return
+		}
+	}
+
+	if s.State != ServerOn || s.Address == nil {
+		proxyPage(w, http.StatusBadGateway, "The application is not running.")
+		return
+	}
+
+	target := &url.URL{Scheme: "http", Host: net.JoinHostPort(*s.Address, strconv.Itoa(s.Port))}
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.Out.Host = pr.In.Host
+			if stripPrefix != "" {
+				pr.Out.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(pr.In.URL.Path, stripPrefix), "/")
+				pr.Out.URL.RawPath = ""
+				pr.Out.Header.Set("X-Forwarded-Prefix", stripPrefix)
+			}
+			pr.SetXForwarded()
+			stripSessionCookies(pr.Out)
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			a.log.Warnf("proxy: server %s: %v", s.ID, err)
+			proxyPage(w, http.StatusBadGateway, "The application is not responding.")
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// stripSessionCookies évite de transmettre la session de l'API ou du serveur au code hébergé
+func stripSessionCookies(r *http.Request) {
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != "session_token" && c.Name != appSessionCookie {
+			r.AddCookie(c)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/auth"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/go-chi/chi"
+)
+
+// Connexion aux serveurs réservés aux utilisateurs connectés, exposés sur <name>.<domaine>.
+//
+// Le cookie de session de l'API reste limité à l'hôte de l'API : le code hébergé sur un
+// sous-domaine ne le reçoit jamais. Le visiteur sans session est renvoyé sur
+// GET /servers/{id}/signin (sur l'API), qui émet un ticket à usage unique ; le serveur
+// l'échange contre un cookie propre à son sous-domaine, lié à la session de l'API.
+const (
+	// Chemin réservé, sur l'hôte du serveur, qui échange le ticket contre le cookie
+	appSignInPath = "/.webhosting/signin"
+	// Cookie de session d'un serveur, limité à son sous-domaine
+	appSessionCookie = "app_session"
+
+	appTicketTTL  = time.Minute
+	appSessionTTL = 12 * time.Hour
+)
+
+// appGrant donne accès à un serveur tant que la session de l'API est valide
+type appGrant struct {
+	ServerID     string
+	SessionToken string
+	Expires      time.Time
+}
+
+// Noms qui ne peuvent pas servir de sous-domaine à un serveur
+var reservedServerNames = []string{"api", "www", "admin", "app", "apps", "auth", "login", "mail", "static", "docs"}
+
+// reservedServerName indique si name est réservé, y compris le premier label de l'hôte de l'API
+func (a *App) reservedServerName(name string) bool {
+	for _, reserved := range reservedServerNames {
+		if name == reserved {
+			return true
+		}
+	}
+	if a.apiURL != nil && a.config.ProxyBaseDomain != "" {
+		label, ok := strings.CutSuffix(strings.ToLower(a.apiURL.Hostname()), "."+a.config.ProxyBaseDomain)
+		return ok && name == label
+	}
+	return false
+}
+
+// isAPIHost indique si la requête vise l'hôte public de l'API
+func (a *App) isAPIHost(host string) bool {
+	if a.apiURL == nil {
+		return false
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.EqualFold(host, a.apiURL.Hostname())
+}
+
+// safeReturnPath n'accepte qu'un chemin local, pour ne pas rediriger hors du serveur
+func safeReturnPath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.HasPrefix(p, `/\`) {
+		return "/"
+	}
+	return p
+}
+
+// appURL retourne l'URL de path sur le sous-domaine du serveur, avec le schéma et le port de l'API
+func (a *App) appURL(name, path string, query url.Values) string {
+	host := name + "." + a.config.ProxyBaseDomain
+	if port := a.apiURL.Port(); port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	u := url.URL{Scheme: a.apiURL.Scheme, Host: host, Path: path, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// signInURL retourne la page de l'API qui ouvre une session sur le serveur, vide si l'URL de l'API est inconnue
+func (a *App) signInURL(s store.Server, r *http.Request) string {
+	if a.apiURL == nil {
+		return ""
+	}
+	u := *a.apiURL
+	u.Path = api.Prefix + "/servers/" + s.ID + "/signin"
+	u.RawQuery = url.Values{"return": {r.URL.RequestURI()}}.Encode()
+	return u.String()
+}
+
+// ServerSignInHandler — GET /servers/{id}/signin?return=/chemin
+// Vérifie l'accès de l'utilisateur connecté puis le renvoie sur le serveur avec un ticket
+func (a *App) ServerSignInHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	s, err := a.store.Servers.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Server not found")
+		return
+	}
+	if s.Access == AccessOwner && s.UserID != userID {
+		// Même réponse qu'un serveur inexistant : son existence ne regarde que son propriétaire
+		api.NotFoundErrorHandler(w, "Server not found")
+		return
+	}
+	if a.apiURL == nil || a.config.ProxyBaseDomain == "" {
+		api.RequestErrorHandler(w, api.Invalid("id", "servers are not exposed on subdomains"))
+		return
+	}
+
+	returnPath := safeReturnPath(r.URL.Query().Get("return"))
+	if s.Access == AccessPublic {
+		http.Redirect(w, r, a.appURL(s.Name, returnPath, nil), http.StatusFound)
+		return
+	}
+
+	cookie, err := r.Cookie("session_token")
+	if err != nil {
+		// Session ouverte par header Authorization : rien à lier au cookie du serveur
+		api.UnauthorizedErrorHandler(w, "Sign in with the session cookie to open an application")
+		return
+	}
+	ticket, err := auth.GenerateSessionToken()
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	a.appAuthMu.Lock()
+	now := time.Now()
+	for t, g := range a.appTickets {
+		if now.After(g.Expires) {
+			delete(a.appTickets, t)
+		}
+	}
+	a.appTickets[ticket] = appGrant{ServerID: s.ID, SessionToken: cookie.Value, Expires: now.Add(appTicketTTL)}
+	a.appAuthMu.Unlock()
+
+	http.Redirect(w, r, a.appURL(s.Name, appSignInPath, url.Values{"ticket": {ticket}, "return": {returnPath}}), http.StatusFound)
+}
+
+// redeemAppTicket échange le ticket contre le cookie de session du serveur (sur son sous-domaine)
+func (a *App) redeemAppTicket(w http.ResponseWriter, r *http.Request, s store.Server) {
+	a.appAuthMu.Lock()
+	ticket := r.URL.Query().Get("ticket")
+	grant, ok := a.appTickets[ticket]
+	delete(a.appTickets, ticket)
+	a.appAuthMu.Unlock()
+
+	if !ok || grant.ServerID != s.ID || time.Now().After(grant.Expires) {
+		proxyPage(w, http.StatusUnauthorized, "This sign-in link has expired.")
+		return
+	}
+	if _, err := a.auth.GetUserFromSession(grant.SessionToken); err != nil {
+		proxyPage(w, http.StatusUnauthorized, "You must be logged in to access this application.")
+		return
+	}
+
+	token, err := auth.GenerateSessionToken()
+	if err != nil {
+		proxyPage(w, http.StatusInternalServerError, "Please try again later.")
+		return
+	}
+	a.appAuthMu.Lock()
+	now := time.Now()
+	for t, g := range a.appSessions {
+		if now.After(g.Expires) {
+			delete(a.appSessions, t)
+		}
+	}
+	grant.Expires = now.Add(appSessionTTL)
+	a.appSessions[token] = grant
+	a.appAuthMu.Unlock()
+
+	// Sans Domain : le cookie reste sur ce sous-domaine
+	http.SetCookie(w, &http.Cookie{
+		Name:     appSessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(appSessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   a.config.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, safeReturnPath(r.URL.Query().Get("return")), http.StatusFound)
+}
+
+// appSessionUser retourne l'utilisateur du cookie de session du serveur s
+func (a *App) appSessionUser(r *http.Request, s store.Server) (int, bool) {
+	cookie, err := r.Cookie(appSessionCookie)
+	if err != nil {
+		return 0, false
+	}
+	a.appAuthMu.Lock()
+	grant, ok := a.appSessions[cookie.Value]
+	a.appAuthMu.Unlock()
+	if !ok || grant.ServerID != s.ID || time.Now().After(grant.Expires) {
+		return 0, false
+	}
+	// Déconnecté de l'API : la session du serveur tombe avec elle
+	userID, err := a.auth.GetUserFromSession(grant.SessionToken)
+	if err != nil {
+		return 0, false
+	}
+	return userID, true
+}
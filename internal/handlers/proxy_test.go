@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+)
+
+// Client qui ne suit pas les redirections et ne garde aucun cookie
+var noRedirect = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+// hostRequest envoie une requête au serveur de test en se présentant comme host
+func hostRequest(t *testing.T, srv *httptest.Server, host, target string, cookies ...*http.Cookie) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = host
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	resp, err := noRedirect.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func cookieNamed(resp *http.Response, name string) *http.Cookie {
+	for _, c := range resp.Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestProxyPathModeIsOptIn(t *testing.T) {
+	srv, _ := newTestServer(t)
+	if resp := hostRequest(t, srv, "localhost", "/apps/blog/"); resp.StatusCode != http.StatusNotFound || resp.Header.Get("Content-Security-Policy") != "" {
+		t.Errorf("path mode off: %d, CSP %q", resp.StatusCode, resp.Header.Get("Content-Security-Policy"))
+	}
+
+	app, _ := newTestApp(t)
+	app.config.ProxyPathMode = true
+	srv = httptest.NewServer(app.Handler())
+	defer srv.Close()
+
+	resp := hostRequest(t, srv, "localhost", "/apps/blog/")
+	if resp.StatusCode != http.StatusNotFound || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("unknown server: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if csp := resp.Header.Get("Content-Security-Policy"); !strings.HasPrefix(csp, "sandbox") || strings.Contains(csp, "allow-same-origin") {
+		t.Errorf("path mode CSP: %q", csp)
+	}
+}
+
+func TestProxyHostRouting(t *testing.T) {
+	app, st := newTestApp(t)
+	app.config.ProxyBaseDomain = "example.test"
+	app.config.ProxyAPIURL = "http://control.example.test"
+	app.apiURL, _ = url.Parse(app.config.ProxyAPIURL)
+	srv := httptest.NewServer(app.Handler())
+	defer srv.Close()
+
+	createUser(t, st, "alice", "secret")
+	createScript(t, st, "alice", "web", "python")
+	c, _ := login(t, srv, "alice", "secret")
+
+	// Le cookie de session reste sur l'hôte de l'API
+	body := `{"username":"alice","password":"secret"}`
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+api.Prefix+"/login", strings.NewReader(body))
+	req.Host = "control.example.test"
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if session := cookieNamed(resp, "session_token"); session == nil || session.Domain != "" {
+		t.Fatalf("session cookie: %+v", session)
+	}
+
+	for _, name := range []string{"api", "www", "control"} {
+		resp, err := c.Post(srv.URL+api.Prefix+"/servers", "application/json", strings.NewReader(`{"name":"`+name+`","script_id":"web"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("create server %q: %d, want 400", name, resp.StatusCode)
+		}
+	}
+
+	// L'hôte de l'API et les noms réservés ne sont jamais relayés
+	if resp := hostRequest(t, srv, "control.example.test", api.Prefix+"/languages"); resp.StatusCode != http.StatusOK {
+		t.Errorf("API host: %d", resp.StatusCode)
+	}
+	if resp := hostRequest(t, srv, "www.example.test:8000", api.Prefix+"/languages"); resp.StatusCode != http.StatusOK {
+		t.Errorf("reserved host: %d", resp.StatusCode)
+	}
+	if resp := hostRequest(t, srv, "blog.example.test", "/"); resp.StatusCode != http.StatusNotFound || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("unknown server: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestProxySignIn(t *testing.T) {
+	app, st := newTestApp(t)
+	app.config.ProxyBaseDomain = "example.test"
+	app.config.ProxyAPIURL = "http://control.example.test:8000"
+	app.apiURL, _ = url.Parse(app.config.ProxyAPIURL)
+	srv := httptest.NewServer(app.Handler())
+	defer srv.Close()
+
+	createUser(t, st, "alice", "secret")
+	createUser(t, st, "bob", "secret")
+	createScript(t, st, "alice", "web", "python")
+	alice, _ := login(t, srv, "alice", "secret")
+	bob, _ := login(t, srv, "bob", "secret")
+	sessionOf := func(c *http.Client) *http.Cookie {
+		u, _ := url.Parse(srv.URL)
+		for _, cookie := range c.Jar.Cookies(u) {
+			if cookie.Name == "session_token" {
+				return cookie
+			}
+		}
+		t.Fatal("no session cookie")
+		return nil
+	}
+
+	for _, s := range []struct{ name, access string }{{"blog", AccessAuthenticated}, {"private", AccessOwner}} {
+		resp, err := alice.Post(srv.URL+api.Prefix+"/servers", "application/json",
+			strings.NewReader(`{"name":"`+s.name+`","script_id":"web","access":"`+s.access+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create %s: %d", s.name, resp.StatusCode)
+		}
+	}
+	blog, err := st.Servers.GetByName("blog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := st.Servers.GetByName("private")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sans session sur le sous-domaine : renvoi vers l'API, même avec le cookie de l'API
+	resp := hostRequest(t, srv, "blog.example.test:8000", "/page?x=1", sessionOf(bob))
+	want := "http://control.example.test:8000" + api.Prefix + "/servers/" + blog.ID + "/signin?return=%2Fpage%3Fx%3D1"
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != want {
+		t.Fatalf("anonymous visit: %d %s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	// L'API émet un ticket pour le sous-domaine
+	resp = hostRequest(t, srv, "control.example.test:8000", api.Prefix+"/servers/"+blog.ID+"/signin?return=/page", sessionOf(bob))
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if resp.StatusCode != http.StatusFound || err != nil || location.Host != "blog.example.test:8000" || location.Path != appSignInPath {
+		t.Fatalf("signin: %d %s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	// Le ticket devient un cookie limité au sous-domaine, puis ne sert plus
+	resp = hostRequest(t, srv, "blog.example.test:8000", location.RequestURI())
+	appSession := cookieNamed(resp, appSessionCookie)
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/page" || appSession == nil || appSession.Domain != "" {
+		t.Fatalf("redeem: %d %s %+v", resp.StatusCode, resp.Header.Get("Location"), appSession)
+	}
+	if resp := hostRequest(t, srv, "blog.example.test:8000", location.RequestURI()); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("ticket reused: %d", resp.StatusCode)
+	}
+
+	// Accès accordé : le serveur arrêté répond 502 au lieu de renvoyer vers l'API
+	if resp := hostRequest(t, srv, "blog.example.test:8000", "/page", appSession); resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("signed in visit: %d", resp.StatusCode)
+	}
+	// Le cookie d'un serveur ne vaut pas pour un autre
+	if resp := hostRequest(t, srv, "private.example.test:8000", "/", appSession); resp.StatusCode != http.StatusFound {
+		t.Errorf("cookie of another server: %d", resp.StatusCode)
+	}
+	// Serveur réservé à son propriétaire : pas de ticket pour bob
+	if resp := hostRequest(t, srv, "control.example.test:8000", api.Prefix+"/servers/"+private.ID+"/signin", sessionOf(bob)); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("signin on another user's private server: %d", resp.StatusCode)
+	}
+
+	// Déconnecté de l'API : la session du serveur tombe avec elle
+	resp, err = bob.Post(srv.URL+api.Prefix+"/logout", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp := hostRequest(t, srv, "blog.example.test:8000", "/page", appSession); resp.StatusCode != http.StatusFound {
+		t.Errorf("visit after logout: %d", resp.StatusCode)
+	}
+}
+
+func TestSafeReturnPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"/page?x=1":         "/page?x=1",
+		"":                  "/",
+		"//evil.example":    "/",
+		`/\evil.example`:    "/",
+		"https://evil.test": "/",
+	} {
+		if got := safeReturnPath(in); got != want {
+			t.Errorf("safeReturnPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
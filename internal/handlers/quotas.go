@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/quotas"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/go-chi/chi"
+)
+
+// quotaError répond 429 (trop d'exécutions simultanées), 403 (quota dépassé)
+// ou 503 (arrêt de l'API en cours). Retourne false pour toute autre erreur.
+func quotaError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, quotas.ErrConcurrencyLimit):
+		w.Header().Set("Retry-After", "10")
+		api.WriteError(w, http.StatusTooManyRequests, api.CodeTooManyRequests, err.Error())
+	case errors.Is(err, quotas.ErrQuotaExceeded):
+		api.WriteError(w, http.StatusForbidden, api.CodeQuotaExceeded, err.Error())
+	case errors.Is(err, errShuttingDown):
+		w.Header().Set("Retry-After", "30")
+		api.WriteError(w, http.StatusServiceUnavailable, api.CodeUnavailable, err.Error())
+	default:
+		return false
+	}
+	return true
+}
+
+// UsageHandler — GET /usage
+// Consommation du mois en cours et limites du plan de l'utilisateur
+func (a *App) UsageHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	usage, err := a.quotas.GetUsage(userID, time.Now())
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
+
+// ListPlansHandler — GET /admin/plans
+func (a *App) ListPlansHandler(w http.ResponseWriter, r *http.Request) {
+	plans, err := a.quotas.ListPlans()
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plans)
+}
+
+// SavePlanHandler — PUT /admin/plans/{name}
+// JSON : max_scripts, max_storage, max_concurrent, max_compute_seconds, max_volume_space, max_volume_total (0 = illimité)
+func (a *App) SavePlanHandler(w http.ResponseWriter, r *http.Request) {
+	var plan quotas.Plan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		api.RequestErrorHandler(w, errors.New("invalid request"))
+		return
+	}
+	plan.Name = chi.URLParam(r, "name")
+
+	err := a.quotas.SavePlan(plan)
+	if errors.Is(err, quotas.ErrInvalidPlan) {
+		api.RequestErrorHandler(w, err)
+		return
+	} else if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// DeletePlanHandler — DELETE /admin/plans/{name}
+func (a *App) DeletePlanHandler(w http.ResponseWriter, r *http.Request) {
+	err := a.quotas.DeletePlan(chi.URLParam(r, "name"))
+	switch {
+	case errors.Is(err, quotas.ErrPlanNotFound):
+		api.NotFoundErrorHandler(w, "Plan not found")
+	case errors.Is(err, quotas.ErrInvalidPlan), errors.Is(err, quotas.ErrPlanInUse):
+		api.RequestErrorHandler(w, err)
+	case err != nil:
+		a.internalError(w, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// SetUserPlanHandler — PUT /admin/users/{id}/plan
+// JSON : plan
+func (a *App) SetUserPlanHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		api.NotFoundErrorHandler(w, "User not found")
+		return
+	}
+
+	var req struct {
+		Plan string `json:"plan"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.RequestErrorHandler(w, errors.New("invalid request"))
+		return
+	}
+
+	err = a.quotas.SetUserPlan(userID, req.Plan)
+	switch {
+	case errors.Is(err, quotas.ErrPlanNotFound):
+		api.RequestErrorHandler(w, err)
+	case errors.Is(err, store.ErrNotFound):
+		api.NotFoundErrorHandler(w, "User not found")
+	case err != nil:
+		a.internalError(w, err)
+	default:
+		usage, err := a.quotas.GetUsage(userID, time.Now())
+		if err != nil {
+			a.internalError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(usage)
+	}
+}
@@ -0,0 +1,430 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// fakeRuntime simule le démon Docker en mémoire : images, réseaux et conteneurs.
+// Un conteneur démarré se termine selon exit, ou tourne jusqu'à Kill/Stop si Block.
+type fakeRuntime struct {
+	mu sync.Mutex
+	// Démon injoignable : chaque appel échoue avec cette erreur
+	down error
+
+	images     map[string]bool
+	pulls      []string
+	networks   map[string]bool
+	containers map[string]*fakeContainer // par ID
+	seq        int
+
+	// exit décide de la sortie d'un conteneur démarré (stdin déjà lu) ; nil : code 0 sans sortie
+	exit func(c *fakeContainer) fakeExit
+	// Flux de stats renvoyé par ContainerStats (JSON concaténés)
+	stats string
+	// Code de sortie des commandes de health check
+	execExitCode int
+	execs        [][]string
+}
+
+type fakeExit struct {
+	Code           int64
+	Stdout, Stderr string
+	// Le conteneur tourne jusqu'à ContainerKill ou ContainerStop
+	Block bool
+}
+
+type fakeContainer struct {
+	ID, Name string
+	Config   *container.Config
+	Host     *container.HostConfig
+
+	status   string // created, running, exited
+	started  time.Time
+	exitCode int64
+	output   fakeExit
+	killed   bool
+	stopped  bool
+	stdin    bytes.Buffer
+	// Fermé à la fin du conteneur
+	done chan struct{}
+	// Fermé quand le stdin attaché avant le démarrage est entièrement lu
+	stdinDone chan struct{}
+}
+
+func newFakeRuntime() *fakeRuntime {
+	return &fakeRuntime{
+		images:     map[string]bool{},
+		networks:   map[string]bool{},
+		containers: map[string]*fakeContainer{},
+	}
+}
+
+// container retourne le conteneur par ID ou par nom, sous verrou
+func (f *fakeRuntime) container(ref string) (*fakeContainer, error) {
+	if c, ok := f.containers[ref]; ok {
+		return c, nil
+	}
+	for _, c := range f.containers {
+		if c.Name == ref {
+			return c, nil
+		}
+	}
+	return nil, errdefs.NotFound(fmt.Errorf("No such container: %s", ref))
+}
+
+// byName retourne le conteneur nommé name, nil s'il n'existe pas (ou plus)
+func (f *fakeRuntime) byName(name string) *fakeContainer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, _ := f.container(name)
+	return c
+}
+
+// created retourne les noms des conteneurs créés et pas encore supprimés
+func (f *fakeRuntime) created() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var names []string
+	for _, c := range f.containers {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// exitContainer termine le conteneur nommé name comme si son processus sortait avec code
+func (f *fakeRuntime) exitContainer(name string, code int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, err := f.container(name); err == nil {
+		c.finish(code)
+	}
+}
+
+// finish arrête le conteneur avec code, sous verrou
+func (c *fakeContainer) finish(code int64) {
+	if c.status == "exited" {
+		return
+	}
+	c.status, c.exitCode = "exited", code
+	close(c.done)
+}
+
+func (f *fakeRuntime) ImageInspectWithRaw(ctx context.Context, ref string) (types.ImageInspect, []byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return types.ImageInspect{}, nil, f.down
+	}
+	if !f.images[ref] {
+		return types.ImageInspect{}, nil, errdefs.NotFound(fmt.Errorf("No such image: %s", ref))
+	}
+	return types.ImageInspect{ID: ref}, nil, nil
+}
+
+func (f *fakeRuntime) ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return nil, f.down
+	}
+	f.pulls = append(f.pulls, ref)
+	f.images[ref] = true
+	return io.NopCloser(strings.NewReader(`{"status":"Downloaded newer image for ` + ref + `"}`)), nil
+}
+
+func (f *fakeRuntime) ContainerStats(ctx context.Context, containerID string, stream bool) (container.StatsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return container.StatsResponse{}, f.down
+	}
+	return container.StatsResponse{Body: io.NopCloser(strings.NewReader(f.stats))}, nil
+}
+
+func (f *fakeRuntime) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return container.CreateResponse{}, f.down
+	}
+	if !f.images[config.Image] {
+		return container.CreateResponse{}, errdefs.NotFound(fmt.Errorf("No such image: %s", config.Image))
+	}
+	if _, err := f.container(containerName); err == nil {
+		return container.CreateResponse{}, errdefs.Conflict(fmt.Errorf("container name %s is already in use", containerName))
+	}
+	if mode := string(hostConfig.NetworkMode); mode != "" && !f.networks[mode] {
+		return container.CreateResponse{}, errdefs.NotFound(fmt.Errorf("network %s not found", mode))
+	}
+	f.seq++
+	c := &fakeContainer{
+		ID:        fmt.Sprintf("container-%d", f.seq),
+		Name:      containerName,
+		Config:    config,
+		Host:      hostConfig,
+		status:    "created",
+		done:      make(chan struct{}),
+		stdinDone: make(chan struct{}),
+	}
+	if !config.AttachStdin {
+		close(c.stdinDone)
+	}
+	f.containers[c.ID] = c
+	return container.CreateResponse{ID: c.ID}, nil
+}
+
+// fakeConn ferme toute la connexion à CloseWrite, ce qui suffit à signaler la fin de stdin
+type fakeConn struct{ net.Conn }
+
+func (c fakeConn) CloseWrite() error { return c.Conn.Close() }
+
+func (f *fakeRuntime) ContainerAttach(ctx context.Context, containerID string, options container.AttachOptions) (types.HijackedResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return types.HijackedResponse{}, f.down
+	}
+	c, err := f.container(containerID)
+	if err != nil {
+		return types.HijackedResponse{}, err
+	}
+	local, remote := net.Pipe()
+	beforeStart := c.status == "created"
+	go func() {
+		data, _ := io.ReadAll(remote)
+		remote.Close()
+		f.mu.Lock()
+		c.stdin.Write(data)
+		f.mu.Unlock()
+		if beforeStart && c.Config.AttachStdin {
+			close(c.stdinDone)
+		}
+	}()
+	return types.HijackedResponse{Conn: fakeConn{local}, Reader: bufio.NewReader(local)}, nil
+}
+
+func (f *fakeRuntime) ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return f.down
+	}
+	c, err := f.container(containerID)
+	if err != nil {
+		return err
+	}
+	c.status, c.started = "running", time.Now()
+	go func() {
+		<-c.stdinDone
+		var out fakeExit
+		if f.exit != nil {
+			out = f.exit(c)
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		c.output = out
+		if !out.Block {
+			c.finish(out.Code)
+		}
+	}()
+	return nil
+}
+
+func (f *fakeRuntime) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return types.ContainerJSON{}, f.down
+	}
+	c, err := f.container(containerID)
+	if err != nil {
+		return types.ContainerJSON{}, err
+	}
+	inspect := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:   c.ID,
+			Name: "/" + c.Name,
+			State: &types.ContainerState{
+				Status:    c.status,
+				Running:   c.status == "running",
+				ExitCode:  int(c.exitCode),
+				StartedAt: c.started.Format(time.RFC3339Nano),
+			},
+		},
+		NetworkSettings: &types.NetworkSettings{Networks: map[string]*network.EndpointSettings{}},
+	}
+	if mode := string(c.Host.NetworkMode); mode != "" {
+		inspect.NetworkSettings.Networks[mode] = &network.EndpointSettings{IPAddress: "10.0.0." + strings.TrimPrefix(c.ID, "container-")}
+	}
+	return inspect, nil
+}
+
+func (f *fakeRuntime) ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	statusCh, errCh := make(chan container.WaitResponse, 1), make(chan error, 1)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		errCh <- f.down
+		return statusCh, errCh
+	}
+	c, err := f.container(containerID)
+	if err != nil {
+		errCh <- err
+		return statusCh, errCh
+	}
+	go func() {
+		select {
+		case <-c.done:
+			f.mu.Lock()
+			statusCh <- container.WaitResponse{StatusCode: c.exitCode}
+			f.mu.Unlock()
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+		}
+	}()
+	return statusCh, errCh
+}
+
+func (f *fakeRuntime) ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return nil, f.down
+	}
+	c, err := f.container(containerID)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if options.ShowStdout && c.output.Stdout != "" {
+		stdcopy.NewStdWriter(&out, stdcopy.Stdout).Write([]byte(c.output.Stdout))
+	}
+	if options.ShowStderr && c.output.Stderr != "" {
+		stdcopy.NewStdWriter(&out, stdcopy.Stderr).Write([]byte(c.output.Stderr))
+	}
+	return io.NopCloser(&out), nil
+}
+
+func (f *fakeRuntime) ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return f.down
+	}
+	c, err := f.container(containerID)
+	if err != nil {
+		return err
+	}
+	c.stopped = true
+	c.finish(0)
+	return nil
+}
+
+func (f *fakeRuntime) ContainerKill(ctx context.Context, containerID, signal string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return f.down
+	}
+	c, err := f.container(containerID)
+	if err != nil {
+		return err
+	}
+	if c.status != "running" {
+		return errdefs.Conflict(fmt.Errorf("container %s is not running", containerID))
+	}
+	c.killed = true
+	c.finish(137)
+	return nil
+}
+
+func (f *fakeRuntime) ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return f.down
+	}
+	c, err := f.container(containerID)
+	if err != nil {
+		return err
+	}
+	if c.status == "running" {
+		if !options.Force {
+			return errdefs.Conflict(fmt.Errorf("container %s is running", containerID))
+		}
+		c.killed = true
+		c.finish(137)
+	}
+	delete(f.containers, c.ID)
+	return nil
+}
+
+func (f *fakeRuntime) ContainerExecCreate(ctx context.Context, containerID string, options container.ExecOptions) (types.IDResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return types.IDResponse{}, f.down
+	}
+	if _, err := f.container(containerID); err != nil {
+		return types.IDResponse{}, err
+	}
+	f.execs = append(f.execs, options.Cmd)
+	return types.IDResponse{ID: fmt.Sprintf("exec-%d", len(f.execs))}, nil
+}
+
+func (f *fakeRuntime) ContainerExecStart(ctx context.Context, execID string, options container.ExecStartOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.down
+}
+
+func (f *fakeRuntime) ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return container.ExecInspect{}, f.down
+	}
+	return container.ExecInspect{ExecID: execID, ExitCode: f.execExitCode}, nil
+}
+
+func (f *fakeRuntime) NetworkInspect(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return network.Inspect{}, f.down
+	}
+	if !f.networks[networkID] {
+		return network.Inspect{}, errdefs.NotFound(fmt.Errorf("network %s not found", networkID))
+	}
+	return network.Inspect{Name: networkID}, nil
+}
+
+func (f *fakeRuntime) NetworkCreate(ctx context.Context, name string, options network.CreateOptions) (network.CreateResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return network.CreateResponse{}, f.down
+	}
+	if f.networks[name] {
+		return network.CreateResponse{}, errdefs.Conflict(errors.New("network with name " + name + " already exists"))
+	}
+	f.networks[name] = true
+	return network.CreateResponse{ID: name}, nil
+}
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/cron"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/quotas"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// Politique quand une exécution précédente du même planning tourne encore
+const (
+	OverlapSkip  = "skip"
+	OverlapQueue = "queue"
+	OverlapAllow = "allow"
+)
+
+// Fréquence de vérification des plannings échus
+const schedulerInterval = 10 * time.Second
+
+type Schedule struct {
+	ID            string  `json:"id"`
+	ScriptID      string  `json:"script_id"`
+	Cron          string  `json:"cron"`
+	Timezone      string  `json:"timezone"`
+	Enabled       bool    `json:"enabled"`
+	OverlapPolicy string  `json:"overlap_policy"`
+	NextRunAt     *string `json:"next_run_at"`
+	LastRunAt     *string `json:"last_run_at"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+type scheduleRequest struct {
+	Cron          *string `json:"cron"`
+	Timezone      *string `json:"timezone"`
+	Enabled       *bool   `json:"enabled"`
+	OverlapPolicy *string `json:"overlap_policy"`
+}
+
+// nextRun calcule la prochaine occurrence (UTC) après `after` dans le fuseau du planning
+func nextRun(expr, timezone string, after time.Time) (time.Time, error) {
+	sched, err := cron.Parse(expr)
+	if err != nil {
+		return time.Time{}, api.Invalid("cron", "%v", err)
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, api.Invalid("timezone", "unknown timezone: %s", timezone)
+	}
+
+	next := sched.Next(after.In(loc))
+	if next.IsZero() {
+		return time.Time{}, api.Invalid("cron", "expression %q never fires", expr)
+	}
+	return next.UTC(), nil
+}
+
+func validOverlapPolicy(policy string) bool {
+	return policy == OverlapSkip || policy == OverlapQueue || policy == OverlapAllow
+}
+
+// applyScheduleRequest valide la requête et recalcule next_run_at
+func applyScheduleRequest(s *Schedule, req scheduleRequest) error {
+	if req.Cron != nil {
+		s.Cron = *req.Cron
+	}
+	if req.Timezone != nil {
+		s.Timezone = *req.Timezone
+	}
+	if req.Enabled != nil {
+		s.Enabled = *req.Enabled
+	}
+	if req.OverlapPolicy != nil {
+		s.OverlapPolicy = *req.OverlapPolicy
+	}
+
+	if s.Cron == "" {
+		return api.Invalid("cron", "is required")
+	}
+	if !validOverlapPolicy(s.OverlapPolicy) {
+		return api.Invalid("overlap_policy", "%s is not supported (supported: skip, queue, allow)", s.OverlapPolicy)
+	}
+
+	next, err := nextRun(s.Cron, s.Timezone, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Un planning désactivé n'a pas de prochaine exécution
+	s.NextRunAt = nil
+	if s.Enabled {
+		formatted := next.Format(time.RFC3339)
+		s.NextRunAt = &formatted
+	}
+	return nil
+}
+
+func scheduleView(s store.Schedule) Schedule {
+	return Schedule{
+		ID: s.ID, ScriptID: s.ScriptID, Cron: s.Cron, Timezone: s.Timezone, Enabled: s.Enabled,
+		OverlapPolicy: s.OverlapPolicy, NextRunAt: s.NextRunAt, LastRunAt: s.LastRunAt, CreatedAt: s.CreatedAt,
+	}
+}
+
+func (s Schedule) stored(userID int) store.Schedule {
+	return store.Schedule{
+		ID: s.ID, ScriptID: s.ScriptID, UserID: userID, Cron: s.Cron, Timezone: s.Timezone, Enabled: s.Enabled,
+		OverlapPolicy: s.OverlapPolicy, NextRunAt: s.NextRunAt,
+	}
+}
+
+// ListSchedulesHandler — GET /scripts/{id}/schedules
+func (a *App) ListSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	scriptID := chi.URLParam(r, "id")
+
+	if _, err := a.loadScript(scriptID, userID); err != nil {
+		api.NotFoundErrorHandler(w, "Script not found")
+		return
+	}
+
+	stored, err := a.store.Schedules.List(scriptID, userID)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	schedules := []Schedule{}
+	for _, s := range stored {
+		schedules = append(schedules, scheduleView(s))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedules)
+}
+
+// CreateScheduleHandler — POST /scripts/{id}/schedules
+// JSON : cron, timezone (UTC), enabled (true), overlap_policy (skip)
+func (a *App) CreateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	scriptID := chi.URLParam(r, "id")
+
+	if _, err := a.loadScript(scriptID, userID); err != nil {
+		api.NotFoundErrorHandler(w, "Script not found")
+		return
+	}
+
+	var req scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.RequestErrorHandler(w, errors.New("invalid request"))
+		return
+	}
+
+	s := Schedule{
+		ID:            uuid.New().String(),
+		ScriptID:      scriptID,
+		Timezone:      "UTC",
+		Enabled:       true,
+		OverlapPolicy: OverlapSkip,
+	}
+	if err := applyScheduleRequest(&s, req); err != nil {
+		api.RequestErrorHandler(w, err)
+		return
+	}
+
+	if err := a.store.Schedules.Create(s.stored(userID)); err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	created, err := a.store.Schedules.GetForUser(s.ID, userID)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+	s = scheduleView(created)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s)
+}
+
+// UpdateScheduleHandler — PATCH /schedules/{id}
+func (a *App) UpdateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	scheduleID := chi.URLParam(r, "id")
+
+	stored, err := a.store.Schedules.GetForUser(scheduleID, userID)
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Schedule not found")
+		return
+	}
+	s := scheduleView(stored)
+
+	var req scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.RequestErrorHandler(w, errors.New("invalid request"))
+		return
+	}
+	if err := applyScheduleRequest(&s, req); err != nil {
+		api.RequestErrorHandler(w, err)
+		return
+	}
+
+	if err := a.store.Schedules.Update(s.stored(userID)); err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// DeleteScheduleHandler — DELETE /schedules/{id}
+func (a *App) DeleteScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	scheduleID := chi.URLParam(r, "id")
+
+	err := a.store.Schedules.Delete(scheduleID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		api.NotFoundErrorHandler(w, "Schedule not found")
+		return
+	}
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StartScheduler lance la boucle qui déclenche les plannings échus
+func (a *App) StartScheduler(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(schedulerInterval)
+		defer ticker.Stop()
+
+		for {
+			a.runDueSchedules(time.Now().UTC())
+			a.startQueuedExecutions()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// runDueSchedules déclenche chaque planning dont next_run_at est passé.
+// Après un redémarrage, les occurrences manquées ne donnent lieu qu'à une seule exécution.
+func (a *App) runDueSchedules(now time.Time) {
+	schedules, err := a.store.Schedules.Due(now.Format(time.RFC3339))
+	if err != nil {
+		a.log.Errorf("scheduler: %v", err)
+		return
+	}
+
+	for _, d := range schedules {
+		var nextValue *string
+		if next, err := nextRun(d.Cron, d.Timezone, now); err == nil {
+			formatted := next.Format(time.RFC3339)
+			nextValue = &formatted
+		} else {
+			a.log.Errorf("scheduler: schedule %s: %v", d.ID, err)
+		}
+
+		// Réserver l'occurrence : seul celui qui avance next_run_at la déclenche
+		claimed, err := a.store.Schedules.Claim(d.ID, *d.NextRunAt, nextValue, now.Format(time.RFC3339))
+		if err != nil {
+			a.log.Errorf("scheduler: schedule %s: %v", d.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		a.fireSchedule(d.ID, d.ScriptID, d.UserID, d.OverlapPolicy)
+	}
+}
+
+func (a *App) fireSchedule(scheduleID, scriptID string, userID int, policy string) {
+	script, err := a.loadScript(scriptID, userID)
+	if err != nil {
+		a.log.Errorf("scheduler: schedule %s: script %s: %v", scheduleID, scriptID, err)
+		return
+	}
+
+	active, err := a.store.Executions.ActiveForSchedule(scheduleID)
+	if err != nil {
+		a.log.Errorf("scheduler: schedule %s: %v", scheduleID, err)
+		return
+	}
+
+	switch {
+	case active > 0 && policy == OverlapSkip:
+		a.log.Infof("scheduler: schedule %s skipped, previous run still active", scheduleID)
+		return
+	case active > 0 && policy == OverlapQueue:
+		err = a.store.Executions.Create(store.Execution{
+			ID:         uuid.New().String(),
+			ScriptID:   script.ID,
+			UserID:     script.UserID,
+			Status:     "queued",
+			Trigger:    TriggerSchedule,
+			ScheduleID: &scheduleID,
+		})
+	default:
+		_, err = a.startExecution(script, executionRequest{Trigger: TriggerSchedule, ScheduleID: scheduleID})
+	}
+	if err != nil {
+		a.log.Errorf("scheduler: schedule %s: %v", scheduleID, err)
+	}
+}
+
+// startQueuedExecutions démarre la plus ancienne exécution en attente de chaque planning libre
+func (a *App) startQueuedExecutions() {
+	executions, err := a.store.Executions.Queued()
+	if err != nil {
+		a.log.Errorf("scheduler: %v", err)
+		return
+	}
+
+	type queued struct {
+		ExecutionID string
+		ScheduleID  string
+		Script      scriptRun
+	}
+	var pending []queued
+	seen := map[string]bool{}
+	for _, e := range executions {
+		q := queued{ExecutionID: e.ID}
+		if e.ScheduleID != nil {
+			q.ScheduleID = *e.ScheduleID
+		}
+		if seen[q.ScheduleID] {
+			continue
+		}
+		if q.Script, err = a.loadScript(e.ScriptID, e.UserID); err != nil {
+			continue
+		}
+		seen[q.ScheduleID] = true
+		pending = append(pending, q)
+	}
+
+	for _, q := range pending {
+		// Arrêt en cours : l'exécution reste en attente jusqu'au prochain démarrage
+		if !a.track() {
+			return
+		}
+		// Trop d'exécutions en cours : l'exécution reste en attente jusqu'au prochain tour
+		startedAt := time.Now().UTC().Format(time.RFC3339)
+		started, err := a.quotas.StartQueued(q.ExecutionID, q.Script.UserID, startedAt)
+		if errors.Is(err, quotas.ErrQuotaExceeded) {
+			a.running.Done()
+			a.storeLogs(q.ExecutionID, "stderr", err.Error())
+			a.updateExecution(q.ExecutionID, "failed", -1)
+			continue
+		}
+		if err != nil {
+			a.running.Done()
+			a.log.Errorf("scheduler: execution %s: %v", q.ExecutionID, err)
+			continue
+		}
+		if !started {
+			a.running.Done()
+			continue
+		}
+		a.store.Scripts.SetLastRun(q.Script.ID, startedAt)
+		a.emitExecutionEvent(q.ExecutionID, EventExecutionStarted)
+
+		go a.runContainer(q.ExecutionID, q.Script, executionRequest{Trigger: TriggerSchedule, ScheduleID: q.ScheduleID}, nil)
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+)
+
+// countExecutions retourne le nombre d'exécutions d'un script vues par l'API
+func countExecutions(t *testing.T, c *http.Client, srv *httptest.Server, scriptID string) int {
+	t.Helper()
+	resp, err := c.Get(srv.URL + api.Prefix + "/scripts/" + scriptID + "/executions?limit=100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var page struct {
+		Executions []ExecutionSummary `json:"executions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	return len(page.Executions)
+}
+
+func TestSchedulerFiresOnceAfterRestart(t *testing.T) {
+	app, st := newTestApp(t)
+	srv := httptest.NewServer(app.Handler())
+	defer srv.Close()
+
+	createUser(t, st, "alice", "secret")
+	createScript(t, st, "alice", "script-1", "cobol")
+	c, _ := login(t, srv, "alice", "secret")
+	resp, err := c.Post(srv.URL+api.Prefix+"/scripts/script-1/schedules", "application/json", strings.NewReader(`{"cron":"0 * * * *","timezone":"Europe/Paris"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create schedule: %d", resp.StatusCode)
+	}
+
+	// Trois heures d'arrêt : les occurrences manquées ne donnent qu'une exécution
+	now := time.Now().UTC().Add(3 * time.Hour)
+	app.runDueSchedules(now)
+	app.runDueSchedules(now)
+
+	// Redémarrage : une nouvelle instance sur la même base ne redéclenche pas l'occurrence
+	restarted := New(st, app.languages, app.runtime, app.config, app.log)
+	restarted.runDueSchedules(now)
+
+	// Attendre la fin de l'exécution, sinon le quota d'exécutions simultanées refuse la suivante
+	app.running.Wait()
+
+	// Deux instances qui voient la même occurrence échue : une seule la réserve
+	later := now.Add(time.Hour)
+	done := make(chan struct{})
+	go func() {
+		app.runDueSchedules(later)
+		close(done)
+	}()
+	restarted.runDueSchedules(later)
+	<-done
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := app.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := restarted.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := countExecutions(t, c, srv, "script-1"); n != 2 {
+		t.Fatalf("%d executions, want 2 (one per due occurrence)", n)
+	}
+
+	resp, err = c.Get(srv.URL + api.Prefix + "/scripts/script-1/schedules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schedules []Schedule
+	json.NewDecoder(resp.Body).Decode(&schedules)
+	resp.Body.Close()
+	if len(schedules) != 1 || schedules[0].NextRunAt == nil {
+		t.Fatalf("schedules %+v", schedules)
+	}
+	if next, _ := time.Parse(time.RFC3339, *schedules[0].NextRunAt); !next.After(later) {
+		t.Errorf("next_run_at %s is not after %s", *schedules[0].NextRunAt, later)
+	}
+}
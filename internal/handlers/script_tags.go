@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/go-chi/chi"
+)
+
+// Nombre max de tags par script
+const maxScriptTags = 20
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,31}$`)
+
+// normalizeTags met les tags en minuscules, retire les doublons et les vérifie
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || slices.Contains(normalized, t) {
+			continue
+		}
+		if !tagPattern.MatchString(t) {
+			return nil, api.Invalid("tags", "invalid tag %q: lowercase letters, digits, '_', '.' and '-' (32 max)", t)
+		}
+		normalized = append(normalized, t)
+	}
+	if len(normalized) > maxScriptTags {
+		return nil, api.Invalid("tags", "too many tags (max %d)", maxScriptTags)
+	}
+	slices.Sort(normalized)
+	return normalized, nil
+}
+
+// SetScriptTagsHandler — PUT /scripts/{id}/tags
+// JSON : ["tag", ...] remplace la liste
+func (a *App) SetScriptTagsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	scriptID := chi.URLParam(r, "id")
+
+	if _, err := a.loadScript(scriptID, userID); err != nil {
+		api.NotFoundErrorHandler(w, "Script not found")
+		return
+	}
+
+	var req []string
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.RequestErrorHandler(w, errors.New("invalid request: expected a JSON array of tags"))
+		return
+	}
+	tags, err := normalizeTags(req)
+	if err != nil {
+		api.RequestErrorHandler(w, err)
+		return
+	}
+
+	if err := a.store.Scripts.SetTags(scriptID, userID, tags); err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// ListTagsHandler — GET /tags
+// Tags de l'utilisateur avec leur nombre de scripts
+func (a *App) ListTagsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	counts, err := a.store.Scripts.TagCounts(userID)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	type TagCount struct {
+		Tag     string `json:"tag"`
+		Scripts int    `json:"scripts"`
+	}
+
+	tags := []TagCount{}
+	for _, t := range counts {
+		tags = append(tags, TagCount(t))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
@@ -1,38 +1,38 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/images"
 	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
 )
 
-// Map langage -> image Docker
-var languageImages = map[string]string{
-	"python": "python:3.11-alpine",
-	"bash":   "bash:5-alpine",
-	"nodejs": "node:20-alpine",
-	"js":     "node:20-alpine",
-}
+// Temps max accordé au téléchargement d'une image absente de l'hôte
+const imagePullTimeout = 5 * time.Minute
 
-// Map langage -> extension fichier
-var languageExtensions = map[string]string{
-	"python": ".py",
-	"bash":   ".sh",
-	"nodejs": ".js",
-	"js":     ".js",
+// pullImage télécharge l'image si elle n'est pas déjà présente sur l'hôte
+func (a *App) pullImage(ctx context.Context, ref string) error {
+	ctx, cancel := context.WithTimeout(ctx, imagePullTimeout)
+	defer cancel()
+	return images.Ensure(ctx, a.runtime, ref)
 }
 
 // UploadScriptHandler — POST /scripts/upload
-// multipart/form-data : name, description, language, file
-func UploadScriptHandler(w http.ResponseWriter, r *http.Request) {
+// multipart/form-data : name, description, language, file, docker_image (optionnel), tags (optionnel, "a,b")
+func (a *App) UploadScriptHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(int)
 
 	// Limite à 10MB
@@ -42,53 +42,104 @@ func UploadScriptHandler(w http.ResponseWriter, r *http.Request) {
 	description := r.FormValue("description")
 	language := r.FormValue("language")
 
-	if name == "" || language == "" {
-		api.RequestErrorHandler(w, fmt.Errorf("name and language are required"))
+	var missing []error
+	if name == "" {
+		missing = append(missing, api.Invalid("name", "is required"))
+	}
+	if language == "" {
+		missing = append(missing, api.Invalid("language", "is required"))
+	}
+	if len(missing) > 0 {
+		api.RequestErrorHandler(w, errors.Join(missing...))
+		return
+	}
+
+	tags, err := normalizeTags(strings.Split(r.FormValue("tags"), ","))
+	if err != nil {
+		api.RequestErrorHandler(w, err)
 		return
 	}
 
-	dockerImage, ok := languageImages[language]
+	lang, ok := a.languages.Get(language)
 	if !ok {
-		api.RequestErrorHandler(w, fmt.Errorf("unsupported language: %s (supported: python, bash, nodejs, js)", language))
+		api.RequestErrorHandler(w, api.Invalid("language", "%s is not supported (supported: %s)", language, strings.Join(a.languages.Names(), ", ")))
 		return
 	}
+	dockerImage := lang.Image
+
+	// Image personnalisée : doit être dans l'allow-list
+	if custom := r.FormValue("docker_image"); custom != "" {
+		dockerImage, err = a.images.Check(custom)
+		if errors.Is(err, images.ErrInvalidReference) || errors.Is(err, images.ErrNotAllowed) || errors.Is(err, images.ErrDigestRequired) {
+			api.RequestErrorHandler(w, api.Invalid("docker_image", "%s: %v", custom, err))
+			return
+		} else if err != nil {
+			a.internalError(w, err)
+			return
+		}
+
+		// Image personnalisée absente de l'hôte : pré-pull pour signaler tout de suite une image introuvable.
+		// Les images des langages sont téléchargées au premier lancement.
+		if err := a.pullImage(r.Context(), dockerImage); err != nil {
+			a.log.WithField("request_id", w.Header().Get(api.RequestIDHeader)).Warnf("scripts: pull %s: %v", dockerImage, err)
+			api.RequestErrorHandler(w, api.Invalid("docker_image", "%s can't be pulled", dockerImage))
+			return
+		}
+	}
 
-	file, _, err := r.FormFile("file")
+	file, header, err := r.FormFile("file")
 	if err != nil {
-		api.RequestErrorHandler(w, fmt.Errorf("file is required"))
+		api.RequestErrorHandler(w, api.Invalid("file", "is required"))
 		return
 	}
 	defer file.Close()
 
+	if err := a.quotas.CheckUpload(userID, header.Size); quotaError(w, err) {
+		return
+	} else if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
 	// Créer le dossier du script
 	scriptID := uuid.New().String()
-	ext := languageExtensions[language]
-	dirPath := filepath.Join("data", "scripts", scriptID)
+	ext := lang.Extension
+	dirPath := filepath.Join(a.config.DataDir, "scripts", scriptID)
 	filePath := filepath.Join(dirPath, "script"+ext)
 
 	if err := os.MkdirAll(dirPath, 0755); err != nil {
-		api.InternalErrorHandler(w)
+		a.internalError(w, err)
 		return
 	}
 
 	// Écrire le fichier sur disque
 	dst, err := os.Create(filePath)
 	if err != nil {
-		api.InternalErrorHandler(w)
+		a.internalError(w, err)
 		return
 	}
 	defer dst.Close()
 	io.Copy(dst, file)
 
 	// Insérer en base
-	_, err = db.Exec(
-		`INSERT INTO scripts (id, user_id, name, description, language, docker_image, file_path)
-		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		scriptID, userID, name, description, language, dockerImage, filePath,
-	)
+	err = a.quotas.CreateScript(store.Script{
+		ID:          scriptID,
+		UserID:      userID,
+		Name:        name,
+		Description: description,
+		Language:    language,
+		DockerImage: dockerImage,
+		FilePath:    filePath,
+	})
 	if err != nil {
 		os.RemoveAll(dirPath) // rollback fichier
-		api.InternalErrorHandler(w)
+		if !quotaError(w, err) {
+			a.internalError(w, err)
+		}
+		return
+	}
+	if err := a.store.Scripts.SetTags(scriptID, userID, tags); err != nil {
+		a.internalError(w, err)
 		return
 	}
 
@@ -100,92 +151,185 @@ func UploadScriptHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// Tris possibles de GET /scripts pour ?sort=
+var scriptSorts = []string{"name", "created_at", "last_run_at"}
+
 // ListScriptsHandler — GET /scripts
-func ListScriptsHandler(w http.ResponseWriter, r *http.Request) {
+// ?q=texte&tag=a,b&sort=-created_at|name|last_run_at&limit=50&cursor= ; page suivante dans next_cursor
+// (en-tête X-Next-Cursor et tableau seul sur l'ancienne route /scripts)
+func (a *App) ListScriptsHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(int)
+	query := r.URL.Query()
+
+	f := store.ScriptFilter{UserID: userID, Words: strings.Fields(query.Get("q"))}
+	// Tous les tags demandés doivent être présents
+	if v := query.Get("tag"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			f.Tags = append(f.Tags, strings.ToLower(strings.TrimSpace(t)))
+		}
+	}
+
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = "-created_at"
+	}
+	f.Sort, f.Desc = strings.CutPrefix(sort, "-")
+	if !slices.Contains(scriptSorts, f.Sort) {
+		api.RequestErrorHandler(w, api.Invalid("sort", "%s is not supported (supported: name, created_at, last_run_at, prefixed with - for descending)", sort))
+		return
+	}
 
-	rows, err := db.Query(
-		`SELECT id, name, description, language, docker_image, created_at FROM scripts WHERE user_id = ? ORDER BY created_at DESC`,
-		userID,
-	)
+	limit, err := pageSize(r)
 	if err != nil {
-		api.InternalErrorHandler(w)
+		api.RequestErrorHandler(w, err)
+		return
+	}
+	f.Limit = limit
+	if v := query.Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+		if err != nil {
+			api.RequestErrorHandler(w, err)
+			return
+		}
+		f.After = (*store.Cursor)(&c)
+	}
+
+	stored, cursor, err := a.store.Scripts.List(f)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		api.RequestErrorHandler(w, api.Invalid("cursor", "is invalid"))
+		return
+	}
+	if err != nil {
+		a.internalError(w, err)
 		return
 	}
-	defer rows.Close()
 
 	type ScriptRow struct {
-		ID          string `json:"id"`
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		Language    string `json:"language"`
-		DockerImage string `json:"docker_image"`
-		CreatedAt   string `json:"created_at"`
+		ID          string   `json:"id"`
+		Name        string   `json:"name"`
+		Description string   `json:"description"`
+		Language    string   `json:"language"`
+		DockerImage string   `json:"docker_image"`
+		Tags        []string `json:"tags"`
+		CreatedAt   string   `json:"created_at"`
+		LastRunAt   *string  `json:"last_run_at"`
 	}
 
 	scripts := []ScriptRow{}
-	for rows.Next() {
-		var s ScriptRow
-		rows.Scan(&s.ID, &s.Name, &s.Description, &s.Language, &s.DockerImage, &s.CreatedAt)
-		scripts = append(scripts, s)
+	var ids []string
+	for _, s := range stored {
+		scripts = append(scripts, ScriptRow{
+			ID: s.ID, Name: s.Name, Description: s.Description, Language: s.Language, DockerImage: s.DockerImage,
+			CreatedAt: s.CreatedAt, LastRunAt: s.LastRunAt,
+		})
+		ids = append(ids, s.ID)
+	}
+	var next *string
+	if cursor != nil {
+		c := encodeCursor(pageCursor(*cursor))
+		next = &c
+	}
+
+	tags, err := a.store.Scripts.Tags(ids)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+	for i := range scripts {
+		scripts[i].Tags = tags[scripts[i].ID]
+		if scripts[i].Tags == nil {
+			scripts[i].Tags = []string{}
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(scripts)
+	if legacyRequest(r) {
+		if next != nil {
+			w.Header().Set("X-Next-Cursor", *next)
+		}
+		json.NewEncoder(w).Encode(scripts)
+		return
+	}
+	json.NewEncoder(w).Encode(struct {
+		Scripts    []ScriptRow `json:"scripts"`
+		NextCursor *string     `json:"next_cursor"`
+	}{scripts, next})
 }
 
 // GetScriptHandler — GET /scripts/{id}
-func GetScriptHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) GetScriptHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(int)
 	scriptID := chi.URLParam(r, "id")
 
 	type ScriptDetail struct {
-		ID          string `json:"id"`
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		Language    string `json:"language"`
-		DockerImage string `json:"docker_image"`
-		FilePath    string `json:"file_path"`
-		CreatedAt   string `json:"created_at"`
+		ID          string        `json:"id"`
+		Name        string        `json:"name"`
+		Description string        `json:"description"`
+		Language    string        `json:"language"`
+		DockerImage string        `json:"docker_image"`
+		FilePath    string        `json:"file_path"`
+		Mounts      []VolumeMount `json:"mounts"`
+		Tags        []string      `json:"tags"`
+		CreatedAt   string        `json:"created_at"`
+		LastRunAt   *string       `json:"last_run_at"`
+	}
+
+	script, err := a.store.Scripts.Get(scriptID, userID)
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Script not found")
+		return
 	}
 
-	var s ScriptDetail
-	err := db.QueryRow(
-		`SELECT id, name, description, language, docker_image, file_path, created_at FROM scripts WHERE id = ? AND user_id = ?`,
-		scriptID, userID,
-	).Scan(&s.ID, &s.Name, &s.Description, &s.Language, &s.DockerImage, &s.FilePath, &s.CreatedAt)
+	s := ScriptDetail{
+		ID:          script.ID,
+		Name:        script.Name,
+		Description: script.Description,
+		Language:    script.Language,
+		DockerImage: script.DockerImage,
+		FilePath:    script.FilePath,
+		Mounts:      parseMounts(script.Mounts),
+		CreatedAt:   script.CreatedAt,
+		LastRunAt:   script.LastRunAt,
+	}
 
+	tags, err := a.store.Scripts.Tags([]string{s.ID})
 	if err != nil {
-		http.Error(w, "Script not found", http.StatusNotFound)
+		a.internalError(w, err)
 		return
 	}
+	s.Tags = tags[s.ID]
+	if s.Tags == nil {
+		s.Tags = []string{}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(s)
 }
 
 // DeleteScriptHandler — DELETE /scripts/{id}
-func DeleteScriptHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) DeleteScriptHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(int)
 	scriptID := chi.URLParam(r, "id")
 
-	var filePath string
-	err := db.QueryRow(
-		`SELECT file_path FROM scripts WHERE id = ? AND user_id = ?`,
-		scriptID, userID,
-	).Scan(&filePath)
-
+	script, err := a.store.Scripts.Get(scriptID, userID)
 	if err != nil {
-		http.Error(w, "Script not found", http.StatusNotFound)
+		api.NotFoundErrorHandler(w, "Script not found")
 		return
 	}
 
+	// Arrêter les serveurs qui font tourner ce script
+	if servers, err := a.store.Servers.ListByScript(scriptID); err == nil {
+		for _, srv := range servers {
+			a.removeServer(srv.ID)
+		}
+	}
+
 	// Supprimer le dossier sur disque
-	dirPath := filepath.Dir(filePath)
+	dirPath := filepath.Dir(script.FilePath)
 	os.RemoveAll(dirPath)
 
 	// Supprimer en base (cascade supprimera aussi executions + logs)
-	db.Exec(`DELETE FROM scripts WHERE id = ?`, scriptID)
+	a.store.Scripts.Delete(scriptID)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+)
+
+// scriptPage lit une page de GET /scripts : ids dans l'ordre, curseur suivant ("" en dernière page) et statut
+func scriptPage(t *testing.T, c *http.Client, target string) ([]string, string, int) {
+	t.Helper()
+	resp, err := c.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var page struct {
+		Scripts []struct {
+			ID   string   `json:"id"`
+			Tags []string `json:"tags"`
+		} `json:"scripts"`
+		NextCursor *string `json:"next_cursor"`
+	}
+	json.NewDecoder(resp.Body).Decode(&page)
+	ids := []string{}
+	for _, s := range page.Scripts {
+		ids = append(ids, s.ID)
+	}
+	var next string
+	if page.NextCursor != nil {
+		next = *page.NextCursor
+	}
+	return ids, next, resp.StatusCode
+}
+
+// seedScripts enregistre les scripts des tests de GET /scripts. La recherche passe par
+// FTS5 avec -tags sqlite_fts5, par LIKE sinon : les résultats attendus sont les mêmes.
+func seedScripts(t *testing.T, st *store.Store) {
+	t.Helper()
+	if err := st.SetupSearch(); err != nil {
+		t.Fatal(err)
+	}
+	createUser(t, st, "alice", "secret")
+	createUser(t, st, "bob", "secret")
+	alice, _ := st.Users.GetByUsername("alice")
+	bob, _ := st.Users.GetByUsername("bob")
+
+	for _, s := range []struct {
+		id, name, description string
+		userID                int
+		tags                  []string
+	}{
+		{"s1", "backup-database", "Nightly dump of the main database", alice.ID, []string{"cron", "db"}},
+		{"s2", "send_report", "Weekly report by mail", alice.ID, []string{"cron", "mail"}},
+		{"s3", "Resize images", "Thumbnails for the blog", alice.ID, []string{"media"}},
+		{"s4", "database-migrate", "Apply schema changes", alice.ID, []string{"db"}},
+		{"s5", "hello", "", alice.ID, nil},
+		{"b1", "backup-database", "Bob's backup", bob.ID, []string{"cron", "db"}},
+	} {
+		err := st.Scripts.Create(store.Script{ID: s.id, UserID: s.userID, Name: s.name, Description: s.description, Language: "python", DockerImage: "python", FilePath: s.id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Scripts.SetTags(s.id, s.userID, s.tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListScriptsSearch(t *testing.T) {
+	srv, st := newTestServer(t)
+	seedScripts(t, st)
+	c, _ := login(t, srv, "alice", "secret")
+
+	for q, want := range map[string][]string{
+		// Chaque mot doit commencer un mot du nom ou de la description, sans tenir compte de la casse
+		"database":        {"s1", "s4"},
+		"DATA":            {"s1", "s4"},
+		"base":            {},
+		"backup nightly":  {"s1"},
+		"backup weekly":   {},
+		"report":          {"s2"},
+		"resize thumb":    {"s3"},
+		"migrate schema":  {"s4"},
+		"100%":            {},
+		"send_report":     {"s2"},
+		"backup-database": {"s1"},
+	} {
+		ids, _, code := scriptPage(t, c, srv.URL+api.Prefix+"/scripts?sort=name&q="+url.QueryEscape(q))
+		if code != http.StatusOK || !slices.Equal(ids, want) {
+			t.Errorf("q=%q: %d %v, want %v", q, code, ids, want)
+		}
+	}
+
+	// Supprimé : l'index suit le script
+	if err := st.Scripts.Delete("s1"); err != nil {
+		t.Fatal(err)
+	}
+	if ids, _, _ := scriptPage(t, c, srv.URL+api.Prefix+"/scripts?q=database"); !slices.Equal(ids, []string{"s4"}) {
+		t.Errorf("after delete: %v", ids)
+	}
+}
+
+func TestListScriptsTags(t *testing.T) {
+	srv, st := newTestServer(t)
+	seedScripts(t, st)
+	c, _ := login(t, srv, "alice", "secret")
+
+	for tag, want := range map[string][]string{
+		"cron":       {"s1", "s2"},
+		"db":         {"s1", "s4"},
+		"cron,db":    {"s1"},
+		" CRON , DB": {"s1"},
+		"cron,media": {},
+		"unknown":    {},
+	} {
+		ids, _, code := scriptPage(t, c, srv.URL+api.Prefix+"/scripts?sort=name&tag="+url.QueryEscape(tag))
+		slices.Sort(ids)
+		if code != http.StatusOK || !slices.Equal(ids, want) {
+			t.Errorf("tag=%q: %d %v, want %v", tag, code, ids, want)
+		}
+	}
+	if ids, _, _ := scriptPage(t, c, srv.URL+api.Prefix+"/scripts?tag=db&q=migrate"); !slices.Equal(ids, []string{"s4"}) {
+		t.Errorf("tag and q: %v", ids)
+	}
+}
+
+func TestListScriptsPaging(t *testing.T) {
+	srv, st := newTestServer(t)
+	seedScripts(t, st)
+	c, _ := login(t, srv, "alice", "secret")
+	list := srv.URL + api.Prefix + "/scripts"
+
+	for _, sort := range []string{"name", "-name", "created_at", "-created_at", "last_run_at", "-last_run_at"} {
+		all, next, _ := scriptPage(t, c, list+"?sort="+sort)
+		if len(all) != 5 || next != "" {
+			t.Fatalf("sort %s: %v, next %q", sort, all, next)
+		}
+
+		var got []string
+		pages := 0
+		for cursor := ""; ; {
+			ids, next, code := scriptPage(t, c, list+"?limit=2&sort="+sort+cursor)
+			if code != http.StatusOK {
+				t.Fatalf("sort %s, page %d: %d", sort, pages, code)
+			}
+			pages++
+			got = append(got, ids...)
+			if next == "" {
+				break
+			}
+			cursor = "&cursor=" + next
+		}
+		if !slices.Equal(got, all) || pages != 3 {
+			t.Errorf("sort %s: %v in %d pages, want %v in 3", sort, got, pages, all)
+		}
+	}
+
+	// Les filtres s'appliquent à chaque page
+	ids, next, _ := scriptPage(t, c, list+"?limit=1&sort=name&tag=cron")
+	if !slices.Equal(ids, []string{"s1"}) || next == "" {
+		t.Fatalf("first filtered page: %v, next %q", ids, next)
+	}
+	if ids, next, _ := scriptPage(t, c, list+"?limit=1&sort=name&tag=cron&cursor="+next); !slices.Equal(ids, []string{"s2"}) || next != "" {
+		t.Errorf("last filtered page: %v, next %q", ids, next)
+	}
+
+	raw := base64.RawURLEncoding.EncodeToString([]byte(`{"k":"yesterday","id":"s1"}`))
+	for _, query := range []string{"?cursor=***", "?sort=created_at&cursor=" + raw, "?sort=size", "?limit=0"} {
+		if _, _, code := scriptPage(t, c, list+query); code != http.StatusBadRequest {
+			t.Errorf("GET /scripts%s: %d, want 400", query, code)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Types de health check
+const (
+	HealthCheckHTTP    = "http"    // GET sur le port du serveur, 2xx/3xx attendu
+	HealthCheckTCP     = "tcp"     // connexion TCP sur le port du serveur
+	HealthCheckCommand = "command" // commande exécutée dans le conteneur, code 0 attendu
+)
+
+// États de santé d'un serveur démarré
+const (
+	HealthStarting  = "starting"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+const (
+	// Fréquence de la boucle du moniteur
+	serverMonitorTick = 5 * time.Second
+	// Valeurs par défaut d'un health check (secondes / nombre d'échecs)
+	defaultHealthInterval = 30
+	defaultHealthTimeout  = 5
+	defaultHealthRetries  = 3
+)
+
+type HealthCheck struct {
+	Type     string   `json:"type"`
+	Path     string   `json:"path,omitempty"`
+	Command  []string `json:"command,omitempty"`
+	Interval int      `json:"interval"`
+	Timeout  int      `json:"timeout"`
+	Retries  int      `json:"retries"`
+}
+
+// normalize complète les valeurs par défaut et valide la configuration
+func (h *HealthCheck) normalize() error {
+	switch h.Type {
+	case HealthCheckHTTP:
+		if h.Path == "" {
+			h.Path = "/"
+		}
+		if !strings.HasPrefix(h.Path, "/") {
+			return errors.New("health_check.path must start with /")
+		}
+		h.Command = nil
+	case HealthCheckTCP:
+		h.Path, h.Command = "", nil
+	case HealthCheckCommand:
+		if len(h.Command) == 0 {
+			return errors.New("health_check.command is required")
+		}
+		h.Path = ""
+	default:
+		return fmt.Errorf("invalid health_check.type: %s (supported: http, tcp, command)", h.Type)
+	}
+
+	if h.Interval == 0 {
+		h.Interval = defaultHealthInterval
+	}
+	if h.Timeout == 0 {
+		h.Timeout = defaultHealthTimeout
+	}
+	if h.Retries == 0 {
+		h.Retries = defaultHealthRetries
+	}
+	if h.Interval < 1 || h.Timeout < 1 || h.Retries < 1 {
+		return errors.New("health_check interval, timeout and retries must be positive")
+	}
+	if h.Timeout > h.Interval {
+		return errors.New("health_check.timeout must not exceed interval")
+	}
+	return nil
+}
+
+// healthProbe garde l'avancement des checks d'un conteneur entre deux tours du moniteur
+type healthProbe struct {
+	nextAt   time.Time
+	failures int
+	running  bool
+}
+
+// StartServerMonitor lance les health checks et la surveillance des quotas disque des serveurs démarrés
+// jusqu'à l'annulation du contexte
+func (a *App) StartServerMonitor(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(serverMonitorTick)
+		defer ticker.Stop()
+		disks := time.NewTicker(diskCheckInterval)
+		defer disks.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.runHealthChecks(ctx)
+			case <-disks.C:
+				a.checkServerDisks()
+			}
+		}
+	}()
+}
+
+type monitoredServer struct {
+	ID          string
+	ContainerID string
+	Address     *string
+	Port        int
+	Check       HealthCheck
+}
+
+// runHealthChecks lance les checks arrivés à échéance
+func (a *App) runHealthChecks(ctx context.Context) {
+	running, err := a.store.Servers.Running()
+	if err != nil {
+		a.log.Errorf("servers: health checks: %v", err)
+		return
+	}
+	var servers []monitoredServer
+	for _, srv := range running {
+		if srv.HealthCheck == nil {
+			continue
+		}
+		s := monitoredServer{ID: srv.ID, ContainerID: *srv.ContainerID, Address: srv.Address, Port: srv.Port}
+		if json.Unmarshal([]byte(*srv.HealthCheck), &s.Check) == nil {
+			servers = append(servers, s)
+		}
+	}
+
+	now := time.Now()
+	a.healthMu.Lock()
+	defer a.healthMu.Unlock()
+
+	// Oublier les conteneurs qui ne sont plus surveillés
+	active := map[string]bool{}
+	for _, s := range servers {
+		active[s.ContainerID] = true
+	}
+	for id := range a.healthProbes {
+		if !active[id] {
+			delete(a.healthProbes, id)
+		}
+	}
+
+	for _, s := range servers {
+		probe, ok := a.healthProbes[s.ContainerID]
+		if !ok {
+			// Premier check après un intervalle, le temps que le processus démarre
+			probe = &healthProbe{nextAt: now.Add(time.Duration(s.Check.Interval) * time.Second)}
+			a.healthProbes[s.ContainerID] = probe
+		}
+		if probe.running || now.Before(probe.nextAt) {
+			continue
+		}
+		probe.running = true
+		probe.nextAt = now.Add(time.Duration(s.Check.Interval) * time.Second)
+		go a.checkServer(ctx, s, probe)
+	}
+}
+
+// checkServer exécute un health check et tue le conteneur après trop d'échecs consécutifs
+func (a *App) checkServer(ctx context.Context, s monitoredServer, probe *healthProbe) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.Check.Timeout)*time.Second)
+	defer cancel()
+	err := a.probeServer(ctx, s)
+
+	a.healthMu.Lock()
+	probe.running = false
+	if err == nil {
+		probe.failures = 0
+	} else {
+		probe.failures++
+	}
+	failures := probe.failures
+	a.healthMu.Unlock()
+
+	status := HealthHealthy
+	if err != nil {
+		status = HealthUnhealthy
+		if failures < s.Check.Retries {
+			// Encore dans la marge tolérée, l'état précédent est conservé
+			return
+		}
+	}
+	a.store.Servers.SetHealth(s.ID, s.ContainerID, status)
+	if err == nil {
+		return
+	}
+
+	a.killServer(s.ID, s.ContainerID, fmt.Sprintf("health check failed %d times: %v", failures, err))
+}
+
+// killServer tue le conteneur ; le watcher applique ensuite la politique de redémarrage avec reason
+func (a *App) killServer(serverID, containerID, reason string) {
+	a.killReasons.Store(containerID, reason)
+	a.log.Warnf("servers: server %s: %s", serverID, reason)
+
+	if err := a.runtime.ContainerKill(context.Background(), containerID, "SIGKILL"); err != nil {
+		a.killReasons.Delete(containerID)
+	}
+}
+
+// checkServerDisks tue les serveurs dont les données ou un volume en écriture dépassent leur quota.
+// Au redémarrage, startServer refuse le dossier plein et le serveur passe à l'état 2.
+func (a *App) checkServerDisks() {
+	running, err := a.store.Servers.Running()
+	if err != nil {
+		a.log.Errorf("servers: disk checks: %v", err)
+		return
+	}
+	for _, s := range running {
+		limits := []diskLimit{{Name: "server data", Dir: a.serverDataDir(s.ID), Space: s.Space}}
+		for _, m := range parseMounts(s.Mounts) {
+			if v, err := a.store.Volumes.GetByName(s.UserID, m.Volume); err == nil && !m.ReadOnly {
+				limits = append(limits, diskLimit{Name: "volume " + m.Volume, Dir: a.volumeDir(v.ID), Space: v.Space})
+			}
+		}
+		if err := checkDisks(limits); err != nil {
+			a.killServer(s.ID, *s.ContainerID, err.Error())
+		}
+	}
+}
+
+// probeServer effectue un seul check selon son type
+func (a *App) probeServer(ctx context.Context, s monitoredServer) error {
+	if s.Check.Type == HealthCheckCommand {
+		return a.probeCommand(ctx, s.ContainerID, s.Check.Command)
+	}
+
+	if s.Address == nil {
+		return errors.New("server has no network address")
+	}
+	address := net.JoinHostPort(*s.Address, strconv.Itoa(s.Port))
+
+	if s.Check.Type == HealthCheckTCP {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+address+s.Check.Path, nil)
+	if err != nil {
+		return err
+	}
+	client := http.Client{
+		// Une redirection suffit à prouver que le serveur répond
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("GET %s returned %d", s.Check.Path, resp.StatusCode)
+	}
+	return nil
+}
+
+// probeCommand lance la commande dans le conteneur et attend sa fin
+func (a *App) probeCommand(ctx context.Context, containerID string, cmd []string) error {
+	cli := a.runtime
+	exec, err := cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{Cmd: cmd, WorkingDir: serverDataMount})
+	if err != nil {
+		return err
+	}
+	if err := cli.ContainerExecStart(ctx, exec.ID, container.ExecStartOptions{Detach: true}); err != nil {
+		return err
+	}
+
+	for {
+		inspect, err := cli.ContainerExecInspect(ctx, exec.ID)
+		if err != nil {
+			return err
+		}
+		if !inspect.Running {
+			if inspect.ExitCode != 0 {
+				return fmt.Errorf("command exited with code %d", inspect.ExitCode)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("command timed out")
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/images"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/languages"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/quotas"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// États d'un serveur (voir README)
+const (
+	ServerOff   = 0
+	ServerOn    = 1
+	ServerError = 2
+)
+
+// Réseau Docker privé sur lequel le reverse proxy joint les serveurs
+const serverNetwork = "webhosting"
+
+// Dossier persistant du serveur, monté en écriture dans le conteneur
+const serverDataMount = "/app/data"
+
+var errDiskQuota = errors.New("disk quota exceeded")
+
+// blockingError marque un échec de démarrage qui se reproduira tant que l'utilisateur n'a rien changé
+// (quota, langage retiré, image introuvable, build en échec, volume absent) : le serveur passe à l'état 2.
+// Les autres échecs (Docker injoignable, ...) laissent l'état inchangé.
+type blockingError struct{ err error }
+
+func (e *blockingError) Error() string { return e.err.Error() }
+func (e *blockingError) Unwrap() error { return e.err }
+
+func blocking(err error) error {
+	return &blockingError{err}
+}
+
+func isBlocking(err error) bool {
+	var b *blockingError
+	return errors.As(err, &b)
+}
+
+// Politiques de redémarrage après la fin du processus
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+const (
+	// Délai max entre deux redémarrages automatiques
+	serverRestartMaxDelay = 5 * time.Minute
+	// Durée de fonctionnement après laquelle le compteur de redémarrages repart de zéro
+	serverStableAfter = 10 * time.Minute
+)
+
+// Délai laissé au processus pour s'arrêter proprement avant SIGKILL
+const serverStopTimeout = 10
+
+func serverContainerName(serverID string) string {
+	return "server-" + serverID
+}
+
+func (a *App) serverDir(serverID string) string {
+	return filepath.Join(a.config.DataDir, "servers", serverID)
+}
+
+func (a *App) serverDataDir(serverID string) string {
+	return filepath.Join(a.serverDir(serverID), "data")
+}
+
+// dirSize additionne la taille des fichiers d'un dossier
+func dirSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// setServerState met à jour l'état et la dernière erreur d'un serveur arrêté (0 ou 2)
+func (a *App) setServerState(serverID string, state int, lastError string) {
+	var reason *string
+	if lastError != "" {
+		reason = &lastError
+	}
+	a.store.Servers.SetState(serverID, state, reason)
+}
+
+// startServer (re)crée le conteneur du serveur et le démarre
+func (a *App) startServer(serverID string) error {
+	srv, err := a.store.Servers.Get(serverID)
+	if err != nil {
+		return err
+	}
+	script, err := a.loadScript(srv.ScriptID, srv.UserID)
+	if err != nil {
+		return err
+	}
+	space, port, mounts := srv.Space, srv.Port, srv.Mounts
+
+	// Un serveur arrêté occupe une place d'exécution une fois démarré ; la place est
+	// vérifiée de nouveau, avec le verrou de l'utilisateur, par SetStarted
+	if srv.State != ServerOn {
+		if err := a.quotas.CheckRun(srv.UserID); err != nil {
+			return err
+		}
+	}
+	plan, err := a.quotas.UserPlan(srv.UserID)
+	if err != nil {
+		return err
+	}
+
+	dataDir := a.serverDataDir(serverID)
+	if used := dirSize(dataDir); used > space {
+		return blocking(fmt.Errorf("%w (%d/%d bytes)", errDiskQuota, used, space))
+	}
+
+	lang, ok := a.languages.Get(script.Language)
+	if !ok {
+		return blocking(fmt.Errorf("language %s is not supported anymore", script.Language))
+	}
+
+	cli := a.runtime
+
+	ctx := context.Background()
+	name := serverContainerName(serverID)
+
+	pullCtx, cancel := context.WithTimeout(ctx, imagePullTimeout)
+	err = images.Ensure(pullCtx, cli, script.DockerImage)
+	cancel()
+	if client.IsErrNotFound(err) {
+		return blocking(fmt.Errorf("image %s can't be pulled: %w", script.DockerImage, err))
+	} else if err != nil {
+		return fmt.Errorf("image %s can't be pulled: %w", script.DockerImage, err)
+	}
+
+	// Supprimer l'ancien conteneur (arrêté) avant d'en créer un neuf
+	cli.ContainerRemove(ctx, name, container.RemoveOptions{Force: true})
+
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return err
+	}
+	absData, _ := filepath.Abs(dataDir)
+	absScript, _ := filepath.Abs(script.FilePath)
+	binds := []string{
+		absScript + ":" + lang.ScriptPath() + ":ro",
+		absData + ":" + serverDataMount,
+	}
+
+	// Phase de build pour les langages compilés
+	if lang.Compiled() {
+		absBuild, _ := filepath.Abs(filepath.Join(a.serverDir(serverID), "build"))
+		if err := os.MkdirAll(absBuild, 0755); err != nil {
+			return err
+		}
+		exitCode, logs, err := runPhase(ctx, cli, containerPhase{
+			Name:     name + "-build",
+			Image:    script.DockerImage,
+			Cmd:      lang.CompileCommand(),
+			Binds:    []string{binds[0], absBuild + ":" + languages.BuildDir},
+			Deadline: time.Now().Add(a.config.ExecutionTimeout),
+		})
+		if err != nil {
+			return fmt.Errorf("build failed: %w", err)
+		}
+		if exitCode != 0 {
+			return blocking(fmt.Errorf("build failed with exit code %d: %s", exitCode, logs))
+		}
+		binds = append(binds, absBuild+":"+languages.BuildDir+":ro")
+	}
+
+	volumeBinds, _, err := a.mountBinds(script.UserID, parseMounts(mounts))
+	if err != nil {
+		return err
+	}
+	binds = append(binds, volumeBinds...)
+
+	if err := ensureServerNetwork(ctx, cli); err != nil {
+		return err
+	}
+
+	resp, err := cli.ContainerCreate(ctx,
+		&container.Config{
+			Image:      script.DockerImage,
+			Cmd:        lang.RunCommand(),
+			Env:        []string{fmt.Sprintf("PORT=%d", port)},
+			WorkingDir: serverDataMount,
+			OpenStdin:  true, // stdin reste ouvert pour POST /servers/{id}/stdin
+		},
+		&container.HostConfig{
+			Binds:       binds,
+			NetworkMode: container.NetworkMode(serverNetwork),
+		},
+		nil, nil, name,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+		return err
+	}
+
+	// Adresse sur le réseau privé, utilisée par le reverse proxy
+	var address string
+	if inspect, err := cli.ContainerInspect(ctx, resp.ID); err == nil && inspect.NetworkSettings != nil {
+		if endpoint, ok := inspect.NetworkSettings.Networks[serverNetwork]; ok {
+			address = endpoint.IPAddress
+		}
+	}
+
+	var addressColumn *string
+	if address != "" {
+		addressColumn = &address
+	}
+	err = a.store.Servers.SetStarted(serverID, ServerOn, resp.ID, addressColumn, time.Now().UTC().Format(time.RFC3339), HealthStarting, plan.MaxConcurrent)
+	if errors.Is(err, store.ErrLimitReached) {
+		cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+		return fmt.Errorf("%w: %d/%d", quotas.ErrConcurrencyLimit, plan.MaxConcurrent, plan.MaxConcurrent)
+	}
+	if err != nil {
+		return err
+	}
+
+	go a.watchServer(serverID, resp.ID)
+	return nil
+}
+
+// resetServerRestarts remet à zéro le compteur de redémarrages avant un démarrage manuel.
+// La dernière erreur n'est pas effacée par startServer pour rester visible après un redémarrage automatique.
+func (a *App) resetServerRestarts(serverID string) {
+	a.store.Servers.SetRestarts(serverID, 0, nil)
+}
+
+// ensureServerNetwork crée le réseau des serveurs s'il n'existe pas encore
+func ensureServerNetwork(ctx context.Context, cli Runtime) error {
+	_, err := cli.NetworkInspect(ctx, serverNetwork, network.InspectOptions{})
+	if err == nil {
+		return nil
+	}
+	if !client.IsErrNotFound(err) {
+		return err
+	}
+	_, err = cli.NetworkCreate(ctx, serverNetwork, network.CreateOptions{Driver: "bridge"})
+	return err
+}
+
+// stopServer passe le serveur à l'état 0 puis arrête son conteneur.
+// Le conteneur est gardé pour pouvoir relire ses logs. Si l'arrêt échoue, l'état précédent est rétabli.
+func (a *App) stopServer(serverID string) error {
+	s, err := a.store.Servers.Get(serverID)
+	if err != nil {
+		return err
+	}
+
+	// L'état est changé avant l'arrêt pour que watchServer ne le prenne pas pour un crash
+	a.setServerState(serverID, ServerOff, "")
+	if s.ContainerID == nil {
+		return nil
+	}
+
+	err = a.stopContainer(*s.ContainerID)
+	if err != nil {
+		a.store.Servers.SetState(serverID, s.State, s.LastError)
+	}
+	return err
+}
+
+func (a *App) stopContainer(containerID string) error {
+	timeout := serverStopTimeout
+	err := a.runtime.ContainerStop(context.Background(), containerID, container.StopOptions{Timeout: &timeout})
+	if err != nil && !client.IsErrNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// removeServer supprime le conteneur et les données du serveur
+func (a *App) removeServer(serverID string) {
+	a.runtime.ContainerRemove(context.Background(), serverContainerName(serverID), container.RemoveOptions{Force: true})
+	os.RemoveAll(a.serverDir(serverID))
+}
+
+// watchServer attend la fin du conteneur puis applique la politique de redémarrage
+func (a *App) watchServer(serverID, containerID string) {
+	cli := a.runtime
+
+	ctx := context.Background()
+	statusCh, errCh := cli.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
+
+	failed, reason := false, "process exited with code 0"
+	select {
+	case status := <-statusCh:
+		if status.StatusCode != 0 {
+			failed, reason = true, fmt.Sprintf("process exited with code %d", status.StatusCode)
+			if inspect, err := cli.ContainerInspect(ctx, containerID); err == nil && inspect.State != nil && inspect.State.OOMKilled {
+				reason = "process killed: out of memory"
+			}
+		}
+	case err := <-errCh:
+		failed, reason = true, err.Error()
+	}
+
+	// Conteneur tué par le moniteur de santé
+	if killed, ok := a.killReasons.LoadAndDelete(containerID); ok {
+		failed, reason = true, killed.(string)
+	}
+
+	a.handleServerExit(serverID, containerID, failed, reason)
+}
+
+// handleServerExit décide entre redémarrage, arrêt (0) et erreur bloquante (2)
+func (a *App) handleServerExit(serverID, containerID string, failed bool, reason string) {
+	s, err := a.store.Servers.Get(serverID)
+
+	// Arrêt demandé, serveur supprimé ou conteneur déjà remplacé
+	if err != nil || s.State != ServerOn || s.ContainerID == nil || *s.ContainerID != containerID {
+		return
+	}
+	policy, maxRetries, restartCount, startedAt := s.RestartPolicy, s.MaxRetries, s.RestartCount, s.StartedAt
+
+	restart := policy == RestartAlways || (policy == RestartOnFailure && failed)
+	if !restart {
+		if failed {
+			a.setServerState(serverID, ServerError, reason)
+		} else {
+			a.setServerState(serverID, ServerOff, "")
+		}
+		return
+	}
+
+	// Un serveur resté stable assez longtemps repart avec un compteur à zéro
+	if startedAt != nil {
+		if t, err := time.Parse(time.RFC3339, *startedAt); err == nil && time.Since(t) > serverStableAfter {
+			restartCount = 0
+		}
+	}
+	// max_retries ne borne que on-failure : always redémarre sans fin, seul le délai augmente
+	if policy == RestartOnFailure && restartCount >= maxRetries {
+		if restartCount > 0 {
+			reason = fmt.Sprintf("crash loop: restarted %d times, last failure: %s", restartCount, reason)
+		}
+		a.setServerState(serverID, ServerError, reason)
+		return
+	}
+
+	// Une sortie propre n'est pas une erreur : last_error ne garde que les échecs
+	var lastError *string
+	if failed {
+		lastError = &reason
+	}
+	restartCount++
+	a.store.Servers.SetRestarts(serverID, restartCount, lastError)
+
+	time.AfterFunc(serverRestartBackoff(restartCount), func() {
+		// Le serveur a pu être arrêté ou redémarré à la main pendant l'attente
+		s, err := a.store.Servers.Get(serverID)
+		if err != nil || s.State != ServerOn || s.ContainerID == nil || *s.ContainerID != containerID {
+			return
+		}
+		if err := a.startServer(serverID); isBlocking(err) {
+			a.setServerState(serverID, ServerError, "restart failed: "+err.Error())
+		} else if err != nil {
+			// Panne passagère (Docker injoignable, ...) : nouvel essai, compté comme un échec
+			a.handleServerExit(serverID, containerID, true, "restart failed: "+err.Error())
+		}
+	})
+}
+
+// serverRestartBackoff retourne le délai avant le n-ième redémarrage (1s, 2s, 4s, ... 5min max)
+func serverRestartBackoff(restartCount int) time.Duration {
+	delay := time.Second
+	for i := 1; i < restartCount && delay < serverRestartMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, serverRestartMaxDelay)
+}
+
+// ReconcileServers resynchronise l'état des serveurs avec Docker au démarrage
+func (a *App) ReconcileServers() {
+	servers, err := a.store.Servers.Running()
+	if err != nil {
+		a.log.Errorf("servers: %v", err)
+		return
+	}
+
+	if len(servers) == 0 {
+		return
+	}
+
+	cli := a.runtime
+
+	for _, s := range servers {
+		containerID := *s.ContainerID
+		inspect, err := cli.ContainerInspect(context.Background(), containerID)
+		switch {
+		case client.IsErrNotFound(err):
+			a.handleServerExit(s.ID, containerID, true, "container disappeared while the API was down")
+		case err != nil:
+			a.log.Errorf("servers: server %s: %v", s.ID, err)
+		case inspect.State.Running:
+			go a.watchServer(s.ID, containerID)
+		default:
+			code := inspect.State.ExitCode
+			a.handleServerExit(s.ID, containerID, code != 0, fmt.Sprintf("process exited with code %d", code))
+		}
+	}
+}
@@ -0,0 +1,579 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+const (
+	// Quota disque par défaut d'un serveur
+	defaultServerSpace = 512 << 20
+	// Nombre de lignes de logs renvoyées par GET /server
+	serverInfoTail = "100"
+	// Taille max d'une écriture sur stdin
+	maxServerStdin = 1 << 20
+	// Port HTTP attendu dans le conteneur (variable PORT)
+	defaultServerPort = 8080
+	// Redémarrages automatiques consécutifs avant l'état 2
+	defaultServerMaxRetries = 3
+)
+
+// Qui peut joindre le serveur à travers le reverse proxy
+const (
+	AccessPublic        = "public"
+	AccessAuthenticated = "authenticated"
+	AccessOwner         = "owner"
+)
+
+func validAccess(access string) bool {
+	return access == AccessPublic || access == AccessAuthenticated || access == AccessOwner
+}
+
+// Le nom sert aussi de sous-domaine, il doit donc être un label DNS valide
+var serverNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+type Server struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	ScriptID  string  `json:"script_id"`
+	State     int     `json:"state"`
+	LastError *string `json:"last_error"`
+	Space     int64   `json:"space"`
+	UsedSpace int64   `json:"usedspace"`
+	Port      int     `json:"port"`
+	Access    string  `json:"access"`
+	StartedAt *string `json:"started_at"`
+	CreatedAt string  `json:"created_at"`
+
+	RestartPolicy string       `json:"restart_policy"`
+	MaxRetries    int          `json:"max_retries"`
+	RestartCount  int          `json:"restart_count"`
+	HealthCheck   *HealthCheck `json:"health_check"`
+	HealthStatus  *string      `json:"health_status"`
+
+	Mounts []VolumeMount `json:"mounts"`
+
+	containerID *string
+}
+
+// serverView ajoute au serveur enregistré l'espace occupé par ses données
+func (a *App) serverView(stored store.Server) Server {
+	s := Server{
+		ID:            stored.ID,
+		Name:          stored.Name,
+		ScriptID:      stored.ScriptID,
+		State:         stored.State,
+		LastError:     stored.LastError,
+		Space:         stored.Space,
+		UsedSpace:     dirSize(a.serverDataDir(stored.ID)),
+		Port:          stored.Port,
+		Access:        stored.Access,
+		StartedAt:     stored.StartedAt,
+		CreatedAt:     stored.CreatedAt,
+		RestartPolicy: stored.RestartPolicy,
+		MaxRetries:    stored.MaxRetries,
+		RestartCount:  stored.RestartCount,
+		HealthStatus:  stored.HealthStatus,
+		Mounts:        parseMounts(stored.Mounts),
+		containerID:   stored.ContainerID,
+	}
+	if stored.HealthCheck != nil {
+		s.HealthCheck = &HealthCheck{}
+		json.Unmarshal([]byte(*stored.HealthCheck), s.HealthCheck)
+	}
+	return s
+}
+
+func validRestartPolicy(policy string) bool {
+	return policy == RestartNever || policy == RestartOnFailure || policy == RestartAlways
+}
+
+// validateRestart vérifie la politique de redémarrage et le health check d'un serveur
+func validateRestart(s *Server) error {
+	if !validRestartPolicy(s.RestartPolicy) {
+		return api.Invalid("restart_policy", "%s is not supported (supported: never, on-failure, always)", s.RestartPolicy)
+	}
+	if s.MaxRetries < 0 {
+		return api.Invalid("max_retries", "must not be negative")
+	}
+	if s.HealthCheck != nil {
+		return s.HealthCheck.normalize()
+	}
+	return nil
+}
+
+// healthCheckColumn sérialise le health check pour la colonne health_check (nil = aucun)
+func healthCheckColumn(check *HealthCheck) *string {
+	if check == nil {
+		return nil
+	}
+	data, _ := json.Marshal(check)
+	column := string(data)
+	return &column
+}
+
+func (a *App) loadServer(serverID string, userID int) (Server, error) {
+	s, err := a.store.Servers.GetForUser(serverID, userID)
+	if err != nil {
+		return Server{}, err
+	}
+	return a.serverView(s), nil
+}
+
+// serverLogs retourne les dernières lignes de stdout et stderr du conteneur
+func (a *App) serverLogs(ctx context.Context, containerID, tail string) (string, string, error) {
+	out, err := a.runtime.ContainerLogs(ctx, containerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       tail,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	defer out.Close()
+
+	var stdout, stderr bytes.Buffer
+	_, err = stdcopy.StdCopy(&stdout, &stderr, out)
+	return stdout.String(), stderr.String(), err
+}
+
+// ServerListHandler — GET /server_list
+func (a *App) ServerListHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	stored, err := a.store.Servers.List(userID)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	servers := []Server{}
+	for _, s := range stored {
+		servers = append(servers, a.serverView(s))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(servers)
+}
+
+// ServerInfoHandler — GET /server?name=
+// Retourne l'état du serveur avec la fin de ses sorties stdout/stderr
+func (a *App) ServerInfoHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	name := r.URL.Query().Get("name")
+
+	stored, err := a.store.Servers.GetByName(name)
+	if err != nil || stored.UserID != userID {
+		api.NotFoundErrorHandler(w, "Server not found")
+		return
+	}
+	s := a.serverView(stored)
+
+	var stdout, stderr string
+	if s.containerID != nil {
+		stdout, stderr, _ = a.serverLogs(r.Context(), *s.containerID, serverInfoTail)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Server
+		Stdin  string `json:"stdin"`
+		Stdout string `json:"stdout"`
+		Stderr string `json:"stderr"`
+	}{
+		Server: s,
+		Stdin:  api.Prefix + "/servers/" + s.ID + "/stdin",
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+}
+
+// CreateServerHandler — POST /servers
+// JSON : name, script_id, space (octets, 512MB par défaut), port (8080), access (public),
+// restart_policy (never), max_retries (3), health_check, mounts (volumes)
+func (a *App) CreateServerHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	var req struct {
+		Name          string        `json:"name"`
+		ScriptID      string        `json:"script_id"`
+		Space         int64         `json:"space"`
+		Port          int           `json:"port"`
+		Access        string        `json:"access"`
+		RestartPolicy string        `json:"restart_policy"`
+		MaxRetries    *int          `json:"max_retries"`
+		HealthCheck   *HealthCheck  `json:"health_check"`
+		Mounts        []VolumeMount `json:"mounts"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.RequestErrorHandler(w, errors.New("invalid request"))
+		return
+	}
+
+	if !serverNamePattern.MatchString(req.Name) {
+		api.RequestErrorHandler(w, api.Invalid("name", "must be lowercase letters, digits and dashes (max 63)"))
+		return
+	}
+	if a.reservedServerName(req.Name) {
+		api.RequestErrorHandler(w, api.Invalid("name", "%s is reserved", req.Name))
+		return
+	}
+	if _, err := a.loadScript(req.ScriptID, userID); err != nil {
+		api.RequestErrorHandler(w, api.Invalid("script_id", "not found"))
+		return
+	}
+	if req.Space <= 0 {
+		req.Space = defaultServerSpace
+	}
+	if req.Port == 0 {
+		req.Port = defaultServerPort
+	}
+	if req.Access == "" {
+		req.Access = AccessPublic
+	}
+	if req.Port < 1 || req.Port > 65535 {
+		api.RequestErrorHandler(w, api.Invalid("port", "must be between 1 and 65535"))
+		return
+	}
+	if !validAccess(req.Access) {
+		api.RequestErrorHandler(w, api.Invalid("access", "%s is not supported (supported: public, authenticated, owner)", req.Access))
+		return
+	}
+
+	restart := Server{RestartPolicy: req.RestartPolicy, MaxRetries: defaultServerMaxRetries, HealthCheck: req.HealthCheck}
+	if restart.RestartPolicy == "" {
+		restart.RestartPolicy = RestartNever
+	}
+	if req.MaxRetries != nil {
+		restart.MaxRetries = *req.MaxRetries
+	}
+	if err := validateRestart(&restart); err != nil {
+		api.RequestErrorHandler(w, err)
+		return
+	}
+	if err := a.validateMounts(userID, req.Mounts); err != nil {
+		a.mountsError(w, err)
+		return
+	}
+
+	serverID := uuid.New().String()
+	err := a.store.Servers.Create(store.Server{
+		ID:            serverID,
+		UserID:        userID,
+		Name:          req.Name,
+		ScriptID:      req.ScriptID,
+		State:         ServerOff,
+		Space:         req.Space,
+		Port:          req.Port,
+		Access:        req.Access,
+		RestartPolicy: restart.RestartPolicy,
+		MaxRetries:    restart.MaxRetries,
+		HealthCheck:   healthCheckColumn(restart.HealthCheck),
+		Mounts:        mountsColumn(req.Mounts),
+	})
+	if errors.Is(err, store.ErrConflict) {
+		api.ConflictErrorHandler(w, fmt.Errorf("server name %s is already taken", req.Name))
+		return
+	} else if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	s, err := a.loadServer(serverID, userID)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s)
+}
+
+// UpdateServerHandler — PATCH /servers/{id}
+// JSON : port, access, space, restart_policy, max_retries, health_check (null pour le retirer), mounts
+// Le port et les volumes sont pris en compte au prochain démarrage
+func (a *App) UpdateServerHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	serverID := chi.URLParam(r, "id")
+
+	s, err := a.loadServer(serverID, userID)
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Server not found")
+		return
+	}
+
+	var req struct {
+		Port          *int            `json:"port"`
+		Access        *string         `json:"access"`
+		Space         *int64          `json:"space"`
+		RestartPolicy *string         `json:"restart_policy"`
+		MaxRetries    *int            `json:"max_retries"`
+		HealthCheck   json.RawMessage `json:"health_check"`
+		Mounts        *[]VolumeMount  `json:"mounts"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.RequestErrorHandler(w, errors.New("invalid request"))
+		return
+	}
+	if req.Port != nil {
+		s.Port = *req.Port
+	}
+	if req.Access != nil {
+		s.Access = *req.Access
+	}
+	if req.Space != nil {
+		s.Space = *req.Space
+	}
+	if req.RestartPolicy != nil {
+		s.RestartPolicy = *req.RestartPolicy
+	}
+	if req.MaxRetries != nil {
+		s.MaxRetries = *req.MaxRetries
+	}
+	if req.Mounts != nil {
+		s.Mounts = *req.Mounts
+	}
+	// Champ absent : inchangé, null : supprimé
+	if req.HealthCheck != nil {
+		s.HealthCheck = nil
+		if string(req.HealthCheck) != "null" {
+			s.HealthCheck = &HealthCheck{}
+			if err := json.Unmarshal(req.HealthCheck, s.HealthCheck); err != nil {
+				api.RequestErrorHandler(w, api.Invalid("health_check", "must be an object"))
+				return
+			}
+		}
+	}
+	if s.Port < 1 || s.Port > 65535 {
+		api.RequestErrorHandler(w, api.Invalid("port", "must be between 1 and 65535"))
+		return
+	}
+	if !validAccess(s.Access) {
+		api.RequestErrorHandler(w, api.Invalid("access", "%s is not supported (supported: public, authenticated, owner)", s.Access))
+		return
+	}
+	if s.Space <= 0 {
+		api.RequestErrorHandler(w, api.Invalid("space", "must be positive"))
+		return
+	}
+	if err := validateRestart(&s); err != nil {
+		api.RequestErrorHandler(w, err)
+		return
+	}
+	if err := a.validateMounts(userID, s.Mounts); err != nil {
+		a.mountsError(w, err)
+		return
+	}
+	column := mountsColumn(s.Mounts)
+	s.Mounts = parseMounts(column)
+
+	err = a.store.Servers.Update(store.Server{
+		ID:            s.ID,
+		Port:          s.Port,
+		Access:        s.Access,
+		Space:         s.Space,
+		RestartPolicy: s.RestartPolicy,
+		MaxRetries:    s.MaxRetries,
+		HealthCheck:   healthCheckColumn(s.HealthCheck),
+		Mounts:        column,
+	})
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// DeleteServerHandler — DELETE /servers/{id}
+func (a *App) DeleteServerHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	serverID := chi.URLParam(r, "id")
+
+	if _, err := a.loadServer(serverID, userID); err != nil {
+		api.NotFoundErrorHandler(w, "Server not found")
+		return
+	}
+
+	// Supprimer en base d'abord pour que watchServer ignore l'arrêt
+	a.store.Servers.Delete(serverID)
+	a.removeServer(serverID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serverAction applique start/stop/restart et renvoie le serveur à jour
+func (a *App) serverAction(w http.ResponseWriter, r *http.Request, action func(serverID string) error) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	serverID := chi.URLParam(r, "id")
+
+	if _, err := a.loadServer(serverID, userID); err != nil {
+		api.NotFoundErrorHandler(w, "Server not found")
+		return
+	}
+
+	if err := action(serverID); err != nil {
+		// Une panne passagère (Docker injoignable, ...) ne change pas l'état du serveur
+		if isBlocking(err) {
+			a.setServerState(serverID, ServerError, err.Error())
+		}
+		if errors.Is(err, errDiskQuota) {
+			api.WriteError(w, http.StatusForbidden, api.CodeQuotaExceeded, err.Error())
+			return
+		}
+		if quotaError(w, err) {
+			return
+		}
+		a.log.WithField("request_id", middleware.GetRequestID(r.Context())).Errorf("servers: server %s: %v", serverID, err)
+		api.WriteError(w, http.StatusBadGateway, api.CodeUpstreamError, "Server action failed")
+		return
+	}
+
+	s, err := a.loadServer(serverID, userID)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// StartServerHandler — POST /servers/{id}/start
+func (a *App) StartServerHandler(w http.ResponseWriter, r *http.Request) {
+	a.serverAction(w, r, func(serverID string) error {
+		a.resetServerRestarts(serverID)
+		return a.startServer(serverID)
+	})
+}
+
+// StopServerHandler — POST /servers/{id}/stop
+func (a *App) StopServerHandler(w http.ResponseWriter, r *http.Request) {
+	a.serverAction(w, r, a.stopServer)
+}
+
+// RestartServerHandler — POST /servers/{id}/restart
+func (a *App) RestartServerHandler(w http.ResponseWriter, r *http.Request) {
+	a.serverAction(w, r, func(serverID string) error {
+		if err := a.stopServer(serverID); err != nil {
+			return err
+		}
+		a.resetServerRestarts(serverID)
+		return a.startServer(serverID)
+	})
+}
+
+// ServerStdinHandler — POST /servers/{id}/stdin
+// Le body brut est écrit sur l'entrée standard du processus
+func (a *App) ServerStdinHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	serverID := chi.URLParam(r, "id")
+
+	s, err := a.loadServer(serverID, userID)
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Server not found")
+		return
+	}
+	if s.State != ServerOn || s.containerID == nil {
+		api.ConflictErrorHandler(w, errors.New("server is not running"))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxServerStdin))
+	if err != nil {
+		api.WriteError(w, http.StatusRequestEntityTooLarge, api.CodePayloadTooLarge, fmt.Sprintf("body exceeds %d bytes", maxServerStdin))
+		return
+	}
+
+	hijacked, err := a.runtime.ContainerAttach(r.Context(), *s.containerID, container.AttachOptions{Stream: true, Stdin: true})
+	if err != nil {
+		api.WriteError(w, http.StatusBadGateway, api.CodeUpstreamError, "Failed to attach to server")
+		return
+	}
+	defer hijacked.Close()
+
+	if _, err := hijacked.Conn.Write(body); err != nil {
+		api.WriteError(w, http.StatusBadGateway, api.CodeUpstreamError, "Failed to write to server stdin")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// flushWriter envoie chaque écriture immédiatement au client (logs en continu)
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
+
+// ServerLogsHandler — GET /servers/{id}/logs?stream=stdout|stderr&tail=100&follow=true
+func (a *App) ServerLogsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	serverID := chi.URLParam(r, "id")
+
+	s, err := a.loadServer(serverID, userID)
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Server not found")
+		return
+	}
+	if s.containerID == nil {
+		api.ConflictErrorHandler(w, errors.New("server has never been started"))
+		return
+	}
+
+	query := r.URL.Query()
+	stream := query.Get("stream")
+	if stream != "" && stream != "stdout" && stream != "stderr" {
+		api.RequestErrorHandler(w, api.Invalid("stream", "must be stdout or stderr"))
+		return
+	}
+	tail := query.Get("tail")
+	if tail == "" {
+		tail = "all"
+	} else if _, err := strconv.Atoi(tail); err != nil {
+		api.RequestErrorHandler(w, api.Invalid("tail", "must be a number"))
+		return
+	}
+	follow, _ := strconv.ParseBool(query.Get("follow"))
+
+	out, err := a.runtime.ContainerLogs(r.Context(), *s.containerID, container.LogsOptions{
+		ShowStdout: stream != "stderr",
+		ShowStderr: stream != "stdout",
+		Follow:     follow,
+		Tail:       tail,
+	})
+	if err != nil {
+		api.WriteError(w, http.StatusBadGateway, api.CodeUpstreamError, "Failed to read server logs")
+		return
+	}
+	defer out.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	fw := flushWriter{w: w}
+	stdcopy.StdCopy(fw, fw, out)
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+)
+
+// createServer crée un serveur par l'API et retourne son identifiant
+func createServer(t *testing.T, c *http.Client, srv *httptest.Server, name, scriptID string) string {
+	t.Helper()
+	resp, err := c.Post(srv.URL+api.Prefix+"/servers", "application/json", strings.NewReader(`{"name":"`+name+`","script_id":"`+scriptID+`"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var s Server
+	json.NewDecoder(resp.Body).Decode(&s)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create server: %d", resp.StatusCode)
+	}
+	return s.ID
+}
+
+func TestServerActionFailures(t *testing.T) {
+	// Démon Docker injoignable : toute opération sur un conteneur échoue
+	app, st := newTestApp(t)
+	fakeDocker(app).down = errors.New("cannot connect to the Docker daemon")
+	srv := httptest.NewServer(app.Handler())
+	defer srv.Close()
+
+	createUser(t, st, "alice", "secret")
+	createScript(t, st, "alice", "retired", "cobol")
+	createScript(t, st, "alice", "web", "python")
+	c, _ := login(t, srv, "alice", "secret")
+
+	state := func(id string) (int, *string) {
+		s, err := st.Servers.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.State, s.LastError
+	}
+	post := func(path string) int {
+		resp, err := c.Post(srv.URL+api.Prefix+path, "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Langage retiré : l'échec se reproduira, le serveur passe à l'état 2
+	retired := createServer(t, c, srv, "retired", "retired")
+	if code := post("/servers/" + retired + "/start"); code != http.StatusBadGateway {
+		t.Errorf("start retired: %d", code)
+	}
+	if s, reason := state(retired); s != ServerError || reason == nil || !strings.Contains(*reason, "not supported") {
+		t.Errorf("retired server: state %d, reason %v", s, reason)
+	}
+
+	// Docker injoignable : panne passagère, l'état ne change pas
+	web := createServer(t, c, srv, "web", "web")
+	if code := post("/servers/" + web + "/start"); code != http.StatusBadGateway {
+		t.Errorf("start web: %d", code)
+	}
+	if s, reason := state(web); s != ServerOff || reason != nil {
+		t.Errorf("start without docker: state %d, reason %v", s, reason)
+	}
+
+	// Arrêt impossible : le serveur reste noté en marche
+	st.Servers.SetStarted(web, ServerOn, "container-1", nil, time.Now().UTC().Format(time.RFC3339), HealthStarting, 0)
+	if code := post("/servers/" + web + "/stop"); code != http.StatusBadGateway {
+		t.Errorf("stop web: %d", code)
+	}
+	if s, _ := state(web); s != ServerOn {
+		t.Errorf("failed stop: state %d, want %d", s, ServerOn)
+	}
+}
+
+func TestServerExitPolicies(t *testing.T) {
+	app, st := newTestApp(t)
+	fakeDocker(app).down = errors.New("cannot connect to the Docker daemon")
+	srv := httptest.NewServer(app.Handler())
+	defer srv.Close()
+
+	createUser(t, st, "alice", "secret")
+	createScript(t, st, "alice", "web", "python")
+	c, _ := login(t, srv, "alice", "secret")
+
+	// run crée un serveur avec la politique donnée, le note en marche puis applique les sorties
+	run := func(name, policy string, maxRetries int, exits ...bool) (int, int, *string) {
+		t.Helper()
+		id := createServer(t, c, srv, name, "web")
+		s, _ := st.Servers.Get(id)
+		s.RestartPolicy, s.MaxRetries = policy, maxRetries
+		if err := st.Servers.Update(s); err != nil {
+			t.Fatal(err)
+		}
+		st.Servers.SetStarted(id, ServerOn, "container-"+name, nil, time.Now().UTC().Format(time.RFC3339), HealthStarting, 0)
+		for _, failed := range exits {
+			reason := "process exited with code 0"
+			if failed {
+				reason = "process exited with code 1"
+			}
+			app.handleServerExit(id, "container-"+name, failed, reason)
+		}
+		s, _ = st.Servers.Get(id)
+		// Arrêté pour que les redémarrages programmés n'aient pas lieu
+		st.Servers.SetState(id, ServerOff, s.LastError)
+		return s.State, s.RestartCount, s.LastError
+	}
+
+	if state, _, reason := run("never-clean", RestartNever, 3, false); state != ServerOff || reason != nil {
+		t.Errorf("never, clean exit: state %d, reason %v", state, reason)
+	}
+	if state, _, reason := run("never-failed", RestartNever, 3, true); state != ServerError || reason == nil || *reason != "process exited with code 1" {
+		t.Errorf("never, failure: state %d, reason %v", state, reason)
+	}
+	if state, _, reason := run("onfailure-clean", RestartOnFailure, 3, false); state != ServerOff || reason != nil {
+		t.Errorf("on-failure, clean exit: state %d, reason %v", state, reason)
+	}
+	if state, count, reason := run("onfailure-retry", RestartOnFailure, 2, true, true); state != ServerOn || count != 2 || reason == nil {
+		t.Errorf("on-failure, 2 failures: state %d, count %d, reason %v", state, count, reason)
+	}
+	state, _, reason := run("onfailure-loop", RestartOnFailure, 2, true, true, true)
+	if state != ServerError || reason == nil || !strings.HasPrefix(*reason, "crash loop: restarted 2 times") {
+		t.Errorf("on-failure, crash loop: state %d, reason %v", state, reason)
+	}
+
+	// always ignore max_retries : clean ou non, le serveur n'atteint jamais l'état 2
+	if state, count, reason := run("always-clean", RestartAlways, 0, false, false, false, false, false); state != ServerOn || count != 5 || reason != nil {
+		t.Errorf("always, clean exits: state %d, count %d, reason %v", state, count, reason)
+	}
+	if state, count, reason := run("always-failed", RestartAlways, 1, true, true, true, true); state != ServerOn || count != 4 || reason == nil {
+		t.Errorf("always, failures: state %d, count %d, reason %v", state, count, reason)
+	}
+}
+
+func TestServerRestartBackoff(t *testing.T) {
+	for count, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 9: 256 * time.Second, 10: serverRestartMaxDelay, 10000: serverRestartMaxDelay} {
+		if got := serverRestartBackoff(count); got != want {
+			t.Errorf("serverRestartBackoff(%d) = %v, want %v", count, got, want)
+		}
+	}
+}
+
+func TestServerLifecycle(t *testing.T) {
+	app, st := newTestApp(t)
+	app.config.DataDir = t.TempDir()
+	docker := fakeDocker(app)
+	docker.exit = func(*fakeContainer) fakeExit {
+		return fakeExit{Stdout: "listening\n", Block: true}
+	}
+	srv := httptest.NewServer(app.Handler())
+	defer srv.Close()
+
+	createUser(t, st, "alice", "secret")
+	createScript(t, st, "alice", "web", "python")
+	c, _ := login(t, srv, "alice", "secret")
+	post := func(path, body string) int {
+		t.Helper()
+		resp, err := c.Post(srv.URL+api.Prefix+path, "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	// waitState attend que le watcher ait appliqué la sortie du conteneur
+	waitState := func(id string, want int) *string {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if s, _ := st.Servers.Get(id); s.State == want {
+				return s.LastError
+			}
+		}
+		s, _ := st.Servers.Get(id)
+		t.Fatalf("server %s: state %d, want %d", id, s.State, want)
+		return nil
+	}
+
+	id := createServer(t, c, srv, "web", "web")
+	if code := post("/servers/"+id+"/start", ""); code != http.StatusOK {
+		t.Fatalf("start: %d", code)
+	}
+	s, _ := st.Servers.Get(id)
+	ctr := docker.byName(serverContainerName(id))
+	if s.State != ServerOn || ctr == nil || s.ContainerID == nil || *s.ContainerID != ctr.ID {
+		t.Fatalf("started server: state %d, container %v", s.State, s.ContainerID)
+	}
+	if string(ctr.Host.NetworkMode) != serverNetwork || s.Address == nil || *s.Address == "" {
+		t.Errorf("network %s, address %v", ctr.Host.NetworkMode, s.Address)
+	}
+	if !slices.Contains(ctr.Config.Env, fmt.Sprintf("PORT=%d", s.Port)) {
+		t.Errorf("env %v", ctr.Config.Env)
+	}
+
+	// stdin est transmis au processus, ses logs sont relus depuis Docker
+	if code := post("/servers/"+id+"/stdin", "reload\n"); code != http.StatusNoContent {
+		t.Fatalf("stdin: %d", code)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		docker.mu.Lock()
+		got := ctr.stdin.String()
+		docker.mu.Unlock()
+		if got == "reload\n" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stdin %q", got)
+		}
+	}
+	resp, err := c.Get(srv.URL + api.Prefix + "/servers/" + id + "/logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(logs), "listening") {
+		t.Errorf("logs %q", logs)
+	}
+
+	// Arrêt demandé : le conteneur est arrêté mais gardé pour ses logs
+	if code := post("/servers/"+id+"/stop", ""); code != http.StatusOK {
+		t.Fatalf("stop: %d", code)
+	}
+	if reason := waitState(id, ServerOff); reason != nil || !ctr.stopped || docker.byName(serverContainerName(id)) == nil {
+		t.Errorf("stopped server: reason %v, container stopped %v", reason, ctr.stopped)
+	}
+
+	// Redémarré dans un conteneur neuf, puis le processus plante (politique never)
+	if code := post("/servers/"+id+"/start", ""); code != http.StatusOK {
+		t.Fatalf("second start: %d", code)
+	}
+	if next := docker.byName(serverContainerName(id)); next == nil || next.ID == ctr.ID {
+		t.Fatal("old container reused")
+	}
+	docker.exitContainer(serverContainerName(id), 1)
+	if reason := waitState(id, ServerError); reason == nil || *reason != "process exited with code 1" {
+		t.Errorf("crashed server: reason %v", reason)
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+)
+
+// Retourné par startExecution pendant l'arrêt de l'API
+var errShuttingDown = errors.New("the API is shutting down, retry later")
+
+// track compte une exécution suivie par l'instance ; false une fois l'arrêt commencé
+func (a *App) track() bool {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	if a.draining {
+		return false
+	}
+	a.running.Add(1)
+	return true
+}
+
+// Drain refuse les nouvelles exécutions (503) ; celles en cours continuent
+func (a *App) Drain() {
+	a.runMu.Lock()
+	a.draining = true
+	a.runMu.Unlock()
+}
+
+// Shutdown attend la fin des exécutions en cours jusqu'à l'expiration de ctx.
+// Les conteneurs encore actifs sont alors détachés : ils continuent dans Docker,
+// leur étape est déjà enregistrée et ResumeExecutions les reprend au démarrage suivant.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.Drain()
+
+	done := make(chan struct{})
+	go func() {
+		a.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	a.detachOnce.Do(func() { close(a.detach) })
+	<-done
+	return ctx.Err()
+}
+
+// ResumeExecutions reprend au démarrage les exécutions restées 'running' :
+// le conteneur de l'étape enregistrée est de nouveau suivi jusqu'à sa fin.
+// Celles arrêtées avant d'avoir lancé un conteneur sont marquées en échec.
+func (a *App) ResumeExecutions() {
+	running, err := a.store.Executions.Running()
+	if err != nil {
+		a.log.Errorf("executions: %v", err)
+		return
+	}
+
+	for _, e := range running {
+		checkpoint, err := a.store.Executions.Checkpoint(e.ID)
+		if errors.Is(err, store.ErrNotFound) {
+			a.storeLogs(e.ID, "stderr", "execution interrupted: the API stopped before the container started")
+			a.updateExecution(e.ID, "failed", -1)
+			continue
+		} else if err != nil {
+			a.log.Errorf("executions: execution %s: %v", e.ID, err)
+			continue
+		}
+
+		script, err := a.loadScript(e.ScriptID, e.UserID)
+		if err != nil {
+			a.log.Errorf("executions: execution %s: script %s: %v", e.ID, e.ScriptID, err)
+			continue
+		}
+
+		req := executionRequest{Trigger: e.Trigger, Env: checkpoint.Env, Stdin: checkpoint.Stdin}
+		if e.ScheduleID != nil {
+			req.ScheduleID = *e.ScheduleID
+		}
+		if e.TriggerID != nil {
+			req.TriggerID = *e.TriggerID
+		}
+
+		if !a.track() {
+			return
+		}
+		a.log.Infof("executions: resuming %s at the %s phase", e.ID, checkpoint.Phase)
+		go a.runContainer(e.ID, script, req, &checkpoint)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+)
+
+func TestDrainRejectsRuns(t *testing.T) {
+	app, st := newTestApp(t)
+	srv := httptest.NewServer(app.Handler())
+	defer srv.Close()
+
+	createUser(t, st, "alice", "secret")
+	user, err := st.Users.GetByUsername("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.Scripts.Create(store.Script{ID: "script-1", UserID: user.ID, Name: "s", Language: "python", DockerImage: "python", FilePath: "s.py", Mounts: "[]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := login(t, srv, "alice", "secret")
+	app.Drain()
+	resp, err := c.Post(srv.URL+api.Prefix+"/scripts/script-1/run", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("run while draining: status %d, Retry-After %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+
+	// Aucune exécution suivie : Shutdown rend la main tout de suite
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := app.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+}
+
+func TestResumeExecutionsWithoutCheckpoint(t *testing.T) {
+	app, st := newTestApp(t)
+	createUser(t, st, "alice", "secret")
+	user, err := st.Users.GetByUsername("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Scripts.Create(store.Script{ID: "script-1", UserID: user.ID, Name: "s", Language: "python", DockerImage: "python", FilePath: "s.py", Mounts: "[]"}); err != nil {
+		t.Fatal(err)
+	}
+	startedAt := time.Now().UTC().Format(time.RFC3339)
+	if err := st.Executions.Create(store.Execution{ID: "exec-1", ScriptID: "script-1", UserID: user.ID, Status: "running", Trigger: TriggerManual, StartedAt: &startedAt}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Arrêtée avant son conteneur : rien à reprendre, l'exécution échoue
+	app.ResumeExecutions()
+	if status, _ := st.Executions.Status("exec-1"); status != "failed" {
+		t.Fatalf("status = %q, want failed", status)
+	}
+}
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/auth"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// Vérification des appels entrants
+const (
+	TriggerAuthToken = "token" // secret passé en header X-Webhook-Token (ou X-Gitlab-Token)
+	TriggerAuthHMAC  = "hmac"  // signature HMAC-SHA256 du body (GitHub, Gitea)
+)
+
+const (
+	// Taille max du body transmis en stdin
+	maxTriggerBody = 1 << 20
+	// Temps max d'attente en mode synchrone avant de répondre 202
+	triggerSyncTimeout = 60 * time.Second
+)
+
+var headerNamePattern = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+type Trigger struct {
+	ID        string   `json:"id"`
+	ScriptID  string   `json:"script_id"`
+	URL       string   `json:"url"`
+	AuthMode  string   `json:"auth_mode"`
+	Headers   []string `json:"headers"`
+	Sync      bool     `json:"sync"`
+	CreatedAt string   `json:"created_at"`
+	// Secret n'est retourné qu'à la création
+	Secret string `json:"secret,omitempty"`
+}
+
+// triggerEnvName convertit un header en variable d'environnement (X-GitHub-Event -> WEBHOOK_HEADER_X_GITHUB_EVENT)
+func triggerEnvName(header string) string {
+	return "WEBHOOK_HEADER_" + strings.ToUpper(strings.ReplaceAll(header, "-", "_"))
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ListTriggersHandler — GET /scripts/{id}/triggers
+func (a *App) ListTriggersHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	scriptID := chi.URLParam(r, "id")
+
+	if _, err := a.loadScript(scriptID, userID); err != nil {
+		api.NotFoundErrorHandler(w, "Script not found")
+		return
+	}
+
+	stored, err := a.store.Triggers.List(scriptID, userID)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	triggers := []Trigger{}
+	for _, st := range stored {
+		t := Trigger{ID: st.ID, ScriptID: st.ScriptID, AuthMode: st.AuthMode, Sync: st.Sync, CreatedAt: st.CreatedAt}
+		json.Unmarshal([]byte(st.Headers), &t.Headers)
+		t.URL = api.Prefix + "/hooks/" + t.ID
+		triggers = append(triggers, t)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(triggers)
+}
+
+// CreateTriggerHandler — POST /scripts/{id}/triggers
+// JSON : auth_mode (token | hmac), headers (transmis en env), sync
+func (a *App) CreateTriggerHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	scriptID := chi.URLParam(r, "id")
+
+	if _, err := a.loadScript(scriptID, userID); err != nil {
+		api.NotFoundErrorHandler(w, "Script not found")
+		return
+	}
+
+	var req struct {
+		AuthMode string   `json:"auth_mode"`
+		Headers  []string `json:"headers"`
+		Sync     bool     `json:"sync"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.RequestErrorHandler(w, errors.New("invalid request"))
+		return
+	}
+
+	if req.AuthMode == "" {
+		req.AuthMode = TriggerAuthToken
+	}
+	if req.AuthMode != TriggerAuthToken && req.AuthMode != TriggerAuthHMAC {
+		api.RequestErrorHandler(w, api.Invalid("auth_mode", "%s is not supported (supported: token, hmac)", req.AuthMode))
+		return
+	}
+	if req.Headers == nil {
+		req.Headers = []string{}
+	}
+	for _, h := range req.Headers {
+		if !headerNamePattern.MatchString(h) {
+			api.RequestErrorHandler(w, api.Invalid("headers", "invalid header name: %s", h))
+			return
+		}
+	}
+
+	secret, err := auth.GenerateSessionToken()
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	// Le token n'est gardé que haché ; la clé HMAC doit rester lisible pour vérifier les signatures
+	var tokenHash, hmacSecret *string
+	if req.AuthMode == TriggerAuthToken {
+		hash := hashToken(secret)
+		tokenHash = &hash
+	} else {
+		hmacSecret = &secret
+	}
+
+	headers, _ := json.Marshal(req.Headers)
+	t := Trigger{
+		ID:       uuid.New().String(),
+		ScriptID: scriptID,
+		AuthMode: req.AuthMode,
+		Headers:  req.Headers,
+		Sync:     req.Sync,
+		Secret:   secret,
+	}
+	t.URL = api.Prefix + "/hooks/" + t.ID
+
+	err = a.store.Triggers.Create(store.Trigger{
+		ID:         t.ID,
+		ScriptID:   scriptID,
+		UserID:     userID,
+		AuthMode:   t.AuthMode,
+		TokenHash:  tokenHash,
+		HMACSecret: hmacSecret,
+		Headers:    string(headers),
+		Sync:       t.Sync,
+	})
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+	if created, err := a.store.Triggers.Get(t.ID); err == nil {
+		t.CreatedAt = created.CreatedAt
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t)
+}
+
+// DeleteTriggerHandler — DELETE /triggers/{id}
+func (a *App) DeleteTriggerHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	triggerID := chi.URLParam(r, "id")
+
+	err := a.store.Triggers.Delete(triggerID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		api.NotFoundErrorHandler(w, "Trigger not found")
+		return
+	}
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verifyTrigger contrôle le token ou la signature HMAC de l'appel entrant
+func verifyTrigger(r *http.Request, body []byte, authMode string, tokenHash, hmacSecret *string) bool {
+	switch authMode {
+	case TriggerAuthToken:
+		// Jamais dans l'URL : elle finit dans les logs des proxys
+		token := r.Header.Get("X-Webhook-Token")
+		if token == "" {
+			token = r.Header.Get("X-Gitlab-Token")
+		}
+		if token == "" || tokenHash == nil {
+			return false
+		}
+		return subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(*tokenHash)) == 1
+
+	case TriggerAuthHMAC:
+		if hmacSecret == nil {
+			return false
+		}
+		// GitHub : "sha256=<hex>", Gitea/Gogs : "<hex>"
+		signature := strings.TrimPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+		if signature == "" {
+			signature = r.Header.Get("X-Gitea-Signature")
+		}
+		if signature == "" {
+			signature = r.Header.Get("X-Gogs-Signature")
+		}
+		got, err := hex.DecodeString(signature)
+		if err != nil || len(got) == 0 {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(*hmacSecret))
+		mac.Write(body)
+		return hmac.Equal(got, mac.Sum(nil))
+	}
+	return false
+}
+
+// TriggerWebhookHandler — POST /hooks/{id}
+// Le body est passé en stdin, les headers choisis en variables d'environnement
+func (a *App) TriggerWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	triggerID := chi.URLParam(r, "id")
+
+	trigger, err := a.store.Triggers.Get(triggerID)
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Trigger not found")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTriggerBody))
+	if err != nil {
+		api.WriteError(w, http.StatusRequestEntityTooLarge, api.CodePayloadTooLarge, fmt.Sprintf("body exceeds %d bytes", maxTriggerBody))
+		return
+	}
+
+	if !verifyTrigger(r, body, trigger.AuthMode, trigger.TokenHash, trigger.HMACSecret) {
+		api.UnauthorizedErrorHandler(w, "Unauthorized")
+		return
+	}
+
+	script, err := a.loadScript(trigger.ScriptID, trigger.UserID)
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Script not found")
+		return
+	}
+
+	var headers []string
+	json.Unmarshal([]byte(trigger.Headers), &headers)
+	env := []string{"WEBHOOK_TRIGGER_ID=" + triggerID}
+	for _, h := range headers {
+		if v := r.Header.Get(h); v != "" {
+			env = append(env, triggerEnvName(h)+"="+v)
+		}
+	}
+
+	executionID, err := a.startExecution(script, executionRequest{
+		Trigger:   TriggerWebhook,
+		TriggerID: triggerID,
+		Stdin:     body,
+		Env:       env,
+	})
+	if quotaError(w, err) {
+		return
+	} else if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Execution-Id", executionID)
+
+	if trigger.Sync {
+		ctx, cancel := context.WithTimeout(r.Context(), triggerSyncTimeout)
+		defer cancel()
+
+		status, err := a.waitExecution(ctx, executionID)
+		if err == nil {
+			a.writeExecutionStdout(w, executionID, status)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"execution_id": executionID,
+		"status":       "running",
+	})
+}
+
+// writeExecutionStdout renvoie la sortie du script comme réponse HTTP
+func (a *App) writeExecutionStdout(w http.ResponseWriter, executionID, status string) {
+	logs, err := a.readLogs(executionID, "stdout", 0)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	var stdout strings.Builder
+	for _, l := range logs {
+		stdout.WriteString(l.Content)
+	}
+
+	code := http.StatusOK
+	if status != "success" {
+		code = http.StatusBadGateway
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Execution-Status", status)
+	w.WriteHeader(code)
+	io.WriteString(w, stdout.String())
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+func TestTriggerTokenOnlyInHeader(t *testing.T) {
+	app, st := newTestApp(t)
+	srv := httptest.NewServer(app.Handler())
+	defer srv.Close()
+	defer app.running.Wait()
+
+	createUser(t, st, "alice", "secret")
+	createScript(t, st, "alice", "script-1", "cobol")
+	c, _ := login(t, srv, "alice", "secret")
+
+	resp, err := c.Post(srv.URL+api.Prefix+"/scripts/script-1/triggers", "application/json", strings.NewReader(`{"auth_mode":"token"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trigger Trigger
+	json.NewDecoder(resp.Body).Decode(&trigger)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || trigger.Secret == "" {
+		t.Fatalf("create trigger: %d %+v", resp.StatusCode, trigger)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		query  string
+		header string
+		token  string
+		want   int
+	}{
+		{"query", "?token=" + trigger.Secret, "", "", http.StatusUnauthorized},
+		{"wrong header", "", "X-Webhook-Token", "nope", http.StatusUnauthorized},
+		{"header", "", "X-Webhook-Token", trigger.Secret, http.StatusAccepted},
+		{"gitlab header", "", "X-Gitlab-Token", trigger.Secret, http.StatusAccepted},
+	} {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+trigger.URL+tc.query, strings.NewReader("{}"))
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+		// Le quota d'exécutions simultanées refuserait l'appel suivant
+		app.running.Wait()
+	}
+}
+
+func TestSyncTriggerReturnsStdoutOnly(t *testing.T) {
+	app, st := newTestApp(t)
+	createUser(t, st, "alice", "secret")
+	createScript(t, st, "alice", "logs", "python")
+	newLogExecution(t, st, "e1", time.Now())
+
+	// Sortie multiplexée d'un conteneur qui écrit sur les deux flux
+	var muxed bytes.Buffer
+	stdout, stderr := stdcopy.NewStdWriter(&muxed, stdcopy.Stdout), stdcopy.NewStdWriter(&muxed, stdcopy.Stderr)
+	io.WriteString(stdout, "result 1\n")
+	io.WriteString(stderr, "warning: deprecated\n")
+	io.WriteString(stdout, "result 2\n")
+
+	out := captureOutput(&muxed)
+	if out.Stdout != "result 1\nresult 2\n" || out.Stderr != "warning: deprecated\n" {
+		t.Fatalf("output %+v", out)
+	}
+	app.storeLogs("e1", "stdout", out.Stdout)
+	app.storeLogs("e1", "stderr", out.Stderr)
+
+	w := httptest.NewRecorder()
+	app.writeExecutionStdout(w, "e1", "success")
+	if w.Code != http.StatusOK || w.Body.String() != "result 1\nresult 2\n" {
+		t.Errorf("sync response: %d %q", w.Code, w.Body.String())
+	}
+	if logs, _ := app.readLogs("e1", "stderr", 0); len(logs) != 1 || logs[0].Content != "warning: deprecated\n" {
+		t.Errorf("stderr logs %+v", logs)
+	}
+}
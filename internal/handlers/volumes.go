@@ -0,0 +1,558 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+const (
+	// Quota par défaut d'un volume
+	defaultVolumeSpace = 1 << 30
+	// Nombre max de volumes montés dans un même conteneur
+	maxMounts = 10
+)
+
+var volumeNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,63}$`)
+
+type Volume struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Space     int64  `json:"space"`
+	UsedSpace int64  `json:"usedspace"`
+	CreatedAt string `json:"created_at"`
+}
+
+// VolumeMount monte un volume de l'utilisateur dans un conteneur (script ou serveur)
+type VolumeMount struct {
+	Volume   string `json:"volume"` // nom du volume
+	Path     string `json:"path"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+type VolumeFile struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	IsDir      bool   `json:"is_dir"`
+	ModifiedAt string `json:"modified_at"`
+}
+
+func (a *App) volumeDir(volumeID string) string {
+	return filepath.Join(a.config.DataDir, "volumes", volumeID)
+}
+
+// volumeView ajoute au volume enregistré l'espace occupé sur disque
+func (a *App) volumeView(v store.Volume) Volume {
+	return Volume{ID: v.ID, Name: v.Name, Space: v.Space, UsedSpace: dirSize(a.volumeDir(v.ID)), CreatedAt: v.CreatedAt}
+}
+
+func (a *App) loadVolume(volumeID string, userID int) (Volume, error) {
+	v, err := a.store.Volumes.GetForUser(volumeID, userID)
+	if err != nil {
+		return Volume{}, err
+	}
+	return a.volumeView(v), nil
+}
+
+// parseMounts lit la colonne mounts des scripts et des serveurs
+func parseMounts(column string) []VolumeMount {
+	mounts := []VolumeMount{}
+	json.Unmarshal([]byte(column), &mounts)
+	return mounts
+}
+
+func mountsColumn(mounts []VolumeMount) string {
+	if mounts == nil {
+		mounts = []VolumeMount{}
+	}
+	data, _ := json.Marshal(mounts)
+	return string(data)
+}
+
+// validateMounts vérifie que les volumes existent et que les chemins ne touchent pas /app
+func (a *App) validateMounts(userID int, mounts []VolumeMount) error {
+	if len(mounts) > maxMounts {
+		return api.Invalid("mounts", "at most %d volumes can be mounted", maxMounts)
+	}
+	paths := map[string]bool{}
+	for i := range mounts {
+		m := &mounts[i]
+		field := fmt.Sprintf("mounts[%d]", i)
+		if !path.IsAbs(m.Path) || path.Clean(m.Path) != m.Path || m.Path == "/" {
+			return api.Invalid(field+".path", "%q must be a clean absolute path", m.Path)
+		}
+		// /app contient le script, le build et les données du serveur
+		if m.Path == "/app" || strings.HasPrefix(m.Path, "/app/") {
+			return api.Invalid(field+".path", "%s is reserved", m.Path)
+		}
+		if paths[m.Path] {
+			return api.Invalid(field+".path", "%s is used twice", m.Path)
+		}
+		paths[m.Path] = true
+
+		if _, err := a.store.Volumes.GetByName(userID, m.Volume); errors.Is(err, store.ErrNotFound) {
+			return api.Invalid(field+".volume", "%s not found", m.Volume)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mountsError répond 400 à un montage invalide et 500 à une erreur de la base
+func (a *App) mountsError(w http.ResponseWriter, err error) {
+	var invalid *api.FieldError
+	if errors.As(err, &invalid) {
+		api.RequestErrorHandler(w, err)
+		return
+	}
+	a.internalError(w, err)
+}
+
+// diskLimit est un dossier monté en écriture et son quota, revérifié tant que le conteneur tourne
+type diskLimit struct {
+	Name  string
+	Dir   string
+	Space int64
+}
+
+// checkDisks retourne errDiskQuota pour le premier dossier au-dessus de son quota
+func checkDisks(limits []diskLimit) error {
+	for _, l := range limits {
+		if used := dirSize(l.Dir); used > l.Space {
+			return fmt.Errorf("%w: %s (%d/%d bytes)", errDiskQuota, l.Name, used, l.Space)
+		}
+	}
+	return nil
+}
+
+// mountBinds traduit les montages en binds Docker en vérifiant le quota de chaque volume.
+// Les volumes montés en écriture sont aussi retournés pour être surveillés pendant l'exécution.
+func (a *App) mountBinds(userID int, mounts []VolumeMount) ([]string, []diskLimit, error) {
+	var binds []string
+	var limits []diskLimit
+	for _, m := range mounts {
+		v, err := a.store.Volumes.GetByName(userID, m.Volume)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil, blocking(fmt.Errorf("volume %s not found", m.Volume))
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		dir := a.volumeDir(v.ID)
+		if !m.ReadOnly {
+			limit := diskLimit{Name: "volume " + m.Volume, Dir: dir, Space: v.Space}
+			if err := checkDisks([]diskLimit{limit}); err != nil {
+				return nil, nil, blocking(err)
+			}
+			limits = append(limits, limit)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, nil, err
+		}
+
+		abs, _ := filepath.Abs(dir)
+		bind := abs + ":" + m.Path
+		if m.ReadOnly {
+			bind += ":ro"
+		}
+		binds = append(binds, bind)
+	}
+	return binds, limits, nil
+}
+
+// volumeUsers retourne les scripts et serveurs qui montent le volume
+func (a *App) volumeUsers(userID int, name string) []string {
+	mounts := func(column string) bool {
+		return slices.ContainsFunc(parseMounts(column), func(m VolumeMount) bool { return m.Volume == name })
+	}
+
+	var users []string
+	scripts, _ := a.store.Scripts.ByUser(userID)
+	for _, s := range scripts {
+		if mounts(s.Mounts) {
+			users = append(users, "script "+s.Name)
+		}
+	}
+	servers, _ := a.store.Servers.List(userID)
+	for _, s := range servers {
+		if mounts(s.Mounts) {
+			users = append(users, "server "+s.Name)
+		}
+	}
+	return users
+}
+
+// ListVolumesHandler — GET /volumes
+func (a *App) ListVolumesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	stored, err := a.store.Volumes.List(userID)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	volumes := []Volume{}
+	for _, v := range stored {
+		volumes = append(volumes, a.volumeView(v))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(volumes)
+}
+
+// CreateVolumeHandler — POST /volumes
+// JSON : name, space (octets, 1GB par défaut, dans la limite du plan)
+func (a *App) CreateVolumeHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	var req struct {
+		Name  string `json:"name"`
+		Space int64  `json:"space"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.RequestErrorHandler(w, errors.New("invalid request"))
+		return
+	}
+	if !volumeNamePattern.MatchString(req.Name) {
+		api.RequestErrorHandler(w, api.Invalid("name", "must be letters, digits, '_', '.' and '-' (max 64)"))
+		return
+	}
+	if req.Space < 0 {
+		api.RequestErrorHandler(w, api.Invalid("space", "must be positive"))
+		return
+	}
+	if req.Space == 0 {
+		req.Space = defaultVolumeSpace
+		if plan, err := a.quotas.UserPlan(userID); err == nil && plan.MaxVolumeSpace > 0 {
+			req.Space = min(req.Space, plan.MaxVolumeSpace)
+		}
+	}
+	if err := a.quotas.CheckVolume(userID, "", req.Space); quotaError(w, err) {
+		return
+	} else if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	volumeID := uuid.New().String()
+	if err := os.MkdirAll(a.volumeDir(volumeID), 0755); err != nil {
+		a.internalError(w, err)
+		return
+	}
+	err := a.store.Volumes.Create(store.Volume{ID: volumeID, UserID: userID, Name: req.Name, Space: req.Space})
+	if err != nil {
+		os.RemoveAll(a.volumeDir(volumeID))
+		if errors.Is(err, store.ErrConflict) {
+			api.ConflictErrorHandler(w, fmt.Errorf("volume %s already exists", req.Name))
+			return
+		}
+		a.internalError(w, err)
+		return
+	}
+
+	v, err := a.loadVolume(volumeID, userID)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(v)
+}
+
+// UpdateVolumeHandler — PATCH /volumes/{id}
+// JSON : space
+func (a *App) UpdateVolumeHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	v, err := a.loadVolume(chi.URLParam(r, "id"), userID)
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Volume not found")
+		return
+	}
+
+	var req struct {
+		Space int64 `json:"space"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.RequestErrorHandler(w, errors.New("invalid request"))
+		return
+	}
+	if req.Space <= 0 {
+		api.RequestErrorHandler(w, api.Invalid("space", "must be positive"))
+		return
+	}
+
+	if err := a.quotas.CheckVolume(userID, v.ID, req.Space); quotaError(w, err) {
+		return
+	} else if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	v.Space = req.Space
+	if err := a.store.Volumes.SetSpace(v.ID, v.Space); err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// DeleteVolumeHandler — DELETE /volumes/{id}
+// Refusé tant qu'un script ou un serveur monte le volume
+func (a *App) DeleteVolumeHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	v, err := a.loadVolume(chi.URLParam(r, "id"), userID)
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Volume not found")
+		return
+	}
+	if users := a.volumeUsers(userID, v.Name); len(users) > 0 {
+		api.ConflictErrorHandler(w, fmt.Errorf("volume %s is mounted by %s", v.Name, strings.Join(users, ", ")))
+		return
+	}
+
+	a.store.Volumes.Delete(v.ID)
+	os.RemoveAll(a.volumeDir(v.ID))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// volumeFilePath nettoie le chemin demandé ; "." désigne la racine du volume
+func volumeFilePath(r *http.Request) string {
+	p := strings.TrimPrefix(path.Clean("/"+chi.URLParam(r, "*")), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// openVolume ouvre la racine du volume ; os.Root empêche de sortir du dossier,
+// y compris par un lien symbolique créé depuis un conteneur
+func (a *App) openVolume(w http.ResponseWriter, r *http.Request) (Volume, *os.Root, bool) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	v, err := a.loadVolume(chi.URLParam(r, "id"), userID)
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Volume not found")
+		return v, nil, false
+	}
+	if err := os.MkdirAll(a.volumeDir(v.ID), 0755); err != nil {
+		a.internalError(w, err)
+		return v, nil, false
+	}
+	root, err := os.OpenRoot(a.volumeDir(v.ID))
+	if err != nil {
+		a.internalError(w, err)
+		return v, nil, false
+	}
+	return v, root, true
+}
+
+// GetVolumeFileHandler — GET /volumes/{id}/files/*
+// Liste un dossier en JSON ou télécharge un fichier
+func (a *App) GetVolumeFileHandler(w http.ResponseWriter, r *http.Request) {
+	_, root, ok := a.openVolume(w, r)
+	if !ok {
+		return
+	}
+	defer root.Close()
+
+	name := volumeFilePath(r)
+	f, err := root.Open(name)
+	if err != nil {
+		api.NotFoundErrorHandler(w, "File not found")
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	if !info.IsDir() {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", info.Name()))
+		http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+		return
+	}
+
+	entries, err := f.ReadDir(-1)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+	files := []VolumeFile{}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		file := VolumeFile{
+			Name:       e.Name(),
+			Path:       path.Join("/", name, e.Name()),
+			IsDir:      e.IsDir(),
+			ModifiedAt: info.ModTime().UTC().Format(time.RFC3339),
+		}
+		if info.Mode().IsRegular() {
+			file.Size = info.Size()
+		}
+		files = append(files, file)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(files)
+}
+
+// PutVolumeFileHandler — PUT /volumes/{id}/files/*
+// Le body brut remplace le fichier ; les dossiers parents sont créés
+func (a *App) PutVolumeFileHandler(w http.ResponseWriter, r *http.Request) {
+	v, root, ok := a.openVolume(w, r)
+	if !ok {
+		return
+	}
+	defer root.Close()
+
+	name := volumeFilePath(r)
+	if name == "." {
+		api.RequestErrorHandler(w, errors.New("file path is required"))
+		return
+	}
+
+	// Le fichier remplacé ne compte pas dans l'espace utilisé
+	available := v.Space - v.UsedSpace
+	if info, err := root.Lstat(name); err == nil {
+		if info.IsDir() {
+			api.RequestErrorHandler(w, errors.New("path is a directory"))
+			return
+		}
+		available += info.Size()
+	}
+	if available <= 0 || r.ContentLength > available {
+		api.WriteError(w, http.StatusForbidden, api.CodeQuotaExceeded, fmt.Sprintf("%v: volume %s (%d/%d bytes)", errDiskQuota, v.Name, v.UsedSpace, v.Space))
+		return
+	}
+
+	if dir := path.Dir(name); dir != "." {
+		if err := root.MkdirAll(dir, 0755); err != nil {
+			api.RequestErrorHandler(w, fmt.Errorf("can't create directory %s", dir))
+			return
+		}
+	}
+
+	// Écrire dans un fichier temporaire pour ne pas tronquer l'ancien en cas d'échec
+	tmp := path.Join(path.Dir(name), "."+path.Base(name)+"."+uuid.New().String()+".tmp")
+	dst, err := root.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+	_, err = io.Copy(dst, http.MaxBytesReader(w, r.Body, available))
+	dst.Close()
+	if err != nil {
+		root.Remove(tmp)
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			api.WriteError(w, http.StatusForbidden, api.CodeQuotaExceeded, fmt.Sprintf("%v: volume %s (%d bytes available)", errDiskQuota, v.Name, available))
+			return
+		}
+		api.RequestErrorHandler(w, errors.New("upload interrupted"))
+		return
+	}
+	if err := root.Rename(tmp, name); err != nil {
+		root.Remove(tmp)
+		a.internalError(w, err)
+		return
+	}
+
+	info, _ := root.Stat(name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(VolumeFile{
+		Name:       path.Base(name),
+		Path:       "/" + name,
+		Size:       info.Size(),
+		ModifiedAt: info.ModTime().UTC().Format(time.RFC3339),
+	})
+}
+
+// DeleteVolumeFileHandler — DELETE /volumes/{id}/files/*
+// Supprime un fichier ou un dossier et son contenu
+func (a *App) DeleteVolumeFileHandler(w http.ResponseWriter, r *http.Request) {
+	_, root, ok := a.openVolume(w, r)
+	if !ok {
+		return
+	}
+	defer root.Close()
+
+	name := volumeFilePath(r)
+	if name == "." {
+		api.RequestErrorHandler(w, errors.New("file path is required"))
+		return
+	}
+	if _, err := root.Lstat(name); errors.Is(err, fs.ErrNotExist) {
+		api.NotFoundErrorHandler(w, "File not found")
+		return
+	}
+	if err := root.RemoveAll(name); err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetScriptMountsHandler — PUT /scripts/{id}/mounts
+// JSON : liste de {volume, path, read_only}, appliquée à toutes les exécutions du script
+func (a *App) SetScriptMountsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	scriptID := chi.URLParam(r, "id")
+
+	if _, err := a.loadScript(scriptID, userID); err != nil {
+		api.NotFoundErrorHandler(w, "Script not found")
+		return
+	}
+
+	var mounts []VolumeMount
+	if err := json.NewDecoder(r.Body).Decode(&mounts); err != nil {
+		api.RequestErrorHandler(w, errors.New("invalid request"))
+		return
+	}
+	if err := a.validateMounts(userID, mounts); err != nil {
+		a.mountsError(w, err)
+		return
+	}
+
+	column := mountsColumn(mounts)
+	if err := a.store.Scripts.SetMounts(scriptID, column); err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(parseMounts(column))
+}
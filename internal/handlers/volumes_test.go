@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/quotas"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+)
+
+// newVolumeApp démarre une instance dont les volumes sont écrits dans un dossier temporaire
+func newVolumeApp(t *testing.T) (*App, *store.Store, *httptest.Server) {
+	t.Helper()
+	app, st := newTestApp(t)
+	app.config.DataDir = t.TempDir()
+	srv := httptest.NewServer(app.Handler())
+	t.Cleanup(srv.Close)
+	return app, st, srv
+}
+
+func sendJSON(t *testing.T, c *http.Client, method, url, body string, out any) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+func TestVolumePlanLimits(t *testing.T) {
+	app, st, srv := newVolumeApp(t)
+	createUser(t, st, "alice", "secret")
+	c, _ := login(t, srv, "alice", "secret")
+	err := app.quotas.SavePlan(quotas.Plan{Name: quotas.DefaultPlan, MaxVolumeSpace: 1000, MaxVolumeTotal: 2500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	volumes := srv.URL + api.Prefix + "/volumes"
+
+	// Sans space : le défaut (1GB) est ramené à la limite du plan
+	var v Volume
+	if code := sendJSON(t, c, http.MethodPost, volumes, `{"name":"cache"}`, &v); code != http.StatusCreated || v.Space != 1000 {
+		t.Fatalf("default space: %d %+v", code, v)
+	}
+	if code := sendJSON(t, c, http.MethodPost, volumes, `{"name":"big","space":1001}`, nil); code != http.StatusForbidden {
+		t.Errorf("volume over the plan: %d, want 403", code)
+	}
+	if code := sendJSON(t, c, http.MethodPost, volumes, `{"name":"second","space":1000}`, nil); code != http.StatusCreated {
+		t.Errorf("second volume: %d", code)
+	}
+	// 1000 + 1000 + 600 > 2500
+	if code := sendJSON(t, c, http.MethodPost, volumes, `{"name":"third","space":600}`, nil); code != http.StatusForbidden {
+		t.Errorf("volume over the user total: %d, want 403", code)
+	}
+	if code := sendJSON(t, c, http.MethodPost, volumes, `{"name":"third","space":500}`, nil); code != http.StatusCreated {
+		t.Errorf("volume within the user total: %d", code)
+	}
+
+	// Le volume redimensionné ne compte pas deux fois
+	if code := sendJSON(t, c, http.MethodPatch, volumes+"/"+v.ID, `{"space":900}`, nil); code != http.StatusOK {
+		t.Errorf("shrink: %d", code)
+	}
+	if code := sendJSON(t, c, http.MethodPatch, volumes+"/"+v.ID, `{"space":1001}`, nil); code != http.StatusForbidden {
+		t.Errorf("grow over the plan: %d, want 403", code)
+	}
+
+	var usage quotas.Usage
+	if code := sendJSON(t, c, http.MethodGet, srv.URL+api.Prefix+"/usage", "", &usage); code != http.StatusOK ||
+		usage.VolumeSpace != (quotas.Counter{Used: 2400, Limit: 2500}) {
+		t.Errorf("usage: %d %+v", code, usage.VolumeSpace)
+	}
+}
+
+func TestValidateMounts(t *testing.T) {
+	app, st, _ := newVolumeApp(t)
+	createUser(t, st, "alice", "secret")
+	alice, _ := st.Users.GetByUsername("alice")
+	if err := st.Volumes.Create(store.Volume{ID: "v1", UserID: alice.ID, Name: "cache", Space: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	tooMany := make([]VolumeMount, maxMounts+1)
+	for name, tc := range map[string]struct {
+		mounts []VolumeMount
+		field  string
+	}{
+		"valid":          {[]VolumeMount{{Volume: "cache", Path: "/cache"}, {Volume: "cache", Path: "/srv/cache", ReadOnly: true}}, ""},
+		"none":           {nil, ""},
+		"relative":       {[]VolumeMount{{Volume: "cache", Path: "cache"}}, "mounts[0].path"},
+		"unclean":        {[]VolumeMount{{Volume: "cache", Path: "/cache/../etc"}}, "mounts[0].path"},
+		"root":           {[]VolumeMount{{Volume: "cache", Path: "/"}}, "mounts[0].path"},
+		"app":            {[]VolumeMount{{Volume: "cache", Path: "/app"}}, "mounts[0].path"},
+		"under app":      {[]VolumeMount{{Volume: "cache", Path: "/app/data"}}, "mounts[0].path"},
+		"same path":      {[]VolumeMount{{Volume: "cache", Path: "/cache"}, {Volume: "cache", Path: "/cache"}}, "mounts[1].path"},
+		"unknown volume": {[]VolumeMount{{Volume: "missing", Path: "/cache"}}, "mounts[0].volume"},
+		"too many":       {tooMany, "mounts"},
+	} {
+		err := app.validateMounts(alice.ID, tc.mounts)
+		var invalid *api.FieldError
+		switch {
+		case tc.field == "" && err != nil:
+			t.Errorf("%s: %v", name, err)
+		case tc.field != "" && (!errors.As(err, &invalid) || invalid.Field != tc.field):
+			t.Errorf("%s: got %v, want an error on %s", name, err, tc.field)
+		}
+	}
+
+	// Les volumes des autres utilisateurs n'existent pas
+	createUser(t, st, "bob", "secret")
+	bob, _ := st.Users.GetByUsername("bob")
+	if err := app.validateMounts(bob.ID, []VolumeMount{{Volume: "cache", Path: "/cache"}}); err == nil {
+		t.Error("bob mounted alice's volume")
+	}
+
+	// Seules les erreurs de validation répondent 400, la base en panne répond 500
+	for err, want := range map[error]int{
+		api.Invalid("mounts[0].path", "/app is reserved"): http.StatusBadRequest,
+		errors.New("database is locked"):                  http.StatusInternalServerError,
+	} {
+		rec := httptest.NewRecorder()
+		app.mountsError(rec, err)
+		if rec.Code != want {
+			t.Errorf("%v: status %d, want %d", err, rec.Code, want)
+		}
+	}
+}
+
+func TestMountBindsDiskLimits(t *testing.T) {
+	app, st, _ := newVolumeApp(t)
+	createUser(t, st, "alice", "secret")
+	alice, _ := st.Users.GetByUsername("alice")
+	st.Volumes.Create(store.Volume{ID: "v1", UserID: alice.ID, Name: "cache", Space: 10})
+
+	mounts := []VolumeMount{{Volume: "cache", Path: "/cache"}, {Volume: "cache", Path: "/ro", ReadOnly: true}}
+	binds, limits, err := app.mountBinds(alice.ID, mounts)
+	if err != nil || len(binds) != 2 || !strings.HasSuffix(binds[1], ":/ro:ro") {
+		t.Fatalf("binds %v, err %v", binds, err)
+	}
+	// Seul le montage en écriture est surveillé
+	if len(limits) != 1 || limits[0].Space != 10 || checkDisks(limits) != nil {
+		t.Fatalf("limits %+v", limits)
+	}
+
+	// Le conteneur a rempli le volume : la surveillance le voit, le prochain lancement est refusé
+	if err := os.WriteFile(filepath.Join(app.volumeDir("v1"), "data"), make([]byte, 11), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkDisks(limits); !errors.Is(err, errDiskQuota) || !strings.Contains(err.Error(), "volume cache (11/10 bytes)") {
+		t.Errorf("checkDisks: %v", err)
+	}
+	if _, _, err := app.mountBinds(alice.ID, mounts); !errors.Is(err, errDiskQuota) || !isBlocking(err) {
+		t.Errorf("writable mount of a full volume: %v", err)
+	}
+	if _, _, err := app.mountBinds(alice.ID, mounts[1:]); err != nil {
+		t.Errorf("read-only mount of a full volume: %v", err)
+	}
+	if _, _, err := app.mountBinds(alice.ID, []VolumeMount{{Volume: "missing", Path: "/x"}}); !isBlocking(err) {
+		t.Errorf("missing volume: %v", err)
+	}
+}
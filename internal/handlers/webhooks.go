@@ -0,0 +1,431 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/auth"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/egress"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/middleware"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// Événements envoyés aux webhooks sortants
+const (
+	EventExecutionStarted   = "execution.started"
+	EventExecutionSucceeded = "execution.succeeded"
+	EventExecutionFailed    = "execution.failed"
+	EventExecutionTimedOut  = "execution.timed_out"
+)
+
+var webhookEvents = []string{
+	EventExecutionStarted,
+	EventExecutionSucceeded,
+	EventExecutionFailed,
+	EventExecutionTimedOut,
+}
+
+const (
+	// Fréquence de traitement de l'outbox
+	webhookDispatchInterval = 5 * time.Second
+	// Nombre d'envois avant d'abandonner une livraison
+	webhookMaxAttempts = 8
+	// Premier délai de retry, doublé à chaque échec
+	webhookRetryBase = 30 * time.Second
+	webhookRetryMax  = time.Hour
+)
+
+// Les livraisons ne peuvent viser que des adresses publiques, vérifiées à chaque connexion
+var webhookClient = egress.Client(10 * time.Second)
+
+type Webhook struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Enabled   bool     `json:"enabled"`
+	CreatedAt string   `json:"created_at"`
+	// Secret n'est retourné qu'à la création
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID             string  `json:"id"`
+	WebhookID      string  `json:"webhook_id"`
+	Event          string  `json:"event"`
+	Status         string  `json:"status"`
+	Attempts       int     `json:"attempts"`
+	NextAttemptAt  *string `json:"next_attempt_at"`
+	LastStatusCode *int    `json:"last_status_code"`
+	LastError      *string `json:"last_error"`
+	CreatedAt      string  `json:"created_at"`
+	DeliveredAt    *string `json:"delivered_at"`
+}
+
+// webhookBackoff retourne le délai avant le prochain essai (30s, 1m, 2m, ... 1h max)
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookRetryMax)
+}
+
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ListWebhooksHandler — GET /webhooks
+func (a *App) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	stored, err := a.store.Webhooks.List(userID)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	webhooks := []Webhook{}
+	for _, st := range stored {
+		wh := Webhook{ID: st.ID, URL: st.URL, Enabled: st.Enabled, CreatedAt: st.CreatedAt}
+		json.Unmarshal([]byte(st.Events), &wh.Events)
+		webhooks = append(webhooks, wh)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// CreateWebhookHandler — POST /webhooks
+// JSON : url, events (vide = tous)
+func (a *App) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+
+	var req struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.RequestErrorHandler(w, errors.New("invalid request"))
+		return
+	}
+
+	if err := egress.CheckURL(r.Context(), req.URL); err != nil {
+		api.RequestErrorHandler(w, api.Invalid("url", "%v", err))
+		return
+	}
+	if len(req.Events) == 0 {
+		req.Events = webhookEvents
+	}
+	for _, event := range req.Events {
+		if !slices.Contains(webhookEvents, event) {
+			api.RequestErrorHandler(w, api.Invalid("events", "unknown event: %s", event))
+			return
+		}
+	}
+
+	secret, err := auth.GenerateSessionToken()
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	wh := Webhook{
+		ID:      uuid.New().String(),
+		URL:     req.URL,
+		Events:  req.Events,
+		Enabled: true,
+		Secret:  secret,
+	}
+	events, _ := json.Marshal(wh.Events)
+	err = a.store.Webhooks.Create(store.Webhook{
+		ID:      wh.ID,
+		UserID:  userID,
+		URL:     wh.URL,
+		Secret:  wh.Secret,
+		Events:  string(events),
+		Enabled: true,
+	})
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+	if created, err := a.store.Webhooks.GetForUser(wh.ID, userID); err == nil {
+		wh.CreatedAt = created.CreatedAt
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(wh)
+}
+
+// UpdateWebhookHandler — PATCH /webhooks/{id}
+// JSON : enabled
+func (a *App) UpdateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	webhookID := chi.URLParam(r, "id")
+
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		api.RequestErrorHandler(w, api.Invalid("enabled", "is required"))
+		return
+	}
+
+	err := a.store.Webhooks.SetEnabled(webhookID, userID, *req.Enabled)
+	if errors.Is(err, store.ErrNotFound) {
+		api.NotFoundErrorHandler(w, "Webhook not found")
+		return
+	}
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteWebhookHandler — DELETE /webhooks/{id}
+func (a *App) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	webhookID := chi.URLParam(r, "id")
+
+	err := a.store.Webhooks.Delete(webhookID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		api.NotFoundErrorHandler(w, "Webhook not found")
+		return
+	}
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveriesHandler — GET /webhooks/{id}/deliveries
+func (a *App) ListWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	webhookID := chi.URLParam(r, "id")
+
+	if _, err := a.store.Webhooks.GetForUser(webhookID, userID); err != nil {
+		api.NotFoundErrorHandler(w, "Webhook not found")
+		return
+	}
+
+	stored, err := a.store.Webhooks.Deliveries(webhookID, 100)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	deliveries := []WebhookDelivery{}
+	for _, d := range stored {
+		deliveries = append(deliveries, WebhookDelivery{
+			ID: d.ID, WebhookID: d.WebhookID, Event: d.Event, Status: d.Status, Attempts: d.Attempts,
+			NextAttemptAt: d.NextAttemptAt, LastStatusCode: d.LastStatusCode, LastError: d.LastError,
+			CreatedAt: d.CreatedAt, DeliveredAt: d.DeliveredAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// RedeliverWebhookHandler — POST /webhooks/{id}/deliveries/{deliveryID}/redeliver
+// Crée une nouvelle livraison avec le même payload
+func (a *App) RedeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	webhookID := chi.URLParam(r, "id")
+	deliveryID := chi.URLParam(r, "deliveryID")
+
+	if _, err := a.store.Webhooks.GetForUser(webhookID, userID); err != nil {
+		api.NotFoundErrorHandler(w, "Delivery not found")
+		return
+	}
+	d, err := a.store.Webhooks.Delivery(deliveryID, webhookID)
+	if err != nil {
+		api.NotFoundErrorHandler(w, "Delivery not found")
+		return
+	}
+
+	newID, err := a.enqueueDelivery(webhookID, d.Event, d.Payload)
+	if err != nil {
+		a.internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"id": newID})
+}
+
+func (a *App) enqueueDelivery(webhookID, event, payload string) (string, error) {
+	id := uuid.New().String()
+	now := time.Now().UTC().Format(time.RFC3339)
+	err := a.store.Webhooks.Enqueue(store.WebhookDelivery{
+		ID:            id,
+		WebhookID:     webhookID,
+		Event:         event,
+		Payload:       payload,
+		NextAttemptAt: &now,
+	})
+	return id, err
+}
+
+// executionEvent retourne l'événement correspondant au statut final d'une exécution
+func executionEvent(status string) string {
+	switch status {
+	case "success":
+		return EventExecutionSucceeded
+	case "timed_out":
+		return EventExecutionTimedOut
+	default:
+		return EventExecutionFailed
+	}
+}
+
+// emitExecutionEvent écrit une livraison dans l'outbox pour chaque webhook abonné
+func (a *App) emitExecutionEvent(executionID, event string) {
+	type executionPayload struct {
+		ID         string  `json:"id"`
+		ScriptID   string  `json:"script_id"`
+		Status     string  `json:"status"`
+		Trigger    string  `json:"trigger"`
+		ExitCode   *int    `json:"exit_code"`
+		StartedAt  *string `json:"started_at"`
+		FinishedAt *string `json:"finished_at"`
+	}
+
+	stored, err := a.store.Executions.Get(executionID)
+	if err != nil {
+		a.log.Errorf("webhooks: execution %s: %v", executionID, err)
+		return
+	}
+	e := executionPayload{
+		ID: stored.ID, ScriptID: stored.ScriptID, Status: stored.Status, Trigger: stored.Trigger,
+		ExitCode: stored.ExitCode, StartedAt: stored.StartedAt, FinishedAt: stored.FinishedAt,
+	}
+
+	webhooks, err := a.store.Webhooks.List(stored.UserID)
+	if err != nil {
+		a.log.Errorf("webhooks: %v", err)
+		return
+	}
+	var targets []string
+	for _, wh := range webhooks {
+		var events []string
+		json.Unmarshal([]byte(wh.Events), &events)
+		if wh.Enabled && slices.Contains(events, event) {
+			targets = append(targets, wh.ID)
+		}
+	}
+
+	if len(targets) == 0 {
+		return
+	}
+
+	payload, _ := json.Marshal(map[string]any{
+		"event":      event,
+		"created_at": time.Now().UTC().Format(time.RFC3339),
+		"execution":  e,
+	})
+	for _, webhookID := range targets {
+		if _, err := a.enqueueDelivery(webhookID, event, string(payload)); err != nil {
+			a.log.Errorf("webhooks: webhook %s: %v", webhookID, err)
+		}
+	}
+}
+
+// StartWebhookDispatcher lance la boucle qui vide l'outbox des livraisons
+func (a *App) StartWebhookDispatcher(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(webhookDispatchInterval)
+		defer ticker.Stop()
+
+		for {
+			a.dispatchWebhooks(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (a *App) dispatchWebhooks(ctx context.Context) {
+	batch, err := a.store.Webhooks.Pending(time.Now().UTC().Format(time.RFC3339), 50)
+	if err != nil {
+		a.log.Errorf("webhooks: %v", err)
+		return
+	}
+
+	for _, p := range batch {
+		if ctx.Err() != nil {
+			return
+		}
+
+		statusCode, err := sendWebhook(ctx, p.URL, p.Secret, p.ID, p.Event, []byte(p.Payload))
+		attempts := p.Attempts + 1
+		now := time.Now().UTC()
+
+		var code *int
+		if statusCode > 0 {
+			code = &statusCode
+		}
+
+		if err == nil {
+			a.store.Webhooks.Delivered(p.ID, attempts, code, now.Format(time.RFC3339))
+			continue
+		}
+
+		var next *string
+		if attempts < webhookMaxAttempts {
+			retryAt := now.Add(webhookBackoff(attempts)).Format(time.RFC3339)
+			next = &retryAt
+		}
+		a.store.Webhooks.Retry(p.ID, attempts, code, err.Error(), next)
+	}
+}
+
+// sendWebhook poste le payload signé et considère tout code 2xx comme un succès
+func sendWebhook(ctx context.Context, target, secret, deliveryID, event string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "webhosting-goapi-webhooks")
+	req.Header.Set("X-Webhook-Event", event)
+	req.Header.Set("X-Webhook-Delivery", deliveryID)
+	req.Header.Set("X-Webhook-Signature-256", signWebhookPayload(secret, payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+)
+
+func TestCreateWebhookRefusesInternalAddresses(t *testing.T) {
+	srv, st := newTestServer(t)
+	createUser(t, st, "alice", "secret")
+	c, _ := login(t, srv, "alice", "secret")
+
+	for _, tc := range []struct {
+		url  string
+		want int
+	}{
+		{"http://127.0.0.1:8080/hook", http.StatusBadRequest},
+		{"http://169.254.169.254/latest/meta-data/", http.StatusBadRequest},
+		{"http://10.0.0.12/hook", http.StatusBadRequest},
+		{"http://[::1]/hook", http.StatusBadRequest},
+		{"http://localhost/hook", http.StatusBadRequest},
+		{"ftp://93.184.216.34/hook", http.StatusBadRequest},
+		{"https://93.184.216.34/hook", http.StatusCreated},
+	} {
+		resp, err := c.Post(srv.URL+api.Prefix+"/webhooks", "application/json", strings.NewReader(`{"url":"`+tc.url+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.url, resp.StatusCode, tc.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// AllowList donne accès aux règles d'images autorisées d'une base
+type AllowList struct {
+	rules store.ImageRules
+}
+
+func NewAllowList(rules store.ImageRules) *AllowList {
+	return &AllowList{rules: rules}
+}
+
+var (
+	ErrInvalidReference = errors.New("invalid image reference")
+	ErrNotAllowed       = errors.New("image is not in the allow-list")
+	ErrDigestRequired   = errors.New("image must be pinned by digest (name@sha256:...)")
+)
+
+// Rule est une entrée de l'allow-list.
+// Pattern vaut soit un dépôt exact (docker.io/library/python),
+// soit un préfixe terminé par /* (ghcr.io/my-org/*)
+type Rule struct {
+	ID            int    `json:"id"`
+	Pattern       string `json:"pattern"`
+	RequireDigest bool   `json:"require_digest"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// Matches indique si le dépôt normalisé est couvert par la règle
+func (r Rule) Matches(repo string) bool {
+	if prefix, ok := strings.CutSuffix(r.Pattern, "/*"); ok {
+		return strings.HasPrefix(repo, prefix+"/")
+	}
+	return repo == r.Pattern
+}
+
+// NormalizePattern accepte les noms courts (python, my-org/*) et les complète
+func NormalizePattern(pattern string) (string, error) {
+	pattern = strings.TrimSpace(pattern)
+	prefix, wildcard := strings.CutSuffix(pattern, "/*")
+
+	if !wildcard {
+		named, err := reference.ParseNormalizedNamed(pattern)
+		if err != nil || !reference.IsNameOnly(named) {
+			return "", ErrInvalidReference
+		}
+		return named.Name(), nil
+	}
+
+	// Registre (ghcr.io/*), namespace Docker Hub (my-org/*) ou les deux (ghcr.io/my-org/*)
+	if _, err := reference.ParseNormalizedNamed(prefix + "/x"); err != nil {
+		return "", ErrInvalidReference
+	}
+	first, _, _ := strings.Cut(prefix, "/")
+	if !strings.ContainsAny(first, ".:") && first != "localhost" {
+		prefix = "docker.io/" + prefix
+	}
+	return prefix + "/*", nil
+}
+
+// Normalize parse une référence et ajoute le tag latest si aucun tag ni digest n'est donné
+func Normalize(ref string) (reference.Named, error) {
+	named, err := reference.ParseNormalizedNamed(strings.TrimSpace(ref))
+	if err != nil {
+		return nil, ErrInvalidReference
+	}
+	return reference.TagNameOnly(named), nil
+}
+
+// Check valide une référence contre l'allow-list et retourne sa forme courte
+func (l *AllowList) Check(ref string) (string, error) {
+	named, err := Normalize(ref)
+	if err != nil {
+		return "", err
+	}
+
+	rules, err := l.ListRules()
+	if err != nil {
+		return "", err
+	}
+
+	_, pinned := named.(reference.Canonical)
+	for _, rule := range rules {
+		if !rule.Matches(named.Name()) {
+			continue
+		}
+		if rule.RequireDigest && !pinned {
+			return "", ErrDigestRequired
+		}
+		return reference.FamiliarString(named), nil
+	}
+	return "", ErrNotAllowed
+}
+
+// Docker regroupe les appels de Ensure, implémentés par *client.Client
+type Docker interface {
+	ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error)
+	ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error)
+}
+
+// Ensure télécharge l'image si elle n'est pas déjà présente sur l'hôte
+// et remonte les erreurs du flux de progression Docker
+func Ensure(ctx context.Context, cli Docker, ref string) error {
+	if _, _, err := cli.ImageInspectWithRaw(ctx, ref); err == nil {
+		return nil
+	} else if !client.IsErrNotFound(err) {
+		return err
+	}
+
+	out, err := cli.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return jsonmessage.DisplayJSONMessagesStream(out, io.Discard, 0, false, nil)
+}
+
+func (l *AllowList) ListRules() ([]Rule, error) {
+	stored, err := l.rules.List()
+	if err != nil {
+		return nil, err
+	}
+	rules := []Rule{}
+	for _, r := range stored {
+		rules = append(rules, Rule(r))
+	}
+	return rules, nil
+}
+
+func (l *AllowList) AddRule(pattern string, requireDigest bool) (int, error) {
+	normalized, err := NormalizePattern(pattern)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", err, pattern)
+	}
+	return l.rules.Save(normalized, requireDigest)
+}
+
+func (l *AllowList) DeleteRule(id int) (bool, error) {
+	err := l.rules.Delete(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
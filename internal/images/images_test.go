@@ -0,0 +1,167 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/database"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+)
+
+func TestNormalizePattern(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, want string
+		err           bool
+	}{
+		{pattern: "python", want: "docker.io/library/python"},
+		{pattern: " my-org/app ", want: "docker.io/my-org/app"},
+		{pattern: "ghcr.io/my-org/app", want: "ghcr.io/my-org/app"},
+		{pattern: "my-org/*", want: "docker.io/my-org/*"},
+		{pattern: "ghcr.io/*", want: "ghcr.io/*"},
+		{pattern: "ghcr.io/my-org/*", want: "ghcr.io/my-org/*"},
+		{pattern: "localhost:5000/*", want: "localhost:5000/*"},
+		{pattern: "python:3.12", err: true},
+		{pattern: "python@sha256:" + strings.Repeat("a", 64), err: true},
+		{pattern: "Bad/Name", err: true},
+		{pattern: "*", err: true},
+		{pattern: "", err: true},
+	} {
+		got, err := NormalizePattern(tc.pattern)
+		if tc.err {
+			if !errors.Is(err, ErrInvalidReference) {
+				t.Errorf("NormalizePattern(%q) = %q, %v, want an error", tc.pattern, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("NormalizePattern(%q) = %q, %v, want %q", tc.pattern, got, err, tc.want)
+		}
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, repo string
+		want          bool
+	}{
+		{"docker.io/library/python", "docker.io/library/python", true},
+		{"docker.io/library/python", "docker.io/library/python2", false},
+		{"ghcr.io/my-org/*", "ghcr.io/my-org/app", true},
+		{"ghcr.io/my-org/*", "ghcr.io/my-org/team/app", true},
+		{"ghcr.io/my-org/*", "ghcr.io/my-org-evil/app", false},
+		{"ghcr.io/my-org/*", "ghcr.io/my-org", false},
+	} {
+		if got := (Rule{Pattern: tc.pattern}).Matches(tc.repo); got != tc.want {
+			t.Errorf("%s matches %s = %v", tc.pattern, tc.repo, got)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	st, err := store.Open(database.SQLite, database.Memory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	list := NewAllowList(st.ImageRules)
+
+	if _, err := list.Check("python:3.12"); !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("empty allow-list: %v", err)
+	}
+	for _, rule := range []struct {
+		pattern string
+		digest  bool
+	}{{"python", false}, {"ghcr.io/my-org/*", true}} {
+		if _, err := list.AddRule(rule.pattern, rule.digest); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := list.AddRule("not a pattern", false); !errors.Is(err, ErrInvalidReference) {
+		t.Fatalf("invalid pattern: %v", err)
+	}
+
+	digest := "@sha256:" + strings.Repeat("a", 64)
+	for _, tc := range []struct {
+		ref, want string
+		err       error
+	}{
+		{ref: "python", want: "python:latest"},
+		{ref: "docker.io/library/python:3.12-alpine", want: "python:3.12-alpine"},
+		{ref: "pythonx", err: ErrNotAllowed},
+		{ref: "ghcr.io/my-org/app:1.0", err: ErrDigestRequired},
+		{ref: "ghcr.io/my-org/app" + digest, want: "ghcr.io/my-org/app" + digest},
+		{ref: "ghcr.io/other/app" + digest, err: ErrNotAllowed},
+		{ref: "UPPER", err: ErrInvalidReference},
+	} {
+		got, err := list.Check(tc.ref)
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Check(%q) = %q, %v, want %v", tc.ref, got, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("Check(%q) = %q, %v, want %q", tc.ref, got, err, tc.want)
+		}
+	}
+
+	// Ajouter une règle existante met à jour l'exigence de digest
+	if _, err := list.AddRule("ghcr.io/my-org/*", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := list.Check("ghcr.io/my-org/app:1.0"); err != nil {
+		t.Errorf("rule not updated: %v", err)
+	}
+	rules, _ := list.ListRules()
+	if len(rules) != 2 {
+		t.Fatalf("rules %+v", rules)
+	}
+	if deleted, err := list.DeleteRule(rules[0].ID); !deleted || err != nil {
+		t.Errorf("delete: %v %v", deleted, err)
+	}
+	if deleted, _ := list.DeleteRule(rules[0].ID); deleted {
+		t.Error("rule deleted twice")
+	}
+}
+
+// fakeDocker simule un hôte Docker avec les images de present
+type fakeDocker struct {
+	present map[string]bool
+	pulled  []string
+	stream  string
+}
+
+func (d *fakeDocker) ImageInspectWithRaw(ctx context.Context, ref string) (types.ImageInspect, []byte, error) {
+	if d.present[ref] {
+		return types.ImageInspect{ID: ref}, nil, nil
+	}
+	return types.ImageInspect{}, nil, errdefs.NotFound(errors.New("No such image: " + ref))
+}
+
+func (d *fakeDocker) ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error) {
+	d.pulled = append(d.pulled, ref)
+	return io.NopCloser(strings.NewReader(d.stream)), nil
+}
+
+func TestEnsure(t *testing.T) {
+	ctx := context.Background()
+	d := &fakeDocker{present: map[string]bool{"python:3.12": true}, stream: `{"status":"Pulling"}`}
+	if err := Ensure(ctx, d, "python:3.12"); err != nil || len(d.pulled) != 0 {
+		t.Fatalf("present image: %v, pulled %v", err, d.pulled)
+	}
+	if err := Ensure(ctx, d, "node:20"); err != nil || len(d.pulled) != 1 {
+		t.Fatalf("missing image: %v, pulled %v", err, d.pulled)
+	}
+
+	// Erreur remontée dans le flux de progression
+	d.stream = `{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}`
+	if err := Ensure(ctx, d, "node:nope"); err == nil || !strings.Contains(err.Error(), "manifest unknown") {
+		t.Fatalf("pull error: %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package languages
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Chemins vus depuis le conteneur
+const (
+	ScriptDir = "/app"
+	BuildDir  = "/app/build"
+)
+
+//go:embed languages.json
+var defaultConfig []byte
+
+// Language décrit un runtime supporté par l'instance
+type Language struct {
+	Name      string   `json:"name"`
+	Aliases   []string `json:"aliases,omitempty"`
+	Image     string   `json:"image"`
+	Extension string   `json:"extension"`
+	Run       []string `json:"run"`
+	Compile   []string `json:"compile,omitempty"`
+}
+
+type config struct {
+	Languages []Language `json:"languages"`
+}
+
+// Compiled indique si le langage a une phase de build avant l'exécution
+func (l Language) Compiled() bool {
+	return len(l.Compile) > 0
+}
+
+// ScriptPath retourne le chemin du script dans le conteneur
+func (l Language) ScriptPath() string {
+	return ScriptDir + "/script" + l.Extension
+}
+
+// RunCommand retourne la commande d'exécution avec les variables remplacées
+func (l Language) RunCommand() []string {
+	return l.expand(l.Run)
+}
+
+// CompileCommand retourne la commande de build (nil si interprété)
+func (l Language) CompileCommand() []string {
+	if !l.Compiled() {
+		return nil
+	}
+	return l.expand(l.Compile)
+}
+
+// expand remplace {{file}} et {{build}} dans chaque argument
+func (l Language) expand(tmpl []string) []string {
+	replacer := strings.NewReplacer(
+		"{{file}}", l.ScriptPath(),
+		"{{build}}", BuildDir,
+	)
+	cmd := make([]string, len(tmpl))
+	for i, arg := range tmpl {
+		cmd[i] = replacer.Replace(arg)
+	}
+	return cmd
+}
+
+func (l Language) validate() error {
+	switch {
+	case l.Name == "":
+		return errors.New("language name is required")
+	case l.Image == "":
+		return fmt.Errorf("language %s: image is required", l.Name)
+	case !strings.HasPrefix(l.Extension, "."):
+		return fmt.Errorf("language %s: extension must start with a dot", l.Name)
+	case len(l.Run) == 0:
+		return fmt.Errorf("language %s: run command is required", l.Name)
+	}
+	return nil
+}
+
+// Registry indexe les langages par nom et alias
+type Registry struct {
+	languages []Language
+	byName    map[string]Language
+}
+
+// Parse construit un registre à partir d'une config JSON
+func Parse(data []byte) (*Registry, error) {
+	var cfg config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid languages config: %w", err)
+	}
+
+	reg := &Registry{byName: map[string]Language{}}
+	for _, l := range cfg.Languages {
+		if err := l.validate(); err != nil {
+			return nil, err
+		}
+		for _, key := range append([]string{l.Name}, l.Aliases...) {
+			if _, exists := reg.byName[key]; exists {
+				return nil, fmt.Errorf("language %s is defined twice", key)
+			}
+			reg.byName[key] = l
+		}
+		reg.languages = append(reg.languages, l)
+	}
+
+	sort.Slice(reg.languages, func(i, j int) bool {
+		return reg.languages[i].Name < reg.languages[j].Name
+	})
+	return reg, nil
+}
+
+// Load lit le fichier de config, ou les définitions embarquées s'il n'existe pas
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Parse(defaultConfig)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// SetImage remplace l'image Docker d'un langage (nom ou alias)
+func (r *Registry) SetImage(name, image string) error {
+	l, ok := r.byName[name]
+	if !ok {
+		return fmt.Errorf("unknown language %s", name)
+	}
+	if image == "" {
+		return fmt.Errorf("language %s: image is required", l.Name)
+	}
+	l.Image = image
+
+	for _, key := range append([]string{l.Name}, l.Aliases...) {
+		r.byName[key] = l
+	}
+	for i := range r.languages {
+		if r.languages[i].Name == l.Name {
+			r.languages[i] = l
+		}
+	}
+	return nil
+}
+
+// Get retourne le langage correspondant au nom ou à un alias
+func (r *Registry) Get(name string) (Language, bool) {
+	l, ok := r.byName[name]
+	return l, ok
+}
+
+// List retourne les langages triés par nom
+func (r *Registry) List() []Language {
+	return r.languages
+}
+
+// Names retourne les noms et alias acceptés
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.byName))
+	for name := range r.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,125 @@
+package languages
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadShipped(t *testing.T) {
+	reg, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reg.List()) == 0 {
+		t.Fatal("no shipped language")
+	}
+	for i, l := range reg.List() {
+		if i > 0 && reg.List()[i-1].Name >= l.Name {
+			t.Errorf("languages not sorted: %s before %s", reg.List()[i-1].Name, l.Name)
+		}
+		for _, key := range append([]string{l.Name}, l.Aliases...) {
+			if got, ok := reg.Get(key); !ok || got.Name != l.Name {
+				t.Errorf("Get(%q) = %v, %v", key, got.Name, ok)
+			}
+		}
+	}
+	if js, ok := reg.Get("js"); !ok || js.Name != "nodejs" {
+		t.Errorf("alias js = %+v", js)
+	}
+
+	// Le fichier de l'instance remplace les définitions embarquées
+	path := filepath.Join(t.TempDir(), "languages.json")
+	os.WriteFile(path, []byte(`{"languages":[{"name":"sh","image":"alpine","extension":".sh","run":["sh","{{file}}"]}]}`), 0o600)
+	if reg, err = Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if names := reg.Names(); !reflect.DeepEqual(names, []string{"sh"}) {
+		t.Errorf("names %v", names)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		config, want string
+	}{
+		{`{"languages":`, "invalid languages config"},
+		{`{"languages":[{"image":"x","extension":".x","run":["x"]}]}`, "name is required"},
+		{`{"languages":[{"name":"x","extension":".x","run":["x"]}]}`, "image is required"},
+		{`{"languages":[{"name":"x","image":"x","extension":"x","run":["x"]}]}`, "extension must start with a dot"},
+		{`{"languages":[{"name":"x","image":"x","extension":".x"}]}`, "run command is required"},
+		{`{"languages":[{"name":"x","image":"x","extension":".x","run":["x"]},{"name":"y","aliases":["x"],"image":"y","extension":".y","run":["y"]}]}`, "defined twice"},
+	} {
+		if _, err := Parse([]byte(tc.config)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Parse(%s) = %v, want %q", tc.config, err, tc.want)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	for _, tc := range []struct {
+		lang         Language
+		run, compile []string
+		compiled     bool
+	}{
+		{
+			lang: Language{Name: "python", Extension: ".py", Run: []string{"python", "{{file}}"}},
+			run:  []string{"python", "/app/script.py"},
+		},
+		{
+			lang:     Language{Name: "go", Extension: ".go", Compile: []string{"go", "build", "-o", "{{build}}/main", "{{file}}"}, Run: []string{"{{build}}/main"}},
+			run:      []string{"/app/build/main"},
+			compile:  []string{"go", "build", "-o", "/app/build/main", "/app/script.go"},
+			compiled: true,
+		},
+		{
+			lang: Language{Name: "sh", Extension: ".sh", Run: []string{"sh", "-c", "cat {{file}} > {{build}}/copy && sh {{build}}/copy"}},
+			run:  []string{"sh", "-c", "cat /app/script.sh > /app/build/copy && sh /app/build/copy"},
+		},
+	} {
+		if got := tc.lang.RunCommand(); !reflect.DeepEqual(got, tc.run) {
+			t.Errorf("%s RunCommand = %q, want %q", tc.lang.Name, got, tc.run)
+		}
+		if got := tc.lang.CompileCommand(); !reflect.DeepEqual(got, tc.compile) {
+			t.Errorf("%s CompileCommand = %q, want %q", tc.lang.Name, got, tc.compile)
+		}
+		if tc.lang.Compiled() != tc.compiled {
+			t.Errorf("%s Compiled = %v", tc.lang.Name, tc.lang.Compiled())
+		}
+	}
+
+	// Le template n'est pas modifié par le rendu
+	l := Language{Extension: ".py", Run: []string{"{{file}}"}}
+	l.RunCommand()
+	if l.Run[0] != "{{file}}" {
+		t.Errorf("template modified: %q", l.Run)
+	}
+}
+
+func TestSetImage(t *testing.T) {
+	reg, err := Parse(defaultConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.SetImage("js", "node:22-alpine"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"js", "nodejs"} {
+		if l, _ := reg.Get(key); l.Image != "node:22-alpine" {
+			t.Errorf("Get(%s).Image = %s", key, l.Image)
+		}
+	}
+	for _, l := range reg.List() {
+		if l.Name == "nodejs" && l.Image != "node:22-alpine" {
+			t.Errorf("List image %s", l.Image)
+		}
+	}
+	if err := reg.SetImage("cobol", "x"); err == nil {
+		t.Error("unknown language accepted")
+	}
+	if err := reg.SetImage("python", ""); err == nil {
+		t.Error("empty image accepted")
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"errors"
 	"net/http"
 	"context"
-	"database/sql"
+	"strings"
 
 	"github.com/Cryptowave2-0/webhosting-goapi/api"
 	"github.com/Cryptowave2-0/webhosting-goapi/internal/auth"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+	log "github.com/sirupsen/logrus"
 )
 
 type contextKey string
@@ -16,19 +18,39 @@ const UserIDKey contextKey = "userID"
 
 var UnAuthorizedError = errors.New("Invalid username or token.")
 
-func AuthMiddleware(db *sql.DB) func(http.Handler) http.Handler {
+// AuthMiddleware répond 401 à une clé ou une session inconnue ; une autre erreur
+// (base indisponible...) est loguée et répond 500
+func AuthMiddleware(sessions *auth.Service, logger *log.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			cookie, err := r.Cookie("session_token")
-			if err != nil {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
+			var userID int
+			// Clé d'API (Authorization: Bearer whk_...), sinon cookie de session
+			if key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+				id, err := sessions.GetUserFromAPIKey(strings.TrimSpace(key))
+				if errors.Is(err, store.ErrNotFound) {
+					api.UnauthorizedErrorHandler(w, UnAuthorizedError.Error())
+					return
+				} else if err != nil {
+					internalError(logger, w, r, err)
+					return
+				}
+				userID = id
+			} else {
+				cookie, err := r.Cookie("session_token")
+				if err != nil {
+					api.UnauthorizedErrorHandler(w, "Unauthorized")
+					return
+				}
 
-			userID, err := auth.GetUserFromSession(cookie.Value)
-			if err != nil {
-				api.RequestErrorHandler(w, UnAuthorizedError)
-				return
+				id, err := sessions.GetUserFromSession(cookie.Value)
+				if errors.Is(err, store.ErrNotFound) {
+					api.UnauthorizedErrorHandler(w, UnAuthorizedError.Error())
+					return
+				} else if err != nil {
+					internalError(logger, w, r, err)
+					return
+				}
+				userID = id
 			}
 
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
@@ -36,3 +58,26 @@ func AuthMiddleware(db *sql.DB) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// internalError logue err avec l'identifiant de la requête et répond 500 sans la détailler
+func internalError(logger *log.Logger, w http.ResponseWriter, r *http.Request, err error) {
+	logger.WithField("request_id", GetRequestID(r.Context())).Error(err)
+	api.InternalErrorHandler(w)
+}
+
+// AdminMiddleware doit être placé après AuthMiddleware
+func AdminMiddleware(sessions *auth.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := r.Context().Value(UserIDKey).(int)
+
+			isAdmin, err := sessions.IsAdmin(userID)
+			if err != nil || !isAdmin {
+				api.ForbiddenErrorHandler(w, "Forbidden")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
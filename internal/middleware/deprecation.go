@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Deprecated marque les réponses d'une route remplacée par prefix + le même chemin :
+// Deprecation (RFC 9745), Sunset (RFC 8594) si la date de retrait est connue,
+// et un lien successor-version vers la nouvelle route
+func Deprecated(since, sunset time.Time, prefix string) func(http.Handler) http.Handler {
+	deprecation := fmt.Sprintf("@%d", since.Unix())
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			w.Header().Add("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, prefix, r.URL.EscapedPath()))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRedirectHTTPS(t *testing.T) {
+	tests := []struct {
+		method, host, target, port string
+		code                       int
+		location                   string
+	}{
+		{http.MethodGet, "example.com", "/scripts?page=2", "443", http.StatusMovedPermanently, "https://example.com/scripts?page=2"},
+		{http.MethodGet, "example.com:80", "/", "8443", http.StatusMovedPermanently, "https://example.com:8443/"},
+		{http.MethodPost, "example.com", "/login", "443", http.StatusPermanentRedirect, "https://example.com/login"},
+		{http.MethodGet, "[::1]:80", "/", "443", http.StatusMovedPermanently, "https://[::1]/"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		RedirectHTTPS(tt.port).ServeHTTP(rec, req)
+		if rec.Code != tt.code || rec.Header().Get("Location") != tt.location {
+			t.Errorf("%s %s%s: %d %q, want %d %q", tt.method, tt.host, tt.target,
+				rec.Code, rec.Header().Get("Location"), tt.code, tt.location)
+		}
+	}
+}
+
+func TestHSTS(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{}
+	rec := httptest.NewRecorder()
+	HSTS(24*time.Hour, true)(ok).ServeHTTP(rec, req)
+	if got := rec.Header().Get("Strict-Transport-Security"); got != "max-age=86400; includeSubDomains" {
+		t.Fatalf("header = %q", got)
+	}
+
+	for name, handler := range map[string]http.Handler{
+		"plain HTTP": HSTS(24*time.Hour, false)(ok),
+		"disabled":   HSTS(0, false)(ok),
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if name != "plain HTTP" {
+			r.TLS = &tls.ConnectionState{}
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if got := rec.Header().Get("Strict-Transport-Security"); got != "" {
+			t.Errorf("%s: header = %q, want none", name, got)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const RequestIDKey contextKey = "requestID"
+
+// RequestID reprend le X-Request-Id du client s'il est court et sans caractère spécial,
+// sinon en génère un ; il est renvoyé dans la réponse et repris dans les erreurs
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(api.RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		w.Header().Set(api.RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		ok := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.'
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// GetRequestID retourne l'identifiant posé par RequestID
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
+
+// Recover transforme une panique d'un handler en erreur 500, sans la détailler au client
+func Recover(logger *log.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				logger.WithField("request_id", GetRequestID(r.Context())).
+					Errorf("panic serving %s %s: %v\n%s", r.Method, r.URL.Path, p, debug.Stack())
+				api.InternalErrorHandler(w)
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/api"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetRequestID(r.Context())
+	}))
+
+	for _, tt := range []struct{ sent, want string }{
+		{"abc-123", "abc-123"},
+		{"", ""},
+		{"bad id\n", ""},
+		{strings.Repeat("a", 65), ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(api.RequestIDHeader, tt.sent)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		got := rec.Header().Get(api.RequestIDHeader)
+		if got == "" || got != seen || (tt.want != "" && got != tt.want) || (tt.want == "" && got == tt.sent) {
+			t.Errorf("sent %q: header %q, context %q", tt.sent, got, seen)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	h := RequestID(Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("secret internal state")
+	})))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "secret") {
+		t.Fatalf("%d %s", rec.Code, rec.Body)
+	}
+	var body api.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error.Code != api.CodeInternal || body.Error.RequestID != rec.Header().Get(api.RequestIDHeader) {
+		t.Fatalf("%+v", body.Error)
+	}
+}
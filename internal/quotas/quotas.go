@@ -0,0 +1,323 @@
+package quotas
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+)
+
+// Service applique les plans et quotas des utilisateurs d'une base
+type Service struct {
+	store *store.Store
+}
+
+func New(st *store.Store) *Service {
+	return &Service{store: st}
+}
+
+// Plan attribué aux utilisateurs sans plan explicite
+const DefaultPlan = "default"
+
+var (
+	ErrQuotaExceeded    = errors.New("quota exceeded")
+	ErrConcurrencyLimit = errors.New("too many running executions and servers")
+	ErrPlanNotFound     = errors.New("plan not found")
+	ErrPlanInUse        = errors.New("plan is assigned to users")
+	ErrInvalidPlan      = errors.New("invalid plan")
+)
+
+var planNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Plan regroupe les limites d'un utilisateur ; 0 signifie illimité
+type Plan struct {
+	Name              string `json:"name"`
+	MaxScripts        int64  `json:"max_scripts"`
+	MaxStorage        int64  `json:"max_storage"`         // octets sous data/scripts
+	MaxConcurrent     int64  `json:"max_concurrent"`      // exécutions et serveurs en cours
+	MaxComputeSeconds int64  `json:"max_compute_seconds"` // secondes de conteneur par mois, serveurs compris
+	MaxVolumeSpace    int64  `json:"max_volume_space"`    // octets d'un volume
+	MaxVolumeTotal    int64  `json:"max_volume_total"`    // octets réservés par l'ensemble des volumes
+	Users             int    `json:"users"`
+}
+
+// Counter associe une consommation à sa limite (0 = illimité)
+type Counter struct {
+	Used  int64 `json:"used"`
+	Limit int64 `json:"limit"`
+}
+
+func (c Counter) exceededBy(n int64) bool {
+	return c.Limit > 0 && c.Used+n > c.Limit
+}
+
+type Usage struct {
+	Plan           string  `json:"plan"`
+	Scripts        Counter `json:"scripts"`
+	Storage        Counter `json:"storage"`
+	Concurrent     Counter `json:"concurrent"`
+	ComputeSeconds Counter `json:"compute_seconds"`
+	VolumeSpace    Counter `json:"volume_space"` // quotas des volumes, pas leur contenu
+	PeriodStart    string  `json:"period_start"`
+	PeriodEnd      string  `json:"period_end"`
+}
+
+// monthStart retourne le début du mois (UTC) qui sert de période de facturation
+func monthStart(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (s *Service) ListPlans() ([]Plan, error) {
+	stored, err := s.store.Plans.List()
+	if err != nil {
+		return nil, err
+	}
+	plans := []Plan{}
+	for _, p := range stored {
+		plans = append(plans, Plan(p))
+	}
+	return plans, nil
+}
+
+// SavePlan crée ou remplace un plan
+func (s *Service) SavePlan(p Plan) error {
+	if !planNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("%w: name must be lowercase letters, digits, '_' and '-'", ErrInvalidPlan)
+	}
+	if p.MaxScripts < 0 || p.MaxStorage < 0 || p.MaxConcurrent < 0 || p.MaxComputeSeconds < 0 || p.MaxVolumeSpace < 0 || p.MaxVolumeTotal < 0 {
+		return fmt.Errorf("%w: limits must not be negative (0 = unlimited)", ErrInvalidPlan)
+	}
+	return s.store.Plans.Save(store.Plan(p))
+}
+
+// DeletePlan supprime un plan qui n'est plus attribué ; le plan par défaut est conservé
+func (s *Service) DeletePlan(name string) error {
+	if name == DefaultPlan {
+		return fmt.Errorf("%w: the default plan can't be deleted", ErrInvalidPlan)
+	}
+	p, err := s.store.Plans.Get(name)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrPlanNotFound
+	} else if err != nil {
+		return err
+	}
+	if p.Users > 0 {
+		return fmt.Errorf("%w (%d)", ErrPlanInUse, p.Users)
+	}
+	if err := s.store.Plans.Delete(name); errors.Is(err, store.ErrNotFound) {
+		return ErrPlanNotFound
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetUserPlan attribue un plan existant à un utilisateur ; store.ErrNotFound si l'utilisateur n'existe pas
+func (s *Service) SetUserPlan(userID int, name string) error {
+	if _, err := s.store.Plans.Get(name); errors.Is(err, store.ErrNotFound) {
+		return ErrPlanNotFound
+	} else if err != nil {
+		return err
+	}
+	return s.store.Plans.Assign(userID, name)
+}
+
+// UserPlan retourne le plan de l'utilisateur, ou le plan par défaut si le sien a disparu
+func (s *Service) UserPlan(userID int) (Plan, error) {
+	p, err := s.store.Plans.ForUser(userID, DefaultPlan)
+	return Plan(p), err
+}
+
+// scriptStorage additionne la taille des dossiers de scripts
+func scriptStorage(scripts []store.Script) int64 {
+	var total int64
+	for _, script := range scripts {
+		filepath.WalkDir(filepath.Dir(script.FilePath), func(_ string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+				total += info.Size()
+			}
+			return nil
+		})
+	}
+	return total
+}
+
+// runUsage calcule les compteurs qui limitent les démarrages : exécutions et serveurs
+// en cours, secondes de conteneur du mois (les exécutions et serveurs en cours comptent jusqu'à now)
+func (s *Service) runUsage(plan Plan, userID int, now time.Time) (concurrent, compute Counter, err error) {
+	concurrent = Counter{Limit: plan.MaxConcurrent}
+	compute = Counter{Limit: plan.MaxComputeSeconds}
+	if concurrent.Used, err = s.store.Executions.CountRunning(userID); err != nil {
+		return
+	}
+	start := monthStart(now)
+	if compute.Used, err = s.store.Executions.ComputeSeconds(userID, start, now); err != nil {
+		return
+	}
+	servers, err := s.store.Servers.ComputeSeconds(userID, start, now)
+	compute.Used += servers
+	return
+}
+
+// GetUsage calcule la consommation de l'utilisateur sur le mois en cours
+func (s *Service) GetUsage(userID int, now time.Time) (Usage, error) {
+	plan, err := s.UserPlan(userID)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	start := monthStart(now)
+	u := Usage{
+		Plan:        plan.Name,
+		Scripts:     Counter{Limit: plan.MaxScripts},
+		Storage:     Counter{Limit: plan.MaxStorage},
+		VolumeSpace: Counter{Limit: plan.MaxVolumeTotal},
+		PeriodStart: start.Format(time.RFC3339),
+		PeriodEnd:   start.AddDate(0, 1, 0).Format(time.RFC3339),
+	}
+
+	scripts, err := s.store.Scripts.ByUser(userID)
+	if err != nil {
+		return u, err
+	}
+	u.Scripts.Used = int64(len(scripts))
+	u.Storage.Used = scriptStorage(scripts)
+	if u.VolumeSpace.Used, err = s.volumeSpace(userID, ""); err != nil {
+		return u, err
+	}
+	u.Concurrent, u.ComputeSeconds, err = s.runUsage(plan, userID, now)
+	return u, err
+}
+
+// CheckUpload vérifie qu'un nouveau script de size octets tient dans le plan.
+// Le nombre de scripts est revérifié à l'insertion par CreateScript.
+func (s *Service) CheckUpload(userID int, size int64) error {
+	u, err := s.GetUsage(userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if u.Scripts.exceededBy(1) {
+		return fmt.Errorf("%w: %d/%d scripts", ErrQuotaExceeded, u.Scripts.Used, u.Scripts.Limit)
+	}
+	if u.Storage.exceededBy(size) {
+		return fmt.Errorf("%w: storage %d + %d bytes over %d", ErrQuotaExceeded, u.Storage.Used, size, u.Storage.Limit)
+	}
+	return nil
+}
+
+// CreateScript enregistre le script si l'utilisateur n'a pas atteint son nombre de scripts ;
+// la vérification et l'insertion sont atomiques
+func (s *Service) CreateScript(script store.Script) error {
+	plan, err := s.UserPlan(script.UserID)
+	if err != nil {
+		return err
+	}
+	err = s.store.Scripts.CreateWithin(script, plan.MaxScripts)
+	if errors.Is(err, store.ErrLimitReached) {
+		return fmt.Errorf("%w: %d/%d scripts", ErrQuotaExceeded, plan.MaxScripts, plan.MaxScripts)
+	}
+	return err
+}
+
+// checkCompute vérifie qu'il reste des secondes de conteneur ce mois-ci
+func (s *Service) checkCompute(plan Plan, userID int) (Counter, error) {
+	concurrent, compute, err := s.runUsage(plan, userID, time.Now())
+	if err != nil {
+		return concurrent, err
+	}
+	if compute.Limit > 0 && compute.Used >= compute.Limit {
+		return concurrent, fmt.Errorf("%w: %d/%d compute seconds this month", ErrQuotaExceeded, compute.Used, compute.Limit)
+	}
+	return concurrent, nil
+}
+
+// CheckRun vérifie qu'une exécution ou un serveur de plus peut démarrer.
+// Simple pré-vérification : la place est réservée par StartExecution, StartQueued
+// ou store.Servers.SetStarted avec la limite MaxConcurrent du plan.
+func (s *Service) CheckRun(userID int) error {
+	plan, err := s.UserPlan(userID)
+	if err != nil {
+		return err
+	}
+	concurrent, err := s.checkCompute(plan, userID)
+	if err != nil {
+		return err
+	}
+	if concurrent.exceededBy(1) {
+		return fmt.Errorf("%w: %d/%d", ErrConcurrencyLimit, concurrent.Used, concurrent.Limit)
+	}
+	return nil
+}
+
+// StartExecution enregistre l'exécution 'running' e si le plan le permet ;
+// la vérification du nombre d'exécutions en cours et l'insertion sont atomiques
+func (s *Service) StartExecution(e store.Execution) error {
+	plan, err := s.UserPlan(e.UserID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.checkCompute(plan, e.UserID); err != nil {
+		return err
+	}
+	err = s.store.Executions.CreateRunning(e, plan.MaxConcurrent)
+	if errors.Is(err, store.ErrLimitReached) {
+		return fmt.Errorf("%w: %d/%d", ErrConcurrencyLimit, plan.MaxConcurrent, plan.MaxConcurrent)
+	}
+	return err
+}
+
+// StartQueued passe l'exécution en attente id à 'running' si le plan le permet ;
+// false si elle n'était plus en attente ou si l'utilisateur a trop d'exécutions en cours
+func (s *Service) StartQueued(id string, userID int, startedAt string) (bool, error) {
+	plan, err := s.UserPlan(userID)
+	if err != nil {
+		return false, err
+	}
+	if _, err := s.checkCompute(plan, userID); err != nil {
+		return false, err
+	}
+	return s.store.Executions.Start(id, userID, startedAt, plan.MaxConcurrent)
+}
+
+// volumeSpace additionne les quotas des volumes de l'utilisateur, sauf exceptID
+func (s *Service) volumeSpace(userID int, exceptID string) (int64, error) {
+	volumes, err := s.store.Volumes.List(userID)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, v := range volumes {
+		if v.ID != exceptID {
+			total += v.Space
+		}
+	}
+	return total, nil
+}
+
+// CheckVolume vérifie qu'un volume de space octets tient dans le plan.
+// volumeID est le volume redimensionné, vide pour une création.
+func (s *Service) CheckVolume(userID int, volumeID string, space int64) error {
+	plan, err := s.UserPlan(userID)
+	if err != nil {
+		return err
+	}
+	if plan.MaxVolumeSpace > 0 && space > plan.MaxVolumeSpace {
+		return fmt.Errorf("%w: volume of %d bytes over %d", ErrQuotaExceeded, space, plan.MaxVolumeSpace)
+	}
+	total := Counter{Limit: plan.MaxVolumeTotal}
+	if total.Used, err = s.volumeSpace(userID, volumeID); err != nil {
+		return err
+	}
+	if total.exceededBy(space) {
+		return fmt.Errorf("%w: volumes %d + %d bytes over %d", ErrQuotaExceeded, total.Used, space, total.Limit)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package quotas
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/database"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/store"
+)
+
+func newTestService(t *testing.T) (*Service, *store.Store, int) {
+	t.Helper()
+	st, err := store.Open(database.SQLite, database.Memory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	userID, err := st.Users.Create("alice", "hash", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(st), st, userID
+}
+
+// script crée un script dont le dossier contient size octets
+func script(t *testing.T, id string, userID int, size int) store.Script {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "script.py")
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return store.Script{ID: id, UserID: userID, Name: id, Language: "python", DockerImage: "python", FilePath: path}
+}
+
+// finished enregistre une exécution terminée de duration, démarrée à start
+func finished(t *testing.T, st *store.Store, id, scriptID string, userID int, start time.Time, duration time.Duration) {
+	t.Helper()
+	startedAt := start.UTC().Format(time.RFC3339)
+	err := st.Executions.Create(store.Execution{ID: id, ScriptID: scriptID, UserID: userID, Status: "running", Trigger: "manual", StartedAt: &startedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Executions.Finish(id, "success", 0, start.Add(duration).UTC().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMonthStart(t *testing.T) {
+	paris := time.FixedZone("Paris", 3600)
+	for in, want := range map[time.Time]time.Time{
+		time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC):   time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC):     time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 2, 28, 23, 59, 59, 0, time.UTC): time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		// 1er mars à 0h30 à Paris : encore février en UTC
+		time.Date(2026, 3, 1, 0, 30, 0, 0, paris): time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		if got := monthStart(in); !got.Equal(want) {
+			t.Errorf("monthStart(%v) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestPlans(t *testing.T) {
+	s, st, userID := newTestService(t)
+
+	if p, err := s.UserPlan(userID); err != nil || p.Name != DefaultPlan {
+		t.Fatalf("plan without assignment = %+v, %v", p, err)
+	}
+	for name, p := range map[string]Plan{
+		"name":     {Name: "Pro!"},
+		"negative": {Name: "pro", MaxConcurrent: -1},
+	} {
+		if err := s.SavePlan(p); !errors.Is(err, ErrInvalidPlan) {
+			t.Errorf("%s: err = %v, want ErrInvalidPlan", name, err)
+		}
+	}
+	if err := s.SetUserPlan(userID, "pro"); !errors.Is(err, ErrPlanNotFound) {
+		t.Errorf("missing plan: err = %v, want ErrPlanNotFound", err)
+	}
+
+	if err := s.SavePlan(Plan{Name: "pro", MaxScripts: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetUserPlan(userID, "pro"); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := s.UserPlan(userID); err != nil || p.Name != "pro" || p.MaxScripts != 100 {
+		t.Fatalf("assigned plan = %+v, %v", p, err)
+	}
+	if err := s.DeletePlan("pro"); !errors.Is(err, ErrPlanInUse) {
+		t.Errorf("delete assigned plan: err = %v, want ErrPlanInUse", err)
+	}
+	if err := s.DeletePlan(DefaultPlan); !errors.Is(err, ErrInvalidPlan) {
+		t.Errorf("delete default plan: err = %v, want ErrInvalidPlan", err)
+	}
+
+	// Plan disparu (supprimé hors du service) : retour au plan par défaut
+	if err := st.Plans.Delete("pro"); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := s.UserPlan(userID); err != nil || p.Name != DefaultPlan {
+		t.Fatalf("plan after deletion = %+v, %v", p, err)
+	}
+}
+
+func TestCheckUpload(t *testing.T) {
+	s, _, userID := newTestService(t)
+	if err := s.SavePlan(Plan{Name: DefaultPlan, MaxScripts: 2, MaxStorage: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.CreateScript(script(t, "first", userID, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckUpload(userID, 2); err != nil {
+		t.Errorf("upload within the storage: %v", err)
+	}
+	if err := s.CheckUpload(userID, 3); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("upload over the storage: err = %v, want ErrQuotaExceeded", err)
+	}
+
+	if err := s.CreateScript(script(t, "second", userID, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckUpload(userID, 0); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("upload over the script count: err = %v, want ErrQuotaExceeded", err)
+	}
+	// Deux envois vérifiés en même temps : l'insertion refuse le second
+	if err := s.CreateScript(script(t, "third", userID, 0)); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("create over the script count: err = %v, want ErrQuotaExceeded", err)
+	}
+}
+
+func TestCheckRun(t *testing.T) {
+	s, st, userID := newTestService(t)
+	if err := s.SavePlan(Plan{Name: DefaultPlan, MaxConcurrent: 2, MaxComputeSeconds: 600}); err != nil {
+		t.Fatal(err)
+	}
+	sc := script(t, "script", userID, 0)
+	if err := st.Scripts.Create(sc); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	if err := s.StartExecution(store.Execution{ID: "e1", ScriptID: sc.ID, UserID: userID, Trigger: "manual", StartedAt: &now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckRun(userID); err != nil {
+		t.Errorf("one slot left: %v", err)
+	}
+
+	// Le serveur en marche prend la dernière place
+	if err := st.Servers.Create(store.Server{ID: "srv", UserID: userID, Name: "web", ScriptID: sc.ID, Access: "public", RestartPolicy: "never"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Servers.SetStarted("srv", 1, "c1", nil, now, "starting", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckRun(userID); !errors.Is(err, ErrConcurrencyLimit) {
+		t.Errorf("check with a running server: err = %v, want ErrConcurrencyLimit", err)
+	}
+	if err := s.StartExecution(store.Execution{ID: "e2", ScriptID: sc.ID, UserID: userID, Trigger: "manual", StartedAt: &now}); !errors.Is(err, ErrConcurrencyLimit) {
+		t.Errorf("start with a running server: err = %v, want ErrConcurrencyLimit", err)
+	}
+	if err := st.Executions.Create(store.Execution{ID: "queued", ScriptID: sc.ID, UserID: userID, Status: "queued", Trigger: "schedule"}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.StartQueued("queued", userID, now); err != nil || ok {
+		t.Errorf("start queued at the limit = %v, %v", ok, err)
+	}
+
+	// Une place se libère
+	if err := st.Servers.SetState("srv", 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.StartQueued("queued", userID, now); err != nil || !ok {
+		t.Errorf("start queued = %v, %v", ok, err)
+	}
+
+	// Secondes de conteneur du mois épuisées
+	finished(t, st, "long", sc.ID, userID, time.Now().Add(-11*time.Minute), 10*time.Minute)
+	if err := s.CheckRun(userID); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("check without compute seconds: err = %v, want ErrQuotaExceeded", err)
+	}
+	if err := s.StartExecution(store.Execution{ID: "e3", ScriptID: sc.ID, UserID: userID, Trigger: "manual", StartedAt: &now}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("start without compute seconds: err = %v, want ErrQuotaExceeded", err)
+	}
+}
+
+func TestGetUsage(t *testing.T) {
+	s, st, userID := newTestService(t)
+	if err := s.SavePlan(Plan{Name: DefaultPlan, MaxScripts: 5, MaxConcurrent: 3, MaxComputeSeconds: 10000, MaxVolumeTotal: 100}); err != nil {
+		t.Fatal(err)
+	}
+	sc := script(t, "script", userID, 42)
+	if err := st.Scripts.Create(sc); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Volumes.Create(store.Volume{ID: "v1", UserID: userID, Name: "cache", Space: 60}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	now := start.Add(15 * 24 * time.Hour)
+	// Le mois précédent ne compte pas
+	finished(t, st, "february", sc.ID, userID, start.Add(-time.Hour), 30*time.Minute)
+	finished(t, st, "march", sc.ID, userID, start.Add(time.Hour), 100*time.Second)
+	// Serveur démarré fin février, toujours en marche : compté depuis le 1er mars
+	if err := st.Servers.Create(store.Server{ID: "srv", UserID: userID, Name: "web", ScriptID: sc.ID, Access: "public", RestartPolicy: "never"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Servers.SetStarted("srv", 1, "c1", nil, start.Add(-time.Hour).Format(time.RFC3339), "starting", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := s.GetUsage(userID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Usage{
+		Plan:           DefaultPlan,
+		Scripts:        Counter{Used: 1, Limit: 5},
+		Storage:        Counter{Used: 42},
+		Concurrent:     Counter{Used: 1, Limit: 3},
+		ComputeSeconds: Counter{Used: 100 + int64(now.Sub(start).Seconds()), Limit: 10000},
+		VolumeSpace:    Counter{Used: 60, Limit: 100},
+		PeriodStart:    "2026-03-01T00:00:00Z",
+		PeriodEnd:      "2026-04-01T00:00:00Z",
+	}
+	if u != want {
+		t.Errorf("usage = %+v\nwant    %+v", u, want)
+	}
+}
@@ -0,0 +1,86 @@
+package store
+
+import "time"
+
+type APIKey struct {
+	ID     string
+	UserID int
+	Name   string
+	// Début de la clé, affiché pour la reconnaître ; la clé n'est gardée que hachée
+	Prefix     string
+	KeyHash    string
+	CreatedAt  string  // RFC3339
+	LastUsedAt *string // RFC3339, nil si jamais utilisée
+}
+
+type APIKeys interface {
+	// Create retourne ErrConflict si l'utilisateur a déjà une clé de ce nom
+	Create(k APIKey) error
+	// List retourne les clés de l'utilisateur, par date de création
+	List(userID int) ([]APIKey, error)
+	// GetByHash retourne la clé de ce hash, ErrNotFound si elle n'existe pas
+	GetByHash(keyHash string) (APIKey, error)
+	// Touch enregistre une utilisation de la clé, au plus une fois par minute
+	Touch(id string, at time.Time) error
+	Delete(id string, userID int) error
+}
+
+type apiKeyRepo struct{ *querier }
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, created_at, last_used_at`
+
+func (r *apiKeyRepo) Create(k APIKey) error {
+	_, err := r.exec(
+		`INSERT INTO api_keys (id, user_id, name, prefix, key_hash) VALUES (?, ?, ?, ?, ?)`,
+		k.ID, k.UserID, k.Name, k.Prefix, k.KeyHash,
+	)
+	if r.dialect.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *apiKeyRepo) scan(row interface{ Scan(...any) error }) (APIKey, error) {
+	var k APIKey
+	err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.KeyHash, &k.CreatedAt, &k.LastUsedAt)
+	k.CreatedAt = rfc3339(k.CreatedAt)
+	if k.LastUsedAt != nil {
+		at := rfc3339(*k.LastUsedAt)
+		k.LastUsedAt = &at
+	}
+	return k, notFound(err)
+}
+
+func (r *apiKeyRepo) List(userID int) ([]APIKey, error) {
+	rows, err := r.query(`SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		k, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *apiKeyRepo) GetByHash(keyHash string) (APIKey, error) {
+	return r.scan(r.queryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ?`, keyHash))
+}
+
+func (r *apiKeyRepo) Touch(id string, at time.Time) error {
+	_, err := r.exec(
+		`UPDATE api_keys SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)`,
+		sqlTime(at), id, sqlTime(at.Add(-time.Minute)),
+	)
+	return err
+}
+
+func (r *apiKeyRepo) Delete(id string, userID int) error {
+	return affected(r.exec(`DELETE FROM api_keys WHERE id = ? AND user_id = ?`, id, userID))
+}
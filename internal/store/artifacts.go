@@ -0,0 +1,97 @@
+package store
+
+type Artifact struct {
+	ID          string
+	ExecutionID string
+	UserID      int
+	Path        string // relatif au dossier d'artifacts de l'exécution, séparateurs /
+	Size        int64
+	CreatedAt   string // RFC3339
+	ExpiresAt   string // RFC3339
+}
+
+type Artifacts interface {
+	Create(a Artifact) error
+	// List retourne les artifacts de l'exécution, par chemin
+	List(executionID string) ([]Artifact, error)
+	Get(id, executionID string) (Artifact, error)
+	DeleteByExecution(executionID string) error
+	// Expired retourne les exécutions dont les artifacts ont expiré, ont perdu leur exécution
+	// ou dépassent les keep dernières exécutions avec artifacts de leur script
+	Expired(now string, keep int) ([]string, error)
+}
+
+type artifactRepo struct{ *querier }
+
+const artifactColumns = `id, execution_id, user_id, path, size, created_at, expires_at`
+
+func (r *artifactRepo) Create(a Artifact) error {
+	_, err := r.exec(
+		`INSERT INTO artifacts (id, execution_id, user_id, path, size, expires_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		a.ID, a.ExecutionID, a.UserID, a.Path, a.Size, a.ExpiresAt,
+	)
+	if r.dialect.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *artifactRepo) scan(row interface{ Scan(...any) error }) (Artifact, error) {
+	var a Artifact
+	err := row.Scan(&a.ID, &a.ExecutionID, &a.UserID, &a.Path, &a.Size, &a.CreatedAt, &a.ExpiresAt)
+	a.CreatedAt = rfc3339(a.CreatedAt)
+	return a, notFound(err)
+}
+
+func (r *artifactRepo) List(executionID string) ([]Artifact, error) {
+	rows, err := r.query(`SELECT `+artifactColumns+` FROM artifacts WHERE execution_id = ? ORDER BY path`, executionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var artifacts []Artifact
+	for rows.Next() {
+		a, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, a)
+	}
+	return artifacts, rows.Err()
+}
+
+func (r *artifactRepo) Get(id, executionID string) (Artifact, error) {
+	return r.scan(r.queryRow(`SELECT `+artifactColumns+` FROM artifacts WHERE id = ? AND execution_id = ?`, id, executionID))
+}
+
+func (r *artifactRepo) DeleteByExecution(executionID string) error {
+	_, err := r.exec(`DELETE FROM artifacts WHERE execution_id = ?`, executionID)
+	return err
+}
+
+func (r *artifactRepo) Expired(now string, keep int) ([]string, error) {
+	rows, err := r.query(
+		`SELECT DISTINCT a.execution_id FROM artifacts a
+		 LEFT JOIN executions e ON e.id = a.execution_id
+		 WHERE a.expires_at <= ? OR e.id IS NULL
+		    OR (SELECT COUNT(DISTINCT a2.execution_id) FROM artifacts a2
+		        JOIN executions e2 ON e2.id = a2.execution_id
+		        WHERE e2.script_id = e.script_id AND e2.started_at > e.started_at) >= ?`,
+		now, keep,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
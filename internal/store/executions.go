@@ -0,0 +1,398 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Execution struct {
+	ID         string
+	ScriptID   string
+	UserID     int
+	Status     string
+	Trigger    string
+	ScheduleID *string
+	TriggerID  *string
+	ExitCode   *int
+	StartedAt  *string
+	FinishedAt *string
+	CreatedAt  string // RFC3339
+	// nil tant que le conteneur n'a pas été mesuré
+	Metrics *Metrics
+}
+
+// Metrics reprend les champs de handlers.ExecutionMetrics (conversion directe)
+type Metrics struct {
+	DurationMs      int64
+	PeakMemoryBytes int64
+	CPUTimeMs       int64
+	NetRxBytes      int64
+	NetTxBytes      int64
+	BlockReadBytes  int64
+	BlockWriteBytes int64
+}
+
+// Checkpoint est l'étape en cours d'une exécution, gardée pour la reprendre après un redémarrage de l'API
+type Checkpoint struct {
+	ExecutionID string
+	Phase       string // build ou run
+	Deadline    string // RFC3339
+	Env         []string
+	Stdin       []byte
+}
+
+type Executions interface {
+	// Create enregistre une exécution 'running' (StartedAt renseigné) ou 'queued'
+	Create(e Execution) error
+	Get(id string) (Execution, error)
+	// GetForUser retourne l'exécution si son script appartient à l'utilisateur
+	GetForUser(id string, userID int) (Execution, error)
+	// CreateRunning enregistre une exécution 'running' si son utilisateur a moins de maxRunning
+	// exécutions et serveurs en cours (0 = illimité) ; ErrLimitReached sinon
+	CreateRunning(e Execution, maxRunning int64) error
+	// Start passe une exécution 'queued' de userID à 'running', dans la même limite ;
+	// false si elle n'était plus en attente ou si la limite est atteinte
+	Start(id string, userID int, startedAt string, maxRunning int64) (bool, error)
+	// Finish termine l'exécution et supprime son point de reprise
+	Finish(id, status string, exitCode int, finishedAt string) error
+	Status(id string) (string, error)
+	SetMetrics(id string, m Metrics) error
+	// Running retourne les exécutions 'running', par date de démarrage
+	Running() ([]Execution, error)
+	// SaveCheckpoint enregistre ou remplace l'étape en cours d'une exécution
+	SaveCheckpoint(c Checkpoint) error
+	// Checkpoint retourne ErrNotFound si l'exécution n'a pas atteint de conteneur
+	Checkpoint(id string) (Checkpoint, error)
+
+	// List retourne une page d'exécutions et le curseur de la suivante (nil en fin de liste)
+	List(f ExecutionFilter) ([]Execution, *Cursor, error)
+	// ActiveForSchedule compte les exécutions 'running' ou 'queued' d'un planning
+	ActiveForSchedule(scheduleID string) (int, error)
+	// Queued retourne les exécutions en attente dont le planning n'a rien en cours, les plus anciennes d'abord
+	Queued() ([]Execution, error)
+	// CountRunning compte les exécutions 'running' et les serveurs en marche de l'utilisateur
+	CountRunning(userID int) (int64, error)
+	// ComputeSeconds additionne la durée des exécutions démarrées depuis since ; celles en cours comptent jusqu'à now
+	ComputeSeconds(userID int, since, now time.Time) (int64, error)
+	// Stats agrège les métriques des exécutions mesurées du script, démarrées depuis since (zéro : toutes)
+	Stats(scriptID string, since time.Time) (ExecutionStats, error)
+}
+
+// ExecutionFilter décrit une page de GET /executions
+type ExecutionFilter struct {
+	UserID   int
+	ScriptID string
+	Statuses []string
+	Triggers []string
+	ExitCode *int
+	// Bornes sur created_at ; zéro : pas de borne
+	Since, Until time.Time
+	Sort         string // created_at ou duration_ms
+	Desc         bool
+	After        *Cursor
+	Limit        int
+}
+
+// ExecutionStats reprend les champs des statistiques de GET /scripts/{id}/metrics (conversion directe)
+type ExecutionStats struct {
+	Executions         int
+	TotalDurationMs    int64
+	AvgDurationMs      int64
+	MaxPeakMemoryBytes int64
+	AvgPeakMemoryBytes int64
+	TotalCPUTimeMs     int64
+	AvgCPUTimeMs       int64
+	TotalNetRxBytes    int64
+	TotalNetTxBytes    int64
+	TotalBlockRead     int64
+	TotalBlockWrite    int64
+}
+
+// Tris possibles de la liste des exécutions
+var executionSorts = map[string]sortKey{
+	"created_at":  {expr: "e.created_at", layout: sqlTimeFormat},
+	"duration_ms": {expr: "COALESCE(e.duration_ms, -1)", numeric: true},
+}
+
+type executionRepo struct{ *querier }
+
+func (r *executionRepo) Create(e Execution) error {
+	_, err := r.exec(
+		`INSERT INTO executions (id, script_id, user_id, status, trigger, schedule_id, trigger_id, started_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ID, e.ScriptID, e.UserID, e.Status, e.Trigger, e.ScheduleID, e.TriggerID, e.StartedAt,
+	)
+	if r.dialect.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+const executionColumns = `e.id, e.script_id, e.user_id, e.status, e.trigger, e.schedule_id, e.trigger_id, e.exit_code,
+	e.started_at, e.finished_at, e.created_at, e.duration_ms, COALESCE(e.peak_memory_bytes, 0), COALESCE(e.cpu_time_ms, 0),
+	COALESCE(e.net_rx_bytes, 0), COALESCE(e.net_tx_bytes, 0), COALESCE(e.block_read_bytes, 0), COALESCE(e.block_write_bytes, 0)`
+
+func (r *executionRepo) Get(id string) (Execution, error) {
+	return r.scan(r.queryRow(`SELECT `+executionColumns+` FROM executions e WHERE e.id = ?`, id))
+}
+
+func (r *executionRepo) GetForUser(id string, userID int) (Execution, error) {
+	return r.scan(r.queryRow(
+		`SELECT `+executionColumns+` FROM executions e JOIN scripts s ON e.script_id = s.id WHERE e.id = ? AND s.user_id = ?`,
+		id, userID,
+	))
+}
+
+func (r *executionRepo) scan(row interface{ Scan(...any) error }) (Execution, error) {
+	var e Execution
+	var m Metrics
+	var duration *int64
+	err := row.Scan(&e.ID, &e.ScriptID, &e.UserID, &e.Status, &e.Trigger, &e.ScheduleID, &e.TriggerID, &e.ExitCode,
+		&e.StartedAt, &e.FinishedAt, &e.CreatedAt, &duration, &m.PeakMemoryBytes, &m.CPUTimeMs,
+		&m.NetRxBytes, &m.NetTxBytes, &m.BlockReadBytes, &m.BlockWriteBytes)
+	if err != nil {
+		return e, notFound(err)
+	}
+	e.CreatedAt = rfc3339(e.CreatedAt)
+	if duration != nil {
+		m.DurationMs = *duration
+		e.Metrics = &m
+	}
+	return e, nil
+}
+
+func (r *executionRepo) CreateRunning(e Execution, maxRunning int64) error {
+	n, err := r.execForUser(e.UserID,
+		`INSERT INTO executions (id, script_id, user_id, status, trigger, schedule_id, trigger_id, started_at)
+		 SELECT ?, ?, CAST(? AS INTEGER), 'running', ?, ?, ?, ? WHERE ? = 0 OR `+runningCount+` < ?`,
+		e.ID, e.ScriptID, e.UserID, e.Trigger, e.ScheduleID, e.TriggerID, e.StartedAt, maxRunning, e.UserID, e.UserID, maxRunning,
+	)
+	if r.dialect.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err == nil && n == 0 {
+		return ErrLimitReached
+	}
+	return err
+}
+
+func (r *executionRepo) Start(id string, userID int, startedAt string, maxRunning int64) (bool, error) {
+	n, err := r.execForUser(userID,
+		`UPDATE executions SET status = 'running', started_at = ?
+		 WHERE id = ? AND status = 'queued' AND (? = 0 OR `+runningCount+` < ?)`,
+		startedAt, id, maxRunning, userID, userID, maxRunning,
+	)
+	return n > 0, err
+}
+
+func (r *executionRepo) Finish(id, status string, exitCode int, finishedAt string) error {
+	err := affected(r.exec(
+		`UPDATE executions SET status = ?, exit_code = ?, finished_at = ? WHERE id = ?`,
+		status, exitCode, finishedAt, id,
+	))
+	if err != nil {
+		return err
+	}
+	_, err = r.exec(`DELETE FROM execution_checkpoints WHERE execution_id = ?`, id)
+	return err
+}
+
+func (r *executionRepo) Status(id string) (string, error) {
+	var status string
+	err := r.queryRow(`SELECT status FROM executions WHERE id = ?`, id).Scan(&status)
+	return status, notFound(err)
+}
+
+func (r *executionRepo) SetMetrics(id string, m Metrics) error {
+	return affected(r.exec(
+		`UPDATE executions SET duration_ms = ?, peak_memory_bytes = ?, cpu_time_ms = ?,
+		 net_rx_bytes = ?, net_tx_bytes = ?, block_read_bytes = ?, block_write_bytes = ?
+		 WHERE id = ?`,
+		m.DurationMs, m.PeakMemoryBytes, m.CPUTimeMs, m.NetRxBytes, m.NetTxBytes, m.BlockReadBytes, m.BlockWriteBytes,
+		id,
+	))
+}
+
+func (r *executionRepo) Running() ([]Execution, error) {
+	return r.list(`SELECT ` + executionColumns + ` FROM executions e WHERE e.status = 'running' ORDER BY e.started_at`)
+}
+
+func (r *executionRepo) SaveCheckpoint(c Checkpoint) error {
+	env, err := json.Marshal(c.Env)
+	if err != nil {
+		return err
+	}
+	if c.Env == nil {
+		env = []byte("[]")
+	}
+	_, err = r.exec(
+		`INSERT INTO execution_checkpoints (execution_id, phase, deadline, env, stdin) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT (execution_id) DO UPDATE SET phase = excluded.phase, deadline = excluded.deadline,
+		 env = excluded.env, stdin = excluded.stdin`,
+		c.ExecutionID, c.Phase, c.Deadline, string(env), c.Stdin,
+	)
+	return err
+}
+
+func (r *executionRepo) Checkpoint(id string) (Checkpoint, error) {
+	c := Checkpoint{ExecutionID: id}
+	var env string
+	err := r.queryRow(
+		`SELECT phase, deadline, env, stdin FROM execution_checkpoints WHERE execution_id = ?`, id,
+	).Scan(&c.Phase, &c.Deadline, &env, &c.Stdin)
+	if err != nil {
+		return c, notFound(err)
+	}
+	return c, json.Unmarshal([]byte(env), &c.Env)
+}
+
+func (r *executionRepo) list(query string, args ...any) ([]Execution, error) {
+	rows, err := r.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var executions []Execution
+	for rows.Next() {
+		e, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		executions = append(executions, e)
+	}
+	return executions, rows.Err()
+}
+
+func (r *executionRepo) List(f ExecutionFilter) ([]Execution, *Cursor, error) {
+	key, ok := executionSorts[f.Sort]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported sort %q", f.Sort)
+	}
+
+	where := []string{"e.user_id = ?"}
+	args := []any{f.UserID}
+	if f.ScriptID != "" {
+		where = append(where, "e.script_id = ?")
+		args = append(args, f.ScriptID)
+	}
+	if len(f.Statuses) > 0 {
+		where = append(where, "e.status IN ("+placeholders(len(f.Statuses))+")")
+		for _, s := range f.Statuses {
+			args = append(args, s)
+		}
+	}
+	if len(f.Triggers) > 0 {
+		where = append(where, "e.trigger IN ("+placeholders(len(f.Triggers))+")")
+		for _, t := range f.Triggers {
+			args = append(args, t)
+		}
+	}
+	if f.ExitCode != nil {
+		where = append(where, "e.exit_code = ?")
+		args = append(args, *f.ExitCode)
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "e.created_at >= ?")
+		args = append(args, sqlTime(f.Since))
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "e.created_at < ?")
+		args = append(args, sqlTime(f.Until))
+	}
+	if f.After != nil {
+		clause, cursorArgs, err := after(key, "e.id", f.Desc, *f.After)
+		if err != nil {
+			return nil, nil, err
+		}
+		where = append(where, clause)
+		args = append(args, cursorArgs...)
+	}
+
+	// Une ligne de plus que la page : il en reste une suivante
+	executions, err := r.list(
+		`SELECT `+executionColumns+` FROM executions e
+		 WHERE `+strings.Join(where, " AND ")+`
+		 ORDER BY `+orderBy(key, "e.id", f.Desc)+` LIMIT ?`,
+		append(args, f.Limit+1)...,
+	)
+	if err != nil || len(executions) <= f.Limit {
+		return executions, nil, err
+	}
+	executions = executions[:f.Limit]
+	last := executions[f.Limit-1]
+	return executions, &Cursor{Key: executionSortValue(f.Sort, last), ID: last.ID}, nil
+}
+
+// executionSortValue retourne la valeur de tri d'une exécution, telle que la compare la requête
+func executionSortValue(sort string, e Execution) string {
+	if sort == "duration_ms" {
+		if e.Metrics == nil {
+			return "-1"
+		}
+		return strconv.FormatInt(e.Metrics.DurationMs, 10)
+	}
+	if t, err := time.Parse(time.RFC3339, e.CreatedAt); err == nil {
+		return sqlTime(t)
+	}
+	return e.CreatedAt
+}
+
+func (r *executionRepo) ActiveForSchedule(scheduleID string) (int, error) {
+	var n int
+	err := r.queryRow(
+		`SELECT COUNT(*) FROM executions WHERE schedule_id = ? AND status IN ('running', 'queued')`,
+		scheduleID,
+	).Scan(&n)
+	return n, err
+}
+
+func (r *executionRepo) Queued() ([]Execution, error) {
+	return r.list(
+		`SELECT ` + executionColumns + ` FROM executions e
+		 WHERE e.status = 'queued' AND NOT EXISTS (
+			SELECT 1 FROM executions q WHERE q.schedule_id = e.schedule_id AND q.status = 'running'
+		 )
+		 ORDER BY e.created_at`,
+	)
+}
+
+func (r *executionRepo) CountRunning(userID int) (int64, error) {
+	var n int64
+	err := r.queryRow(`SELECT `+runningCount, userID, userID).Scan(&n)
+	return n, err
+}
+
+func (r *executionRepo) ComputeSeconds(userID int, since, now time.Time) (int64, error) {
+	var seconds int64
+	err := r.queryRow(
+		`SELECT CAST(ROUND(COALESCE(SUM(`+r.dialect.epoch("COALESCE(finished_at, ?)")+` - `+r.dialect.epoch("started_at")+`), 0)) AS BIGINT)
+		 FROM executions WHERE user_id = ? AND started_at >= ?`,
+		now.UTC().Format(time.RFC3339), userID, since.UTC().Format(time.RFC3339),
+	).Scan(&seconds)
+	return seconds, err
+}
+
+func (r *executionRepo) Stats(scriptID string, since time.Time) (ExecutionStats, error) {
+	query := `SELECT COUNT(*), COALESCE(SUM(duration_ms), 0), CAST(COALESCE(AVG(duration_ms), 0) AS BIGINT),
+		        COALESCE(MAX(peak_memory_bytes), 0), CAST(COALESCE(AVG(peak_memory_bytes), 0) AS BIGINT),
+		        COALESCE(SUM(cpu_time_ms), 0), CAST(COALESCE(AVG(cpu_time_ms), 0) AS BIGINT),
+		        COALESCE(SUM(net_rx_bytes), 0), COALESCE(SUM(net_tx_bytes), 0),
+		        COALESCE(SUM(block_read_bytes), 0), COALESCE(SUM(block_write_bytes), 0)
+		 FROM executions
+		 WHERE script_id = ? AND duration_ms IS NOT NULL`
+	args := []any{scriptID}
+	if !since.IsZero() {
+		query += ` AND started_at >= ?`
+		args = append(args, since.UTC().Format(time.RFC3339))
+	}
+
+	var m ExecutionStats
+	err := r.queryRow(query, args...).Scan(&m.Executions, &m.TotalDurationMs, &m.AvgDurationMs, &m.MaxPeakMemoryBytes,
+		&m.AvgPeakMemoryBytes, &m.TotalCPUTimeMs, &m.AvgCPUTimeMs, &m.TotalNetRxBytes, &m.TotalNetTxBytes,
+		&m.TotalBlockRead, &m.TotalBlockWrite)
+	return m, err
+}
@@ -0,0 +1,55 @@
+package store
+
+// ImageRule reprend les champs de images.Rule (conversion directe)
+type ImageRule struct {
+	ID            int
+	Pattern       string
+	RequireDigest bool
+	CreatedAt     string // RFC3339
+}
+
+type ImageRules interface {
+	// List retourne les règles de l'allow-list par motif
+	List() ([]ImageRule, error)
+	// Save crée la règle ou met à jour require_digest, et retourne son id
+	Save(pattern string, requireDigest bool) (int, error)
+	Delete(id int) error
+}
+
+type imageRuleRepo struct{ *querier }
+
+func (r *imageRuleRepo) List() ([]ImageRule, error) {
+	rows, err := r.query(`SELECT id, pattern, require_digest, created_at FROM image_allowlist ORDER BY pattern`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []ImageRule
+	for rows.Next() {
+		var rule ImageRule
+		var requireDigest int
+		if err := rows.Scan(&rule.ID, &rule.Pattern, &requireDigest, &rule.CreatedAt); err != nil {
+			return nil, err
+		}
+		rule.RequireDigest = requireDigest != 0
+		rule.CreatedAt = rfc3339(rule.CreatedAt)
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (r *imageRuleRepo) Save(pattern string, requireDigest bool) (int, error) {
+	var id int
+	err := r.queryRow(
+		`INSERT INTO image_allowlist (pattern, require_digest) VALUES (?, ?)
+		 ON CONFLICT(pattern) DO UPDATE SET require_digest = excluded.require_digest
+		 RETURNING id`,
+		pattern, boolInt(requireDigest),
+	).Scan(&id)
+	return id, err
+}
+
+func (r *imageRuleRepo) Delete(id int) error {
+	return affected(r.exec(`DELETE FROM image_allowlist WHERE id = ?`, id))
+}
@@ -0,0 +1,133 @@
+package store
+
+// LogChunk est une ligne de la table logs : un morceau borné d'une sortie d'exécution
+type LogChunk struct {
+	ID          string
+	ExecutionID string
+	Stream      string
+	Content     string // vide si compressé
+	Data        []byte // gzip de Content si Compressed
+	Compressed  bool
+	Seq         int64 // ordre dans l'exécution
+	Chunk       int   // > 0 : suite de la même sortie
+	Size        int64 // taille non compressée
+	CreatedAt   string
+}
+
+type Logs interface {
+	// Usage retourne la taille stockée et le dernier numéro de séquence de l'exécution
+	Usage(executionID string) (size, lastSeq int64, err error)
+	// Append ajoute les morceaux dans une même transaction
+	Append(chunks []LogChunk) error
+	// List retourne les morceaux d'une exécution dans l'ordre, pour un stream ou tous si vide,
+	// et seulement ceux de numéro de séquence supérieur à afterSeq si afterSeq > 0
+	List(executionID, stream string, afterSeq int64) ([]LogChunk, error)
+	// DeleteExpired supprime les logs des exécutions terminées avant finishedBefore,
+	// des exécutions terminées au-delà des keepPerScript dernières de leur script, et des exécutions disparues
+	DeleteExpired(finishedBefore string, keepPerScript int) error
+	// Uncompressed retourne des morceaux non compressés d'exécutions terminées avant finishedBefore
+	Uncompressed(finishedBefore string, limit int) ([]LogChunk, error)
+	Compress(id string, data []byte) error
+}
+
+type logRepo struct{ *querier }
+
+func (r *logRepo) Usage(executionID string) (int64, int64, error) {
+	var size, seq int64
+	err := r.queryRow(
+		`SELECT COALESCE(SUM(size), 0), COALESCE(MAX(seq), 0) FROM logs WHERE execution_id = ?`,
+		executionID,
+	).Scan(&size, &seq)
+	return size, seq, err
+}
+
+func (r *logRepo) Append(chunks []LogChunk) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := r.dialect.rebind(
+		`INSERT INTO logs (id, execution_id, stream, content, seq, chunk, size) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+	)
+	for _, c := range chunks {
+		if _, err := tx.Exec(query, c.ID, c.ExecutionID, c.Stream, c.Content, c.Seq, c.Chunk, c.Size); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *logRepo) List(executionID, stream string, afterSeq int64) ([]LogChunk, error) {
+	// Les logs antérieurs au découpage ont tous seq = 0 : inclus sans afterSeq,
+	// et départagés par created_at puis l'ordre d'insertion
+	if afterSeq <= 0 {
+		afterSeq = -1
+	}
+	rows, err := r.query(
+		`SELECT id, execution_id, stream, content, data, compressed, seq, chunk, size, created_at FROM logs
+		 WHERE execution_id = ? AND (? = '' OR stream = ?) AND seq > ?
+		 ORDER BY seq ASC, created_at ASC, `+r.dialect.insertionOrder()+` ASC`,
+		executionID, stream, stream, afterSeq,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chunks := []LogChunk{}
+	for rows.Next() {
+		var c LogChunk
+		err := rows.Scan(&c.ID, &c.ExecutionID, &c.Stream, &c.Content, &c.Data, &c.Compressed, &c.Seq, &c.Chunk, &c.Size, &c.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		c.CreatedAt = rfc3339(c.CreatedAt)
+		chunks = append(chunks, c)
+	}
+	return chunks, rows.Err()
+}
+
+func (r *logRepo) DeleteExpired(finishedBefore string, keepPerScript int) error {
+	_, err := r.exec(
+		`DELETE FROM logs WHERE execution_id IN (
+			SELECT id FROM executions WHERE finished_at IS NOT NULL AND finished_at < ?
+			UNION
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY script_id ORDER BY created_at DESC, id DESC) AS n
+				FROM executions WHERE status NOT IN ('queued', 'running')
+			) ranked WHERE n > ?
+		) OR execution_id NOT IN (SELECT id FROM executions)`,
+		finishedBefore, keepPerScript,
+	)
+	return err
+}
+
+func (r *logRepo) Uncompressed(finishedBefore string, limit int) ([]LogChunk, error) {
+	rows, err := r.query(
+		`SELECT l.id, l.execution_id, l.stream, l.content, l.seq, l.chunk, l.size
+		 FROM logs l JOIN executions e ON e.id = l.execution_id
+		 WHERE l.compressed = 0 AND e.finished_at IS NOT NULL AND e.finished_at < ?
+		 LIMIT ?`,
+		finishedBefore, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chunks := []LogChunk{}
+	for rows.Next() {
+		var c LogChunk
+		if err := rows.Scan(&c.ID, &c.ExecutionID, &c.Stream, &c.Content, &c.Seq, &c.Chunk, &c.Size); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+	return chunks, rows.Err()
+}
+
+func (r *logRepo) Compress(id string, data []byte) error {
+	return affected(r.exec(`UPDATE logs SET content = '', data = ?, compressed = 1 WHERE id = ?`, data, id))
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor repère la dernière ligne d'une page : valeur de tri puis id pour départager
+type Cursor struct {
+	Key string
+	ID  string
+}
+
+// sortKey est une colonne de tri sans NULL ; numeric pour comparer le curseur comme un nombre,
+// layout pour n'accepter qu'une date dans ce format
+type sortKey struct {
+	expr    string
+	numeric bool
+	layout  string
+}
+
+// after retourne la condition de reprise après le curseur, pour l'ordre choisi
+func after(key sortKey, idExpr string, desc bool, c Cursor) (string, []any, error) {
+	var value any = c.Key
+	if key.numeric {
+		n, err := strconv.ParseInt(c.Key, 10, 64)
+		if err != nil {
+			return "", nil, ErrInvalidCursor
+		}
+		value = n
+	}
+	if key.layout != "" {
+		if _, err := time.Parse(key.layout, c.Key); err != nil {
+			return "", nil, ErrInvalidCursor
+		}
+	}
+	cmp := ">"
+	if desc {
+		cmp = "<"
+	}
+	return fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", key.expr, cmp, key.expr, idExpr, cmp),
+		[]any{value, value, c.ID}, nil
+}
+
+// cursorKey ramène la valeur lue en base au format du curseur : le driver SQLite
+// renvoie les colonnes DATETIME en RFC3339 alors qu'elles sont comparées telles que stockées
+func (k sortKey) cursorKey(v string) string {
+	if k.layout == "" {
+		return v
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC().Format(k.layout)
+	}
+	return v
+}
+
+// orderBy trie par la clé puis par id pour départager les égalités
+func orderBy(key sortKey, idExpr string, desc bool) string {
+	order := "ASC"
+	if desc {
+		order = "DESC"
+	}
+	return fmt.Sprintf("%s %s, %s %s", key.expr, order, idExpr, order)
+}
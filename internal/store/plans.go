@@ -0,0 +1,87 @@
+package store
+
+// Plan reprend les champs de quotas.Plan (conversion directe) ; 0 signifie illimité
+type Plan struct {
+	Name              string
+	MaxScripts        int64
+	MaxStorage        int64
+	MaxConcurrent     int64
+	MaxComputeSeconds int64
+	MaxVolumeSpace    int64
+	MaxVolumeTotal    int64
+	Users             int
+}
+
+type Plans interface {
+	// List retourne les plans par nom, avec leur nombre d'utilisateurs
+	List() ([]Plan, error)
+	Get(name string) (Plan, error)
+	// Save crée ou remplace un plan
+	Save(p Plan) error
+	Delete(name string) error
+	// ForUser retourne le plan de l'utilisateur, ou fallback si le sien a disparu
+	ForUser(userID int, fallback string) (Plan, error)
+	// Assign attribue un plan ; ErrNotFound si l'utilisateur n'existe pas
+	Assign(userID int, name string) error
+}
+
+type planRepo struct{ *querier }
+
+const planColumns = `p.name, p.max_scripts, p.max_storage, p.max_concurrent, p.max_compute_seconds,
+	p.max_volume_space, p.max_volume_total, (SELECT COUNT(*) FROM users u WHERE u.plan = p.name)`
+
+func (r *planRepo) scan(row interface{ Scan(...any) error }) (Plan, error) {
+	var p Plan
+	err := row.Scan(&p.Name, &p.MaxScripts, &p.MaxStorage, &p.MaxConcurrent, &p.MaxComputeSeconds, &p.MaxVolumeSpace, &p.MaxVolumeTotal, &p.Users)
+	return p, notFound(err)
+}
+
+func (r *planRepo) List() ([]Plan, error) {
+	rows, err := r.query(`SELECT ` + planColumns + ` FROM plans p ORDER BY p.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plans []Plan
+	for rows.Next() {
+		p, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, p)
+	}
+	return plans, rows.Err()
+}
+
+func (r *planRepo) Get(name string) (Plan, error) {
+	return r.scan(r.queryRow(`SELECT `+planColumns+` FROM plans p WHERE p.name = ?`, name))
+}
+
+func (r *planRepo) Save(p Plan) error {
+	_, err := r.exec(
+		`INSERT INTO plans (name, max_scripts, max_storage, max_concurrent, max_compute_seconds, max_volume_space, max_volume_total)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(name) DO UPDATE SET max_scripts = excluded.max_scripts, max_storage = excluded.max_storage,
+		 max_concurrent = excluded.max_concurrent, max_compute_seconds = excluded.max_compute_seconds,
+		 max_volume_space = excluded.max_volume_space, max_volume_total = excluded.max_volume_total`,
+		p.Name, p.MaxScripts, p.MaxStorage, p.MaxConcurrent, p.MaxComputeSeconds, p.MaxVolumeSpace, p.MaxVolumeTotal,
+	)
+	return err
+}
+
+func (r *planRepo) Delete(name string) error {
+	return affected(r.exec(`DELETE FROM plans WHERE name = ?`, name))
+}
+
+func (r *planRepo) ForUser(userID int, fallback string) (Plan, error) {
+	return r.scan(r.queryRow(
+		`SELECT `+planColumns+` FROM plans p
+		 WHERE p.name = COALESCE((SELECT u.plan FROM users u WHERE u.id = ? AND u.plan IN (SELECT name FROM plans)), ?)`,
+		userID, fallback,
+	))
+}
+
+func (r *planRepo) Assign(userID int, name string) error {
+	return affected(r.exec(`UPDATE users SET plan = ? WHERE id = ?`, name, userID))
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/database"
+	"github.com/lib/pq"
+)
+
+type postgresDialect struct{}
+
+func (postgresDialect) rebind(query string) string {
+	return database.Rebind(database.Postgres, query)
+}
+
+// 23505 : unique_violation
+func (postgresDialect) isUniqueViolation(err error) bool {
+	var e *pq.Error
+	return errors.As(err, &e) && e.Code == "23505"
+}
+
+// Aucune base PostgreSQL n'a de logs antérieurs au découpage : seq suffit
+func (postgresDialect) insertionOrder() string {
+	return "id"
+}
+
+// En READ COMMITTED, deux transactions compteraient les mêmes lignes : le verrou les met en file
+func (postgresDialect) lockUser() string {
+	return `SELECT id FROM users WHERE id = ? FOR UPDATE`
+}
+
+func (postgresDialect) epoch(expr string) string {
+	return "EXTRACT(EPOCH FROM CAST(" + expr + " AS timestamptz))"
+}
+
+func (postgresDialect) like() string {
+	return "ILIKE"
+}
+
+func (d postgresDialect) searchScripts(words []string) (string, []any) {
+	return likeWords(d.like(), words)
+}
+
+// Pas d'index plein texte : la recherche passe par ILIKE
+func (postgresDialect) setupSearch(*sql.DB) error {
+	return nil
+}
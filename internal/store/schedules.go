@@ -0,0 +1,107 @@
+package store
+
+type Schedule struct {
+	ID            string
+	ScriptID      string
+	UserID        int
+	Cron          string
+	Timezone      string
+	Enabled       bool
+	OverlapPolicy string
+	NextRunAt     *string // RFC3339, nil si désactivé
+	LastRunAt     *string
+	CreatedAt     string // RFC3339
+}
+
+type Schedules interface {
+	Create(s Schedule) error
+	// GetForUser retourne le planning s'il appartient à l'utilisateur, ErrNotFound sinon
+	GetForUser(id string, userID int) (Schedule, error)
+	// List retourne les plannings du script, par date de création
+	List(scriptID string, userID int) ([]Schedule, error)
+	// Update enregistre l'expression, le fuseau, l'activation, la politique et next_run_at
+	Update(s Schedule) error
+	Delete(id string, userID int) error
+	// Due retourne les plannings actifs dont next_run_at est passé
+	Due(now string) ([]Schedule, error)
+	// Claim avance next_run_at s'il vaut encore expected ; false si un autre l'a déjà fait
+	Claim(id, expected string, next *string, lastRunAt string) (bool, error)
+}
+
+type scheduleRepo struct{ *querier }
+
+const scheduleColumns = `id, script_id, user_id, cron_expr, timezone, enabled, overlap_policy, next_run_at, last_run_at, created_at`
+
+func (r *scheduleRepo) Create(s Schedule) error {
+	_, err := r.exec(
+		`INSERT INTO schedules (id, script_id, user_id, cron_expr, timezone, enabled, overlap_policy, next_run_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ID, s.ScriptID, s.UserID, s.Cron, s.Timezone, boolInt(s.Enabled), s.OverlapPolicy, s.NextRunAt,
+	)
+	if r.dialect.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *scheduleRepo) scan(row interface{ Scan(...any) error }) (Schedule, error) {
+	var s Schedule
+	var enabled int
+	err := row.Scan(&s.ID, &s.ScriptID, &s.UserID, &s.Cron, &s.Timezone, &enabled, &s.OverlapPolicy,
+		&s.NextRunAt, &s.LastRunAt, &s.CreatedAt)
+	s.Enabled = enabled != 0
+	s.CreatedAt = rfc3339(s.CreatedAt)
+	return s, notFound(err)
+}
+
+func (r *scheduleRepo) list(query string, args ...any) ([]Schedule, error) {
+	rows, err := r.query(`SELECT `+scheduleColumns+` FROM schedules WHERE `+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []Schedule
+	for rows.Next() {
+		s, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+func (r *scheduleRepo) GetForUser(id string, userID int) (Schedule, error) {
+	return r.scan(r.queryRow(`SELECT `+scheduleColumns+` FROM schedules WHERE id = ? AND user_id = ?`, id, userID))
+}
+
+func (r *scheduleRepo) List(scriptID string, userID int) ([]Schedule, error) {
+	return r.list(`script_id = ? AND user_id = ? ORDER BY created_at, id`, scriptID, userID)
+}
+
+func (r *scheduleRepo) Update(s Schedule) error {
+	return affected(r.exec(
+		`UPDATE schedules SET cron_expr = ?, timezone = ?, enabled = ?, overlap_policy = ?, next_run_at = ? WHERE id = ?`,
+		s.Cron, s.Timezone, boolInt(s.Enabled), s.OverlapPolicy, s.NextRunAt, s.ID,
+	))
+}
+
+func (r *scheduleRepo) Delete(id string, userID int) error {
+	return affected(r.exec(`DELETE FROM schedules WHERE id = ? AND user_id = ?`, id, userID))
+}
+
+func (r *scheduleRepo) Due(now string) ([]Schedule, error) {
+	return r.list(`enabled = 1 AND next_run_at IS NOT NULL AND next_run_at <= ?`, now)
+}
+
+func (r *scheduleRepo) Claim(id, expected string, next *string, lastRunAt string) (bool, error) {
+	err := affected(r.exec(
+		`UPDATE schedules SET next_run_at = ?, last_run_at = ? WHERE id = ? AND next_run_at = ?`,
+		next, lastRunAt, id, expected,
+	))
+	if err == ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,271 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Script struct {
+	ID          string
+	UserID      int
+	Name        string
+	Description string
+	Language    string
+	DockerImage string
+	FilePath    string
+	Mounts      string // JSON
+	LastRunAt   *string
+	CreatedAt   string // RFC3339
+}
+
+type Scripts interface {
+	Create(s Script) error
+	// CreateWithin enregistre le script si son utilisateur en a moins de maxScripts (0 = illimité) ; ErrLimitReached sinon
+	CreateWithin(s Script, maxScripts int64) error
+	// Get retourne le script s'il appartient à l'utilisateur, ErrNotFound sinon
+	Get(id string, userID int) (Script, error)
+	// Delete supprime le script ; ses exécutions, logs, planifications et serveurs suivent par cascade
+	Delete(id string) error
+	SetLastRun(id, at string) error
+	SetMounts(id, mounts string) error
+	// ByUser retourne tous les scripts de l'utilisateur (quotas, volumes montés)
+	ByUser(userID int) ([]Script, error)
+	// List retourne une page de scripts et le curseur de la suivante (nil en fin de liste)
+	List(f ScriptFilter) ([]Script, *Cursor, error)
+
+	// SetTags remplace les tags du script
+	SetTags(scriptID string, userID int, tags []string) error
+	// Tags retourne les tags de chaque script demandé, par ordre alphabétique
+	Tags(scriptIDs []string) (map[string][]string, error)
+	// TagCounts retourne les tags de l'utilisateur avec leur nombre de scripts
+	TagCounts(userID int) ([]TagCount, error)
+}
+
+// ScriptFilter décrit une page de GET /scripts
+type ScriptFilter struct {
+	UserID int
+	Words  []string // chaque mot doit apparaître dans le nom ou la description
+	Tags   []string // tous requis
+	Sort   string   // name, created_at ou last_run_at
+	Desc   bool
+	After  *Cursor
+	Limit  int
+}
+
+type TagCount struct {
+	Tag     string
+	Scripts int
+}
+
+// Tris possibles de la liste des scripts
+var scriptSorts = map[string]sortKey{
+	"name":        {expr: "s.name"},
+	"created_at":  {expr: "s.created_at", layout: sqlTimeFormat},
+	"last_run_at": {expr: "COALESCE(s.last_run_at, '')"},
+}
+
+type scriptRepo struct{ *querier }
+
+func (r *scriptRepo) Create(s Script) error {
+	if s.Mounts == "" {
+		s.Mounts = "[]"
+	}
+	_, err := r.exec(
+		`INSERT INTO scripts (id, user_id, name, description, language, docker_image, file_path, mounts)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ID, s.UserID, s.Name, s.Description, s.Language, s.DockerImage, s.FilePath, s.Mounts,
+	)
+	if r.dialect.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *scriptRepo) CreateWithin(s Script, maxScripts int64) error {
+	if s.Mounts == "" {
+		s.Mounts = "[]"
+	}
+	n, err := r.execForUser(s.UserID,
+		`INSERT INTO scripts (id, user_id, name, description, language, docker_image, file_path, mounts)
+		 SELECT ?, CAST(? AS INTEGER), ?, ?, ?, ?, ?, ?
+		 WHERE ? = 0 OR (SELECT COUNT(*) FROM scripts WHERE user_id = ?) < ?`,
+		s.ID, s.UserID, s.Name, s.Description, s.Language, s.DockerImage, s.FilePath, s.Mounts, maxScripts, s.UserID, maxScripts,
+	)
+	if r.dialect.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err == nil && n == 0 {
+		return ErrLimitReached
+	}
+	return err
+}
+
+const scriptColumns = `s.id, s.user_id, s.name, COALESCE(s.description, ''), s.language, s.docker_image, s.file_path,
+	s.mounts, s.last_run_at, s.created_at`
+
+func (r *scriptRepo) scan(row interface{ Scan(...any) error }, extra ...any) (Script, error) {
+	var s Script
+	err := row.Scan(append([]any{&s.ID, &s.UserID, &s.Name, &s.Description, &s.Language, &s.DockerImage, &s.FilePath,
+		&s.Mounts, &s.LastRunAt, &s.CreatedAt}, extra...)...)
+	s.CreatedAt = rfc3339(s.CreatedAt)
+	return s, notFound(err)
+}
+
+func (r *scriptRepo) Get(id string, userID int) (Script, error) {
+	return r.scan(r.queryRow(`SELECT `+scriptColumns+` FROM scripts s WHERE s.id = ? AND s.user_id = ?`, id, userID))
+}
+
+func (r *scriptRepo) Delete(id string) error {
+	return affected(r.exec(`DELETE FROM scripts WHERE id = ?`, id))
+}
+
+func (r *scriptRepo) SetLastRun(id, at string) error {
+	return affected(r.exec(`UPDATE scripts SET last_run_at = ? WHERE id = ?`, at, id))
+}
+
+func (r *scriptRepo) SetMounts(id, mounts string) error {
+	return affected(r.exec(`UPDATE scripts SET mounts = ? WHERE id = ?`, mounts, id))
+}
+
+func (r *scriptRepo) ByUser(userID int) ([]Script, error) {
+	rows, err := r.query(`SELECT `+scriptColumns+` FROM scripts s WHERE s.user_id = ? ORDER BY s.name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scripts []Script
+	for rows.Next() {
+		s, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, s)
+	}
+	return scripts, rows.Err()
+}
+
+func (r *scriptRepo) List(f ScriptFilter) ([]Script, *Cursor, error) {
+	key, ok := scriptSorts[f.Sort]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported sort %q", f.Sort)
+	}
+
+	where := []string{"s.user_id = ?"}
+	args := []any{f.UserID}
+	if len(f.Words) > 0 {
+		clause, wordArgs := r.dialect.searchScripts(f.Words)
+		where = append(where, clause)
+		args = append(args, wordArgs...)
+	}
+	for _, t := range f.Tags {
+		where = append(where, "s.id IN (SELECT script_id FROM script_tags WHERE user_id = ? AND tag = ?)")
+		args = append(args, f.UserID, t)
+	}
+	if f.After != nil {
+		clause, cursorArgs, err := after(key, "s.id", f.Desc, *f.After)
+		if err != nil {
+			return nil, nil, err
+		}
+		where = append(where, clause)
+		args = append(args, cursorArgs...)
+	}
+
+	rows, err := r.query(
+		`SELECT `+scriptColumns+`, `+key.expr+` FROM scripts s
+		 WHERE `+strings.Join(where, " AND ")+`
+		 ORDER BY `+orderBy(key, "s.id", f.Desc)+` LIMIT ?`,
+		append(args, f.Limit+1)...,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	scripts := []Script{}
+	var keys []string
+	for rows.Next() {
+		var k string
+		s, err := r.scan(rows, &k)
+		if err != nil {
+			return nil, nil, err
+		}
+		scripts = append(scripts, s)
+		keys = append(keys, key.cursorKey(k))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// Une ligne de plus que la page : il en reste une suivante
+	if len(scripts) > f.Limit {
+		scripts = scripts[:f.Limit]
+		return scripts, &Cursor{Key: keys[f.Limit-1], ID: scripts[f.Limit-1].ID}, nil
+	}
+	return scripts, nil, nil
+}
+
+func (r *scriptRepo) SetTags(scriptID string, userID int, tags []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(r.dialect.rebind(`DELETE FROM script_tags WHERE script_id = ?`), scriptID); err != nil {
+		return err
+	}
+	for _, t := range tags {
+		_, err := tx.Exec(r.dialect.rebind(`INSERT INTO script_tags (script_id, user_id, tag) VALUES (?, ?, ?)`), scriptID, userID, t)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *scriptRepo) Tags(scriptIDs []string) (map[string][]string, error) {
+	tags := map[string][]string{}
+	if len(scriptIDs) == 0 {
+		return tags, nil
+	}
+	args := make([]any, len(scriptIDs))
+	for i, id := range scriptIDs {
+		args[i] = id
+	}
+	rows, err := r.query(
+		`SELECT script_id, tag FROM script_tags WHERE script_id IN (`+placeholders(len(args))+`) ORDER BY tag`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return nil, err
+		}
+		tags[id] = append(tags[id], tag)
+	}
+	return tags, rows.Err()
+}
+
+func (r *scriptRepo) TagCounts(userID int) ([]TagCount, error) {
+	rows, err := r.query(`SELECT tag, COUNT(*) FROM script_tags WHERE user_id = ? GROUP BY tag ORDER BY tag`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []TagCount
+	for rows.Next() {
+		var t TagCount
+		if err := rows.Scan(&t.Tag, &t.Scripts); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
@@ -0,0 +1,215 @@
+package store
+
+import "time"
+
+type Server struct {
+	ID            string
+	UserID        int
+	Name          string
+	ScriptID      string
+	State         int
+	LastError     *string
+	Space         int64
+	Port          int
+	Access        string
+	ContainerID   *string
+	Address       *string
+	RestartPolicy string
+	MaxRetries    int
+	RestartCount  int
+	HealthCheck   *string // JSON, nil = aucun
+	HealthStatus  *string
+	Mounts        string // JSON
+	StartedAt     *string
+	CreatedAt     string // RFC3339
+}
+
+type Servers interface {
+	// Create retourne ErrConflict si le nom (qui sert de sous-domaine) est déjà pris
+	Create(s Server) error
+	Get(id string) (Server, error)
+	// GetForUser retourne le serveur s'il appartient à l'utilisateur, ErrNotFound sinon
+	GetForUser(id string, userID int) (Server, error)
+	GetByName(name string) (Server, error)
+	// List retourne les serveurs de l'utilisateur, par nom
+	List(userID int) ([]Server, error)
+	ListByScript(scriptID string) ([]Server, error)
+	// Running retourne les serveurs à l'état 1 qui ont un conteneur
+	Running() ([]Server, error)
+	// Update enregistre les réglages modifiables par PATCH /servers/{id}
+	Update(s Server) error
+	Delete(id string) error
+	// SetState change l'état et la dernière erreur ; le statut de santé est oublié
+	SetState(id string, state int, lastError *string) error
+	// SetStarted enregistre le nouveau conteneur d'un serveur démarré (état 1).
+	// healthStatus n'est gardé que si le serveur a un health check.
+	// Un serveur arrêté ne démarre que si son utilisateur a moins de maxRunning exécutions
+	// et serveurs en cours (0 = illimité) ; ErrLimitReached sinon.
+	SetStarted(id string, state int, containerID string, address *string, startedAt, healthStatus string, maxRunning int64) error
+	SetRestarts(id string, count int, lastError *string) error
+	// SetHealth ignore un serveur dont le conteneur a changé entre-temps
+	SetHealth(id, containerID, status string) error
+	// ComputeSeconds additionne le temps passé à l'état 1 par les serveurs de l'utilisateur
+	// entre since et now, serveurs supprimés compris
+	ComputeSeconds(userID int, since, now time.Time) (int64, error)
+}
+
+type serverRepo struct{ *querier }
+
+const serverColumns = `id, user_id, name, script_id, state, last_error, space, port, access, container_id, address,
+	restart_policy, max_retries, restart_count, health_check, health_status, mounts, started_at, created_at`
+
+func (r *serverRepo) Create(s Server) error {
+	if s.Mounts == "" {
+		s.Mounts = "[]"
+	}
+	_, err := r.exec(
+		`INSERT INTO servers (id, user_id, name, script_id, state, space, port, access, restart_policy, max_retries, health_check, mounts)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ID, s.UserID, s.Name, s.ScriptID, s.State, s.Space, s.Port, s.Access, s.RestartPolicy, s.MaxRetries, s.HealthCheck, s.Mounts,
+	)
+	if r.dialect.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *serverRepo) scan(row interface{ Scan(...any) error }) (Server, error) {
+	var s Server
+	err := row.Scan(&s.ID, &s.UserID, &s.Name, &s.ScriptID, &s.State, &s.LastError, &s.Space, &s.Port, &s.Access,
+		&s.ContainerID, &s.Address, &s.RestartPolicy, &s.MaxRetries, &s.RestartCount, &s.HealthCheck, &s.HealthStatus,
+		&s.Mounts, &s.StartedAt, &s.CreatedAt)
+	s.CreatedAt = rfc3339(s.CreatedAt)
+	return s, notFound(err)
+}
+
+func (r *serverRepo) list(query string, args ...any) ([]Server, error) {
+	rows, err := r.query(`SELECT `+serverColumns+` FROM servers WHERE `+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var servers []Server
+	for rows.Next() {
+		s, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, s)
+	}
+	return servers, rows.Err()
+}
+
+func (r *serverRepo) Get(id string) (Server, error) {
+	return r.scan(r.queryRow(`SELECT `+serverColumns+` FROM servers WHERE id = ?`, id))
+}
+
+func (r *serverRepo) GetForUser(id string, userID int) (Server, error) {
+	return r.scan(r.queryRow(`SELECT `+serverColumns+` FROM servers WHERE id = ? AND user_id = ?`, id, userID))
+}
+
+func (r *serverRepo) GetByName(name string) (Server, error) {
+	return r.scan(r.queryRow(`SELECT `+serverColumns+` FROM servers WHERE name = ?`, name))
+}
+
+func (r *serverRepo) List(userID int) ([]Server, error) {
+	return r.list(`user_id = ? ORDER BY name`, userID)
+}
+
+func (r *serverRepo) ListByScript(scriptID string) ([]Server, error) {
+	return r.list(`script_id = ?`, scriptID)
+}
+
+// 1 : état "en marche" (handlers.ServerOn)
+func (r *serverRepo) Running() ([]Server, error) {
+	return r.list(`state = 1 AND container_id IS NOT NULL`)
+}
+
+func (r *serverRepo) Update(s Server) error {
+	return affected(r.exec(
+		`UPDATE servers SET port = ?, access = ?, space = ?, restart_policy = ?, max_retries = ?, health_check = ?, mounts = ?
+		 WHERE id = ?`,
+		s.Port, s.Access, s.Space, s.RestartPolicy, s.MaxRetries, s.HealthCheck, s.Mounts, s.ID,
+	))
+}
+
+func (r *serverRepo) Delete(id string) error {
+	if err := r.trackRun(id, 0, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	return affected(r.exec(`DELETE FROM servers WHERE id = ?`, id))
+}
+
+// trackRun ouvre une période de fonctionnement à l'état 1 (si aucune n'est ouverte), la ferme sinon
+func (r *serverRepo) trackRun(id string, state int, at string) error {
+	if state == 1 {
+		_, err := r.exec(
+			`INSERT INTO server_runs (server_id, user_id, started_at)
+			 SELECT id, user_id, ? FROM servers
+			 WHERE id = ? AND NOT EXISTS (SELECT 1 FROM server_runs WHERE server_id = ? AND stopped_at IS NULL)`,
+			at, id, id,
+		)
+		return err
+	}
+	_, err := r.exec(`UPDATE server_runs SET stopped_at = ? WHERE server_id = ? AND stopped_at IS NULL`, at, id)
+	return err
+}
+
+func (r *serverRepo) SetState(id string, state int, lastError *string) error {
+	err := affected(r.exec(
+		`UPDATE servers SET state = ?, last_error = ?, health_status = NULL WHERE id = ?`,
+		state, lastError, id,
+	))
+	if err != nil {
+		return err
+	}
+	return r.trackRun(id, state, time.Now().UTC().Format(time.RFC3339))
+}
+
+func (r *serverRepo) SetStarted(id string, state int, containerID string, address *string, startedAt, healthStatus string, maxRunning int64) error {
+	var userID int
+	if err := r.queryRow(`SELECT user_id FROM servers WHERE id = ?`, id).Scan(&userID); err != nil {
+		return notFound(err)
+	}
+	// Un serveur déjà à l'état 1 (redémarrage) est déjà compté
+	n, err := r.execForUser(userID,
+		`UPDATE servers SET state = ?, container_id = ?, address = ?, started_at = ?,
+		 health_status = CASE WHEN health_check IS NULL THEN NULL ELSE ? END
+		 WHERE id = ? AND (? = 0 OR state = 1 OR `+runningCount+` < ?)`,
+		state, containerID, address, startedAt, healthStatus, id, maxRunning, userID, userID, maxRunning,
+	)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLimitReached
+	}
+	return r.trackRun(id, state, startedAt)
+}
+
+func (r *serverRepo) SetRestarts(id string, count int, lastError *string) error {
+	return affected(r.exec(`UPDATE servers SET restart_count = ?, last_error = ? WHERE id = ?`, count, lastError, id))
+}
+
+func (r *serverRepo) SetHealth(id, containerID, status string) error {
+	return affected(r.exec(
+		`UPDATE servers SET health_status = ? WHERE id = ? AND container_id = ?`,
+		status, id, containerID,
+	))
+}
+
+func (r *serverRepo) ComputeSeconds(userID int, since, now time.Time) (int64, error) {
+	from, to := since.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339)
+	// Chaque période est ramenée à [since, now] ; celles en cours comptent jusqu'à now
+	var seconds int64
+	err := r.queryRow(
+		`SELECT CAST(ROUND(COALESCE(SUM(
+			`+r.dialect.epoch("CASE WHEN stopped_at IS NULL OR stopped_at > ? THEN ? ELSE stopped_at END")+` -
+			`+r.dialect.epoch("CASE WHEN started_at < ? THEN ? ELSE started_at END")+`), 0)) AS BIGINT)
+		 FROM server_runs
+		 WHERE user_id = ? AND started_at < ? AND (stopped_at IS NULL OR stopped_at > ?)`,
+		to, to, from, from, userID, to, from,
+	).Scan(&seconds)
+	return seconds, err
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+	log "github.com/sirupsen/logrus"
+)
+
+// sqliteDialect retient si l'index FTS5 des scripts est utilisable
+type sqliteDialect struct {
+	fts bool
+}
+
+func newSQLiteDialect(db *sql.DB) *sqliteDialect {
+	_, err := db.Exec(`SELECT COUNT(*) FROM scripts_fts`)
+	return &sqliteDialect{fts: err == nil}
+}
+
+func (*sqliteDialect) rebind(query string) string {
+	return query
+}
+
+func (*sqliteDialect) isUniqueViolation(err error) bool {
+	var e sqlite3.Error
+	return errors.As(err, &e) &&
+		(e.ExtendedCode == sqlite3.ErrConstraintUnique || e.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+// Les logs antérieurs au découpage n'ont que l'ordre d'insertion pour se départager
+func (*sqliteDialect) insertionOrder() string {
+	return "rowid"
+}
+
+func (*sqliteDialect) epoch(expr string) string {
+	return "(julianday(" + expr + ") * 86400.0)"
+}
+
+// SQLite n'a qu'un écrivain à la fois : chaque requête est déjà atomique
+func (*sqliteDialect) lockUser() string {
+	return ""
+}
+
+// LIKE ignore déjà la casse des caractères ASCII
+func (*sqliteDialect) like() string {
+	return "LIKE"
+}
+
+func (d *sqliteDialect) searchScripts(words []string) (string, []any) {
+	if !d.fts {
+		return likeWords(d.like(), words)
+	}
+	terms := make([]string, len(words))
+	for i, w := range words {
+		terms[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"*`
+	}
+	return "s.id IN (SELECT id FROM scripts_fts WHERE scripts_fts MATCH ?)", []any{strings.Join(terms, " ")}
+}
+
+// setupSearch crée l'index plein texte des scripts (FTS5), tenu à jour par triggers.
+// L'index garde l'id du script (colonne non indexée) : le rowid implicite d'une table
+// à clé TEXT peut changer au VACUUM. Hors migrations car optionnel : FTS5 n'est
+// disponible qu'avec le build tag sqlite_fts5, sans lui la recherche passe par LIKE.
+func (d *sqliteDialect) setupSearch(db *sql.DB) error {
+	d.fts = false
+
+	var schema string
+	db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'scripts_fts'`).Scan(&schema)
+
+	if schema != "" {
+		if _, err := db.Exec(`SELECT COUNT(*) FROM scripts_fts`); err != nil {
+			// Index créé par un binaire avec FTS5 : sans le module, les triggers bloqueraient les écritures
+			dropSearchTriggers(db)
+			log.Warnf("full-text search disabled (build with -tags sqlite_fts5): %v", err)
+			return nil
+		}
+		// Ancien index lié au rowid des scripts : remplacé par un index sur leur id
+		if strings.Contains(schema, "content_rowid") {
+			dropSearchTriggers(db)
+			if _, err := db.Exec(`DROP TABLE scripts_fts`); err != nil {
+				return fmt.Errorf("failed dropping scripts_fts: %w", err)
+			}
+			schema = ""
+		}
+	}
+	if schema == "" {
+		_, err := db.Exec(`CREATE VIRTUAL TABLE scripts_fts USING fts5(id UNINDEXED, name, description);`)
+		if err != nil {
+			log.Warnf("full-text search disabled (build with -tags sqlite_fts5): %v", err)
+			return nil
+		}
+		log.Info("full-text search index created")
+	}
+
+	// Triggers absents (nouvel index ou désactivés plus haut) : l'index est reconstruit
+	var triggers int
+	db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = 'scripts_fts_insert'`).Scan(&triggers)
+	if triggers == 0 {
+		_, err := db.Exec(`
+			CREATE TRIGGER IF NOT EXISTS scripts_fts_insert AFTER INSERT ON scripts BEGIN
+				INSERT INTO scripts_fts(id, name, description) VALUES (new.id, new.name, new.description);
+			END;
+			CREATE TRIGGER IF NOT EXISTS scripts_fts_delete AFTER DELETE ON scripts BEGIN
+				DELETE FROM scripts_fts WHERE id = old.id;
+			END;
+			CREATE TRIGGER IF NOT EXISTS scripts_fts_update AFTER UPDATE OF name, description ON scripts BEGIN
+				DELETE FROM scripts_fts WHERE id = old.id;
+				INSERT INTO scripts_fts(id, name, description) VALUES (new.id, new.name, new.description);
+			END;
+			DELETE FROM scripts_fts;
+			INSERT INTO scripts_fts(id, name, description) SELECT id, name, description FROM scripts;`)
+		if err != nil {
+			return fmt.Errorf("failed creating scripts_fts triggers: %w", err)
+		}
+	}
+	d.fts = true
+	return nil
+}
+
+func dropSearchTriggers(db *sql.DB) {
+	db.Exec(`
+		DROP TRIGGER IF EXISTS scripts_fts_insert;
+		DROP TRIGGER IF EXISTS scripts_fts_delete;
+		DROP TRIGGER IF EXISTS scripts_fts_update;`)
+}
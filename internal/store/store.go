@@ -0,0 +1,229 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/database"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("already exists")
+	// ErrLimitReached : l'écriture dépasserait la limite donnée (scripts, exécutions et serveurs en cours)
+	ErrLimitReached = errors.New("limit reached")
+)
+
+// Store regroupe les dépôts d'une base SQLite ou PostgreSQL.
+// Toutes les requêtes passent par un dépôt, écrites avec des ? et traduites par le dialecte.
+type Store struct {
+	Driver string
+
+	Users      Users
+	Sessions   Sessions
+	APIKeys    APIKeys
+	Plans      Plans
+	Scripts    Scripts
+	Executions Executions
+	Logs       Logs
+	Artifacts  Artifacts
+	Schedules  Schedules
+	Triggers   Triggers
+	Webhooks   Webhooks
+	Servers    Servers
+	Volumes    Volumes
+	ImageRules ImageRules
+
+	db      *sql.DB
+	dialect dialect
+}
+
+// Open ouvre la base (DATABASE_DRIVER, DATABASE_URL) et applique les migrations en attente
+func Open(driver, dsn string) (*Store, error) {
+	db, err := database.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.Migrate(db, driver); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return New(db, driver)
+}
+
+// New construit les dépôts sur une base déjà migrée
+func New(db *sql.DB, driver string) (*Store, error) {
+	var d dialect
+	switch driver {
+	case database.SQLite:
+		d = newSQLiteDialect(db)
+	case database.Postgres:
+		d = postgresDialect{}
+	default:
+		return nil, database.ErrUnknownDriver
+	}
+
+	q := &querier{db: db, dialect: d}
+	return &Store{
+		Driver:     driver,
+		Users:      &userRepo{q},
+		Sessions:   &sessionRepo{q},
+		APIKeys:    &apiKeyRepo{q},
+		Plans:      &planRepo{q},
+		Scripts:    &scriptRepo{q},
+		Executions: &executionRepo{q},
+		Logs:       &logRepo{q},
+		Artifacts:  &artifactRepo{q},
+		Schedules:  &scheduleRepo{q},
+		Triggers:   &triggerRepo{q},
+		Webhooks:   &webhookRepo{q},
+		Servers:    &serverRepo{q},
+		Volumes:    &volumeRepo{q},
+		ImageRules: &imageRuleRepo{q},
+		db:         db,
+		dialect:    d,
+	}, nil
+}
+
+// SetupSearch prépare la recherche plein texte des scripts quand la base la permet
+func (s *Store) SetupSearch() error {
+	return s.dialect.setupSearch(s.db)
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// dialect isole ce qui diffère entre SQLite et PostgreSQL
+type dialect interface {
+	rebind(query string) string
+	// isUniqueViolation reconnaît une contrainte UNIQUE ou PRIMARY KEY violée
+	isUniqueViolation(err error) bool
+	// insertionOrder départage des lignes de même seq et created_at
+	insertionOrder() string
+	// epoch convertit une date RFC3339 stockée en TEXT en secondes depuis 1970
+	epoch(expr string) string
+	// like compare sans tenir compte de la casse
+	like() string
+	// searchScripts filtre les scripts dont le nom ou la description contient chaque mot (préfixe)
+	searchScripts(words []string) (string, []any)
+	setupSearch(db *sql.DB) error
+	// lockUser verrouille la ligne d'un utilisateur jusqu'à la fin de la transaction ;
+	// vide quand la base sérialise déjà les écritures
+	lockUser() string
+}
+
+// querier exécute les requêtes écrites avec des ? dans le dialecte de la base
+type querier struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func (q *querier) exec(query string, args ...any) (sql.Result, error) {
+	return q.db.Exec(q.dialect.rebind(query), args...)
+}
+
+func (q *querier) query(query string, args ...any) (*sql.Rows, error) {
+	return q.db.Query(q.dialect.rebind(query), args...)
+}
+
+func (q *querier) queryRow(query string, args ...any) *sql.Row {
+	return q.db.QueryRow(q.dialect.rebind(query), args...)
+}
+
+// runningCount compte les exécutions et les serveurs en cours d'un utilisateur (deux fois son id en paramètre)
+const runningCount = `((SELECT COUNT(*) FROM executions WHERE user_id = ? AND status = 'running') +
+	(SELECT COUNT(*) FROM servers WHERE user_id = ? AND state = 1))`
+
+// execForUser exécute query avec l'utilisateur verrouillé : une condition sur runningCount
+// et l'écriture qu'elle garde ne peuvent pas être entrelacées avec celles d'un autre démarrage.
+// Retourne le nombre de lignes touchées.
+func (q *querier) execForUser(userID int, query string, args ...any) (int64, error) {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if lock := q.dialect.lockUser(); lock != "" {
+		if _, err := tx.Exec(q.dialect.rebind(lock), userID); err != nil {
+			return 0, err
+		}
+	}
+	res, err := tx.Exec(q.dialect.rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, tx.Commit()
+}
+
+// notFound traduit sql.ErrNoRows en ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// affected retourne ErrNotFound si la requête n'a touché aucune ligne
+func affected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Format de CURRENT_TIMESTAMP (colonnes created_at)
+const sqlTimeFormat = "2006-01-02 15:04:05"
+
+// sqlTime met une date au format des colonnes created_at
+func sqlTime(t time.Time) string {
+	return t.UTC().Format(sqlTimeFormat)
+}
+
+// boolInt convertit un booléen pour les colonnes INTEGER 0/1 : PostgreSQL refuse true/false
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// likeWords exige que chaque mot commence un mot du nom ou de la description, comme la
+// recherche FTS5 : le texte est précédé d'une espace et ses séparateurs (-, _, .) deviennent
+// des espaces, puis cherché avec l'opérateur LIKE du dialecte
+func likeWords(op string, words []string) (string, []any) {
+	var where []string
+	var args []any
+	escape := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	for _, w := range words {
+		pattern := "% " + escape.Replace(wordSeparators.Replace(w)) + "%"
+		where = append(where, `(`+searchText("s.name")+` `+op+` ? ESCAPE '\' OR `+searchText("s.description")+` `+op+` ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+	return strings.Join(where, " AND "), args
+}
+
+var wordSeparators = strings.NewReplacer("-", " ", "_", " ", ".", " ")
+
+// searchText prépare une colonne pour likeWords
+func searchText(column string) string {
+	return `(' ' || REPLACE(REPLACE(REPLACE(COALESCE(` + column + `, ''), '-', ' '), '_', ' '), '.', ' '))`
+}
+
+// rfc3339 ramène une date created_at au format RFC3339, quel que soit le driver
+func rfc3339(s string) string {
+	if t, err := time.Parse(sqlTimeFormat, s); err == nil {
+		return t.Format(time.RFC3339)
+	}
+	return s
+}
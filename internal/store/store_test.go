@@ -0,0 +1,613 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/database"
+	"github.com/Cryptowave2-0/webhosting-goapi/internal/database/dbtest"
+)
+
+// Suite de contrat commune : chaque test tourne sur SQLite et sur PostgreSQL (voir dbtest)
+func forEachBackend(t *testing.T, fn func(t *testing.T, st *Store)) {
+	t.Run(database.SQLite, func(t *testing.T) {
+		st, err := Open(database.SQLite, filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { st.Close() })
+		fn(t, st)
+	})
+	t.Run(database.Postgres, func(t *testing.T) {
+		st, err := Open(database.Postgres, dbtest.Postgres(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { st.Close() })
+		fn(t, st)
+	})
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	dbtest.Stop()
+	os.Exit(code)
+}
+
+func TestMigrationsMatch(t *testing.T) {
+	sqlite, err := database.Migrations(database.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postgres, err := database.Migrations(database.Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("sqlite has %d migrations, postgres %d", len(sqlite), len(postgres))
+	}
+	for i := range sqlite {
+		if sqlite[i].Version != postgres[i].Version || sqlite[i].Name != postgres[i].Name {
+			t.Errorf("migration %d: sqlite %04d_%s, postgres %04d_%s", i,
+				sqlite[i].Version, sqlite[i].Name, postgres[i].Version, postgres[i].Name)
+		}
+	}
+}
+
+func TestMigrateDownUp(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st *Store) {
+		latest, err := database.Current(st.db, st.Driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := database.MigrateTo(st.db, st.Driver, 0); err != nil {
+			t.Fatalf("down to 0: %v", err)
+		}
+		if v, _ := database.Current(st.db, st.Driver); v != 0 {
+			t.Fatalf("version after down = %d, want 0", v)
+		}
+		if err := database.Migrate(st.db, st.Driver); err != nil {
+			t.Fatalf("up again: %v", err)
+		}
+		if v, _ := database.Current(st.db, st.Driver); v != latest {
+			t.Fatalf("version after up = %d, want %d", v, latest)
+		}
+	})
+}
+
+func TestUsersAndSessions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st *Store) {
+		id, err := st.Users.Create("alice", "hash", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := st.Users.Create("alice", "other", false); !errors.Is(err, ErrConflict) {
+			t.Fatalf("duplicate username: err = %v, want ErrConflict", err)
+		}
+
+		u, err := st.Users.GetByUsername("alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.ID != id || u.Password != "hash" || !u.IsAdmin || u.Plan != "default" {
+			t.Fatalf("user = %+v", u)
+		}
+		if _, err := st.Users.Get(id + 1000); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("missing user: err = %v, want ErrNotFound", err)
+		}
+
+		now := time.Now()
+		if err := st.Sessions.Save("token", id, now.Add(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Sessions.Save("token", id, now.Add(time.Hour)); err != nil {
+			t.Fatalf("saving an existing session: %v", err)
+		}
+		if got, err := st.Sessions.UserID("token", now); err != nil || got != id {
+			t.Fatalf("session user = %d, %v", got, err)
+		}
+		// La nouvelle expiration remplace l'ancienne
+		if _, err := st.Sessions.UserID("token", now.Add(10*time.Minute)); err != nil {
+			t.Fatalf("renewed session: %v", err)
+		}
+		if _, err := st.Sessions.UserID("token", now.Add(2*time.Hour)); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expired session: err = %v, want ErrNotFound", err)
+		}
+
+		if err := st.Sessions.Save("old", id, now.Add(-time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Sessions.DeleteExpired(now); err != nil {
+			t.Fatal(err)
+		}
+		var left int
+		if err := st.db.QueryRow(`SELECT COUNT(*) FROM sessions`).Scan(&left); err != nil || left != 1 {
+			t.Fatalf("%d sessions after DeleteExpired, %v", left, err)
+		}
+		if got, err := st.Sessions.UserID("token", now); err != nil || got != id {
+			t.Fatalf("session kept by DeleteExpired = %d, %v", got, err)
+		}
+		if err := st.Sessions.Delete("token"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := st.Sessions.UserID("token", now); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("deleted session: err = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestAPIKeys(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st *Store) {
+		alice, _ := st.Users.Create("alice", "hash", false)
+		bob, _ := st.Users.Create("bob", "hash", false)
+
+		if err := st.APIKeys.Create(APIKey{ID: "k1", UserID: alice, Name: "ci", Prefix: "whk_abcd", KeyHash: "h1"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.APIKeys.Create(APIKey{ID: "k2", UserID: alice, Name: "ci", Prefix: "whk_efgh", KeyHash: "h2"}); !errors.Is(err, ErrConflict) {
+			t.Fatalf("duplicate name: err = %v, want ErrConflict", err)
+		}
+		if err := st.APIKeys.Create(APIKey{ID: "k2", UserID: bob, Name: "ci", Prefix: "whk_efgh", KeyHash: "h2"}); err != nil {
+			t.Fatalf("same name for another user: %v", err)
+		}
+
+		k, err := st.APIKeys.GetByHash("h1")
+		if err != nil || k.ID != "k1" || k.UserID != alice || k.LastUsedAt != nil || k.CreatedAt == "" {
+			t.Fatalf("key = %+v, %v", k, err)
+		}
+		if _, err := st.APIKeys.GetByHash("missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("missing key: err = %v, want ErrNotFound", err)
+		}
+
+		// Une utilisation par minute au plus est enregistrée
+		now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		for _, at := range []time.Time{now, now.Add(30 * time.Second)} {
+			if err := st.APIKeys.Touch("k1", at); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if k, _ := st.APIKeys.GetByHash("h1"); k.LastUsedAt == nil || *k.LastUsedAt != "2026-10-19T12:00:00Z" {
+			t.Fatalf("last used at %v", k.LastUsedAt)
+		}
+
+		if keys, err := st.APIKeys.List(alice); err != nil || len(keys) != 1 || keys[0].ID != "k1" {
+			t.Fatalf("alice's keys = %+v, %v", keys, err)
+		}
+		if err := st.APIKeys.Delete("k1", bob); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("delete another user's key: err = %v, want ErrNotFound", err)
+		}
+		if err := st.APIKeys.Delete("k1", alice); err != nil {
+			t.Fatal(err)
+		}
+		if keys, _ := st.APIKeys.List(alice); len(keys) != 0 {
+			t.Fatalf("keys after delete = %+v", keys)
+		}
+	})
+}
+
+// fixture crée un utilisateur et un script
+func fixture(t *testing.T, st *Store) (int, Script) {
+	t.Helper()
+	userID, err := st.Users.Create("bob", "hash", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Script{
+		ID:          "script-1",
+		UserID:      userID,
+		Name:        "hello",
+		Language:    "python",
+		DockerImage: "python:3.12-slim",
+		FilePath:    "scripts/script-1/script.py",
+	}
+	if err := st.Scripts.Create(s); err != nil {
+		t.Fatal(err)
+	}
+	return userID, s
+}
+
+func TestScripts(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st *Store) {
+		userID, s := fixture(t, st)
+		if err := st.Scripts.Create(s); !errors.Is(err, ErrConflict) {
+			t.Fatalf("duplicate script: err = %v, want ErrConflict", err)
+		}
+
+		got, err := st.Scripts.Get(s.ID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != s.Name || got.Mounts != "[]" || got.LastRunAt != nil {
+			t.Fatalf("script = %+v", got)
+		}
+		if _, err := time.Parse(time.RFC3339, got.CreatedAt); err != nil {
+			t.Fatalf("created_at %q is not RFC3339", got.CreatedAt)
+		}
+		if _, err := st.Scripts.Get(s.ID, userID+1); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("other user's script: err = %v, want ErrNotFound", err)
+		}
+
+		at := "2026-01-02T03:04:05Z"
+		if err := st.Scripts.SetLastRun(s.ID, at); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := st.Scripts.Get(s.ID, userID); got.LastRunAt == nil || *got.LastRunAt != at {
+			t.Fatalf("last_run_at = %v", got.LastRunAt)
+		}
+
+		// Les exécutions et leurs logs suivent le script
+		started := at
+		if err := st.Executions.Create(Execution{ID: "exec-1", ScriptID: s.ID, UserID: userID, Status: "running", Trigger: "manual", StartedAt: &started}); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Logs.Append([]LogChunk{{ID: "log-1", ExecutionID: "exec-1", Stream: "stdout", Content: "hi", Seq: 1, Size: 2}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Scripts.Delete(s.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := st.Executions.Get("exec-1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("execution after script delete: err = %v, want ErrNotFound", err)
+		}
+		if chunks, _ := st.Logs.List("exec-1", "", 0); len(chunks) != 0 {
+			t.Fatalf("%d log chunks left after script delete", len(chunks))
+		}
+		if err := st.Scripts.Delete(s.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("deleting twice: err = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestScriptSearchIndex(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st *Store) {
+		d, ok := st.dialect.(*sqliteDialect)
+		if !ok {
+			t.Skip("no full-text index on " + st.Driver)
+		}
+		// Index de l'ancienne version, lié au rowid des scripts : remplacé au démarrage
+		if _, err := st.db.Exec(`CREATE VIRTUAL TABLE scripts_fts USING fts5(name, description, content='scripts', content_rowid='rowid')`); err != nil {
+			t.Skip("built without -tags sqlite_fts5")
+		}
+		userID, err := st.Users.Create("bob", "hash", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{"a", "b", "c"} {
+			if err := st.Scripts.Create(Script{ID: id, UserID: userID, Name: "job " + id, Description: "report " + id, FilePath: id}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := st.SetupSearch(); err != nil || !d.fts {
+			t.Fatalf("setup = %v, fts %v", err, d.fts)
+		}
+		var schema string
+		st.db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'scripts_fts'`).Scan(&schema)
+		if !strings.Contains(schema, "id UNINDEXED") {
+			t.Fatalf("index not migrated: %s", schema)
+		}
+
+		// VACUUM peut renuméroter le rowid d'une table à clé TEXT : l'index suit l'id
+		if err := st.Scripts.Delete("a"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := st.db.Exec(`VACUUM`); err != nil {
+			t.Fatal(err)
+		}
+		for q, want := range map[string][]string{"report": {"b", "c"}, "job c": {"c"}, "a": {}} {
+			scripts, _, err := st.Scripts.List(ScriptFilter{UserID: userID, Words: strings.Fields(q), Sort: "name", Limit: 10})
+			ids := []string{}
+			for _, s := range scripts {
+				ids = append(ids, s.ID)
+			}
+			if err != nil || !slices.Equal(ids, want) {
+				t.Errorf("q=%q: %v %v, want %v", q, ids, err, want)
+			}
+		}
+	})
+}
+
+func TestExecutions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st *Store) {
+		userID, s := fixture(t, st)
+		if err := st.Executions.Create(Execution{ID: "exec-1", ScriptID: s.ID, UserID: userID, Status: "queued", Trigger: "schedule"}); err != nil {
+			t.Fatal(err)
+		}
+
+		e, err := st.Executions.GetForUser("exec-1", userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Status != "queued" || e.StartedAt != nil || e.ScheduleID != nil || e.Metrics != nil {
+			t.Fatalf("execution = %+v", e)
+		}
+		if _, err := st.Executions.GetForUser("exec-1", userID+1); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("other user's execution: err = %v, want ErrNotFound", err)
+		}
+
+		at := "2026-01-02T03:04:05Z"
+		if ok, err := st.Executions.Start("exec-1", userID, at, 0); err != nil || !ok {
+			t.Fatalf("start = %v, %v", ok, err)
+		}
+		if ok, err := st.Executions.Start("exec-1", userID, at, 0); err != nil || ok {
+			t.Fatalf("starting twice = %v, %v", ok, err)
+		}
+		if status, _ := st.Executions.Status("exec-1"); status != "running" {
+			t.Fatalf("status = %q, want running", status)
+		}
+
+		if running, err := st.Executions.Running(); err != nil || len(running) != 1 || running[0].ID != "exec-1" {
+			t.Fatalf("running = %+v, %v", running, err)
+		}
+		if _, err := st.Executions.Checkpoint("exec-1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("checkpoint before any phase: err = %v, want ErrNotFound", err)
+		}
+		cp := Checkpoint{ExecutionID: "exec-1", Phase: "build", Deadline: at, Env: []string{"A=1"}, Stdin: []byte("in")}
+		if err := st.Executions.SaveCheckpoint(cp); err != nil {
+			t.Fatal(err)
+		}
+		cp.Phase = "run"
+		if err := st.Executions.SaveCheckpoint(cp); err != nil {
+			t.Fatal(err)
+		}
+		got, err := st.Executions.Checkpoint("exec-1")
+		if err != nil || got.Phase != "run" || got.Deadline != at || len(got.Env) != 1 || got.Env[0] != "A=1" || string(got.Stdin) != "in" {
+			t.Fatalf("checkpoint = %+v, %v", got, err)
+		}
+
+		if err := st.Executions.Finish("exec-1", "success", 0, at); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := st.Executions.Checkpoint("exec-1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("checkpoint after finish: err = %v, want ErrNotFound", err)
+		}
+		if running, _ := st.Executions.Running(); len(running) != 0 {
+			t.Fatalf("running after finish = %+v", running)
+		}
+		m := Metrics{DurationMs: 1500, PeakMemoryBytes: 1 << 20, CPUTimeMs: 20, NetRxBytes: 3, NetTxBytes: 4, BlockReadBytes: 5, BlockWriteBytes: 6}
+		if err := st.Executions.SetMetrics("exec-1", m); err != nil {
+			t.Fatal(err)
+		}
+
+		e, err = st.Executions.Get("exec-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Status != "success" || e.ExitCode == nil || *e.ExitCode != 0 || e.FinishedAt == nil || *e.FinishedAt != at {
+			t.Fatalf("finished execution = %+v", e)
+		}
+		if e.Metrics == nil || *e.Metrics != m {
+			t.Fatalf("metrics = %+v, want %+v", e.Metrics, m)
+		}
+		if _, err := st.Executions.Status("missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("missing execution: err = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestRunningLimit(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st *Store) {
+		userID, s := fixture(t, st)
+		at := "2026-01-02T03:04:05Z"
+		running := func(id string) Execution {
+			return Execution{ID: id, ScriptID: s.ID, UserID: userID, Trigger: "manual", StartedAt: &at}
+		}
+
+		if err := st.Executions.CreateRunning(running("exec-1"), 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Executions.CreateRunning(running("exec-2"), 1); !errors.Is(err, ErrLimitReached) {
+			t.Fatalf("second execution over the limit: err = %v, want ErrLimitReached", err)
+		}
+		if err := st.Executions.CreateRunning(running("exec-1"), 0); !errors.Is(err, ErrConflict) {
+			t.Fatalf("duplicate execution: err = %v, want ErrConflict", err)
+		}
+		if err := st.Executions.Create(Execution{ID: "queued", ScriptID: s.ID, UserID: userID, Status: "queued", Trigger: "schedule"}); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := st.Executions.Start("queued", userID, at, 1); err != nil || ok {
+			t.Fatalf("start over the limit = %v, %v", ok, err)
+		}
+
+		// Un serveur en marche prend une place ; son redémarrage ne compte pas deux fois
+		if err := st.Servers.Create(Server{ID: "srv-1", UserID: userID, Name: "web", ScriptID: s.ID, Access: "public", RestartPolicy: "never"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Servers.SetStarted("srv-1", 1, "c1", nil, at, "starting", 1); !errors.Is(err, ErrLimitReached) {
+			t.Fatalf("server over the limit: err = %v, want ErrLimitReached", err)
+		}
+		if err := st.Servers.SetStarted("srv-1", 1, "c1", nil, at, "starting", 2); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := st.Executions.CountRunning(userID); err != nil || n != 2 {
+			t.Fatalf("running = %d, %v, want 2", n, err)
+		}
+		if err := st.Servers.SetStarted("srv-1", 1, "c2", nil, at, "starting", 2); err != nil {
+			t.Fatalf("restart at the limit: %v", err)
+		}
+		if err := st.Servers.SetStarted("missing", 1, "c3", nil, at, "starting", 0); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("missing server: err = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestServerComputeSeconds(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st *Store) {
+		userID, s := fixture(t, st)
+		if err := st.Servers.Create(Server{ID: "srv-1", UserID: userID, Name: "web", ScriptID: s.ID, Access: "public", RestartPolicy: "never"}); err != nil {
+			t.Fatal(err)
+		}
+		since := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+		now := since.Add(time.Hour)
+
+		// Démarré avant le début de la période : seule la partie après since compte
+		if err := st.Servers.SetStarted("srv-1", 1, "c1", nil, since.Add(-time.Hour).Format(time.RFC3339), "starting", 0); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := st.Servers.ComputeSeconds(userID, since, now); err != nil || n != 3600 {
+			t.Fatalf("open run = %d, %v, want 3600", n, err)
+		}
+		if n, err := st.Servers.ComputeSeconds(userID, since.AddDate(0, 1, 0), now.AddDate(0, 1, 0)); err != nil || n != 3600 {
+			t.Fatalf("open run next month = %d, %v, want 3600", n, err)
+		}
+
+		// Arrêté puis supprimé : la période fermée reste comptée
+		if err := st.Servers.SetState("srv-1", 0, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Servers.Delete("srv-1"); err != nil {
+			t.Fatal(err)
+		}
+		end := time.Now().UTC().Add(time.Minute)
+		n, err := st.Servers.ComputeSeconds(userID, since, end)
+		if want := int64(end.Sub(since).Seconds()); err != nil || n < want-120 || n > want {
+			t.Fatalf("closed run = %d, %v, want about %d", n, err, want)
+		}
+		if n, err := st.Servers.ComputeSeconds(userID, end, end.Add(time.Hour)); err != nil || n != 0 {
+			t.Fatalf("after the run = %d, %v, want 0", n, err)
+		}
+	})
+}
+
+func TestLogs(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st *Store) {
+		userID, s := fixture(t, st)
+		old := "2026-01-01T00:00:00Z"
+		for i, id := range []string{"exec-1", "exec-2", "exec-3"} {
+			if err := st.Executions.Create(Execution{ID: id, ScriptID: s.ID, UserID: userID, Status: "running", Trigger: "manual", StartedAt: &old}); err != nil {
+				t.Fatal(err)
+			}
+			if i < 2 {
+				if err := st.Executions.Finish(id, "success", 0, old); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		chunks := []LogChunk{
+			{ID: "a", ExecutionID: "exec-1", Stream: "stdout", Content: "hello ", Seq: 1, Size: 6},
+			{ID: "b", ExecutionID: "exec-1", Stream: "stdout", Content: "world", Seq: 2, Chunk: 1, Size: 5},
+			{ID: "c", ExecutionID: "exec-1", Stream: "stderr", Content: "oops", Seq: 3, Size: 4},
+		}
+		if err := st.Logs.Append(chunks); err != nil {
+			t.Fatal(err)
+		}
+		if size, seq, err := st.Logs.Usage("exec-1"); err != nil || size != 15 || seq != 3 {
+			t.Fatalf("usage = %d, %d, %v", size, seq, err)
+		}
+		if size, seq, err := st.Logs.Usage("exec-2"); err != nil || size != 0 || seq != 0 {
+			t.Fatalf("empty usage = %d, %d, %v", size, seq, err)
+		}
+
+		all, err := st.Logs.List("exec-1", "", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 3 || all[0].ID != "a" || all[1].ID != "b" || all[2].ID != "c" {
+			t.Fatalf("list = %+v", all)
+		}
+		if stderr, _ := st.Logs.List("exec-1", "stderr", 0); len(stderr) != 1 || stderr[0].Content != "oops" {
+			t.Fatalf("stderr = %+v", stderr)
+		}
+
+		// Compression des logs des exécutions terminées
+		pending, err := st.Logs.Uncompressed("2026-06-01T00:00:00Z", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != 3 {
+			t.Fatalf("%d uncompressed chunks, want 3", len(pending))
+		}
+		if err := st.Logs.Compress("a", []byte{1, 2, 3}); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := st.Logs.List("exec-1", "stdout", 0)
+		if !got[0].Compressed || got[0].Content != "" || string(got[0].Data) != "\x01\x02\x03" {
+			t.Fatalf("compressed chunk = %+v", got[0])
+		}
+		if pending, _ := st.Logs.Uncompressed("2026-06-01T00:00:00Z", 10); len(pending) != 2 {
+			t.Fatalf("%d uncompressed chunks after compress, want 2", len(pending))
+		}
+
+		// Rétention : seule la dernière exécution terminée du script garde ses logs,
+		// l'exécution en cours n'est pas comptée
+		st.Logs.Append([]LogChunk{
+			{ID: "d", ExecutionID: "exec-2", Stream: "stdout", Content: "x", Seq: 1, Size: 1},
+			{ID: "e", ExecutionID: "exec-3", Stream: "stdout", Content: "y", Seq: 1, Size: 1},
+		})
+		if err := st.Logs.DeleteExpired("2025-01-01T00:00:00Z", 1); err != nil {
+			t.Fatal(err)
+		}
+		left := map[string]int{}
+		for _, id := range []string{"exec-1", "exec-2", "exec-3"} {
+			chunks, _ := st.Logs.List(id, "", 0)
+			left[id] = len(chunks)
+		}
+		if left["exec-1"]+left["exec-2"] != 1 || left["exec-3"] != 1 {
+			t.Fatalf("chunks left after retention = %v", left)
+		}
+
+		// Ancienneté : tous les logs des exécutions terminées avant la date disparaissent
+		if err := st.Logs.DeleteExpired("2026-06-01T00:00:00Z", 100); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{"exec-1", "exec-2"} {
+			if chunks, _ := st.Logs.List(id, "", 0); len(chunks) != 0 {
+				t.Fatalf("%s: %d chunks left after expiry", id, len(chunks))
+			}
+		}
+		if chunks, _ := st.Logs.List("exec-3", "", 0); len(chunks) != 1 {
+			t.Fatalf("running execution lost its logs")
+		}
+	})
+}
+
+func TestWebhookPending(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, st *Store) {
+		userID, _ := fixture(t, st)
+		for _, id := range []string{"wh-on", "wh-off"} {
+			if err := st.Webhooks.Create(Webhook{ID: id, UserID: userID, URL: "https://example.com/" + id, Secret: "s", Enabled: true}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		at := "2026-01-01 00:00:00"
+		for _, d := range []WebhookDelivery{
+			{ID: "d-on", WebhookID: "wh-on", Event: "execution.finished", Payload: "{}", NextAttemptAt: &at},
+			{ID: "d-off", WebhookID: "wh-off", Event: "execution.finished", Payload: "{}", NextAttemptAt: &at},
+		} {
+			if err := st.Webhooks.Enqueue(d); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if pending, err := st.Webhooks.Pending("2026-01-02 00:00:00", 10); err != nil || len(pending) != 2 {
+			t.Fatalf("pending = %+v, %v", pending, err)
+		}
+
+		// Un webhook désactivé garde ses livraisons en file mais ne les envoie plus
+		if err := st.Webhooks.SetEnabled("wh-off", userID, false); err != nil {
+			t.Fatal(err)
+		}
+		pending, err := st.Webhooks.Pending("2026-01-02 00:00:00", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != 1 || pending[0].ID != "d-on" || pending[0].URL != "https://example.com/wh-on" {
+			t.Fatalf("pending after disable = %+v", pending)
+		}
+		if d, err := st.Webhooks.Delivery("d-off", "wh-off"); err != nil || d.Status != "pending" {
+			t.Fatalf("disabled delivery = %+v, %v", d, err)
+		}
+
+		// Réactivé, il reprend les livraisons en attente
+		if err := st.Webhooks.SetEnabled("wh-off", userID, true); err != nil {
+			t.Fatal(err)
+		}
+		if pending, _ := st.Webhooks.Pending("2026-01-02 00:00:00", 10); len(pending) != 2 {
+			t.Fatalf("%d pending after re-enable, want 2", len(pending))
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package store
+
+type Trigger struct {
+	ID       string
+	ScriptID string
+	UserID   int
+	AuthMode string
+	// Le token n'est gardé que haché ; la clé HMAC reste lisible pour vérifier les signatures
+	TokenHash  *string
+	HMACSecret *string
+	Headers    string // JSON
+	Sync       bool
+	CreatedAt  string // RFC3339
+}
+
+type Triggers interface {
+	Create(t Trigger) error
+	Get(id string) (Trigger, error)
+	// List retourne les déclencheurs du script, par date de création
+	List(scriptID string, userID int) ([]Trigger, error)
+	Delete(id string, userID int) error
+}
+
+type triggerRepo struct{ *querier }
+
+const triggerColumns = `id, script_id, user_id, auth_mode, token_hash, hmac_secret, headers, sync, created_at`
+
+func (r *triggerRepo) Create(t Trigger) error {
+	if t.Headers == "" {
+		t.Headers = "[]"
+	}
+	_, err := r.exec(
+		`INSERT INTO script_triggers (id, script_id, user_id, auth_mode, token_hash, hmac_secret, headers, sync)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, t.ScriptID, t.UserID, t.AuthMode, t.TokenHash, t.HMACSecret, t.Headers, boolInt(t.Sync),
+	)
+	if r.dialect.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *triggerRepo) scan(row interface{ Scan(...any) error }) (Trigger, error) {
+	var t Trigger
+	var sync int
+	err := row.Scan(&t.ID, &t.ScriptID, &t.UserID, &t.AuthMode, &t.TokenHash, &t.HMACSecret, &t.Headers, &sync, &t.CreatedAt)
+	t.Sync = sync != 0
+	t.CreatedAt = rfc3339(t.CreatedAt)
+	return t, notFound(err)
+}
+
+func (r *triggerRepo) Get(id string) (Trigger, error) {
+	return r.scan(r.queryRow(`SELECT `+triggerColumns+` FROM script_triggers WHERE id = ?`, id))
+}
+
+func (r *triggerRepo) List(scriptID string, userID int) ([]Trigger, error) {
+	rows, err := r.query(
+		`SELECT `+triggerColumns+` FROM script_triggers WHERE script_id = ? AND user_id = ? ORDER BY created_at, id`,
+		scriptID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var triggers []Trigger
+	for rows.Next() {
+		t, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, t)
+	}
+	return triggers, rows.Err()
+}
+
+func (r *triggerRepo) Delete(id string, userID int) error {
+	return affected(r.exec(`DELETE FROM script_triggers WHERE id = ? AND user_id = ?`, id, userID))
+}
@@ -0,0 +1,88 @@
+package store
+
+import "time"
+
+type User struct {
+	ID       int
+	Username string
+	Password string // hash bcrypt
+	IsAdmin  bool
+	Plan     string
+}
+
+type Users interface {
+	// Create retourne l'id du nouvel utilisateur, ErrConflict si le nom est pris
+	Create(username, passwordHash string, isAdmin bool) (int, error)
+	Get(id int) (User, error)
+	GetByUsername(username string) (User, error)
+}
+
+type Sessions interface {
+	// Save enregistre ou remplace une session valable jusqu'à expiresAt
+	Save(token string, userID int, expiresAt time.Time) error
+	// UserID retourne l'utilisateur de la session, ErrNotFound si elle n'existe pas ou a expiré à now
+	UserID(token string, now time.Time) (int, error)
+	Delete(token string) error
+	// DeleteExpired supprime les sessions expirées à now
+	DeleteExpired(now time.Time) error
+}
+
+type userRepo struct{ *querier }
+
+func (r *userRepo) Create(username, passwordHash string, isAdmin bool) (int, error) {
+	admin := 0
+	if isAdmin {
+		admin = 1
+	}
+	var id int
+	err := r.queryRow(
+		`INSERT INTO users (username, password, is_admin) VALUES (?, ?, ?) RETURNING id`,
+		username, passwordHash, admin,
+	).Scan(&id)
+	if r.dialect.isUniqueViolation(err) {
+		return 0, ErrConflict
+	}
+	return id, err
+}
+
+func (r *userRepo) Get(id int) (User, error) {
+	return r.scan(r.queryRow(`SELECT id, username, password, is_admin, plan FROM users WHERE id = ?`, id))
+}
+
+func (r *userRepo) GetByUsername(username string) (User, error) {
+	return r.scan(r.queryRow(`SELECT id, username, password, is_admin, plan FROM users WHERE username = ?`, username))
+}
+
+func (r *userRepo) scan(row interface{ Scan(...any) error }) (User, error) {
+	var u User
+	err := row.Scan(&u.ID, &u.Username, &u.Password, &u.IsAdmin, &u.Plan)
+	return u, notFound(err)
+}
+
+type sessionRepo struct{ *querier }
+
+func (r *sessionRepo) Save(token string, userID int, expiresAt time.Time) error {
+	_, err := r.exec(
+		`INSERT INTO sessions (token, user_id, expires_at) VALUES (?, ?, ?)
+		 ON CONFLICT (token) DO UPDATE SET user_id = excluded.user_id, expires_at = excluded.expires_at`,
+		token, userID, sqlTime(expiresAt),
+	)
+	return err
+}
+
+// Les sessions sans expiration (NULL) ne sont pas acceptées
+func (r *sessionRepo) UserID(token string, now time.Time) (int, error) {
+	var userID int
+	err := r.queryRow(`SELECT user_id FROM sessions WHERE token = ? AND expires_at > ?`, token, sqlTime(now)).Scan(&userID)
+	return userID, notFound(err)
+}
+
+func (r *sessionRepo) Delete(token string) error {
+	_, err := r.exec(`DELETE FROM sessions WHERE token = ?`, token)
+	return err
+}
+
+func (r *sessionRepo) DeleteExpired(now time.Time) error {
+	_, err := r.exec(`DELETE FROM sessions WHERE expires_at IS NULL OR expires_at <= ?`, sqlTime(now))
+	return err
+}
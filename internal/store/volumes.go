@@ -0,0 +1,74 @@
+package store
+
+type Volume struct {
+	ID        string
+	UserID    int
+	Name      string
+	Space     int64
+	CreatedAt string // RFC3339
+}
+
+type Volumes interface {
+	// Create retourne ErrConflict si l'utilisateur a déjà un volume de ce nom
+	Create(v Volume) error
+	// GetForUser retourne le volume s'il appartient à l'utilisateur, ErrNotFound sinon
+	GetForUser(id string, userID int) (Volume, error)
+	GetByName(userID int, name string) (Volume, error)
+	// List retourne les volumes de l'utilisateur, par nom
+	List(userID int) ([]Volume, error)
+	SetSpace(id string, space int64) error
+	Delete(id string) error
+}
+
+type volumeRepo struct{ *querier }
+
+const volumeColumns = `id, user_id, name, space, created_at`
+
+func (r *volumeRepo) Create(v Volume) error {
+	_, err := r.exec(`INSERT INTO volumes (id, user_id, name, space) VALUES (?, ?, ?, ?)`, v.ID, v.UserID, v.Name, v.Space)
+	if r.dialect.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *volumeRepo) scan(row interface{ Scan(...any) error }) (Volume, error) {
+	var v Volume
+	err := row.Scan(&v.ID, &v.UserID, &v.Name, &v.Space, &v.CreatedAt)
+	v.CreatedAt = rfc3339(v.CreatedAt)
+	return v, notFound(err)
+}
+
+func (r *volumeRepo) GetForUser(id string, userID int) (Volume, error) {
+	return r.scan(r.queryRow(`SELECT `+volumeColumns+` FROM volumes WHERE id = ? AND user_id = ?`, id, userID))
+}
+
+func (r *volumeRepo) GetByName(userID int, name string) (Volume, error) {
+	return r.scan(r.queryRow(`SELECT `+volumeColumns+` FROM volumes WHERE name = ? AND user_id = ?`, name, userID))
+}
+
+func (r *volumeRepo) List(userID int) ([]Volume, error) {
+	rows, err := r.query(`SELECT `+volumeColumns+` FROM volumes WHERE user_id = ? ORDER BY name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var volumes []Volume
+	for rows.Next() {
+		v, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, v)
+	}
+	return volumes, rows.Err()
+}
+
+func (r *volumeRepo) SetSpace(id string, space int64) error {
+	return affected(r.exec(`UPDATE volumes SET space = ? WHERE id = ?`, space, id))
+}
+
+func (r *volumeRepo) Delete(id string) error {
+	return affected(r.exec(`DELETE FROM volumes WHERE id = ?`, id))
+}
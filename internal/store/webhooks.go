@@ -0,0 +1,203 @@
+package store
+
+type Webhook struct {
+	ID        string
+	UserID    int
+	URL       string
+	Secret    string
+	Events    string // JSON
+	Enabled   bool
+	CreatedAt string // RFC3339
+}
+
+// WebhookDelivery est une entrée de l'outbox des webhooks
+type WebhookDelivery struct {
+	ID             string
+	WebhookID      string
+	Event          string
+	Payload        string
+	Status         string // pending, delivered ou failed
+	Attempts       int
+	NextAttemptAt  *string
+	LastStatusCode *int
+	LastError      *string
+	CreatedAt      string // RFC3339
+	DeliveredAt    *string
+
+	// Destination, renseignée par Pending
+	URL, Secret string
+}
+
+type Webhooks interface {
+	Create(wh Webhook) error
+	// GetForUser retourne le webhook s'il appartient à l'utilisateur, ErrNotFound sinon
+	GetForUser(id string, userID int) (Webhook, error)
+	// List retourne les webhooks de l'utilisateur, par date de création
+	List(userID int) ([]Webhook, error)
+	SetEnabled(id string, userID int, enabled bool) error
+	Delete(id string, userID int) error
+
+	// Deliveries retourne les limit dernières livraisons du webhook
+	Deliveries(webhookID string, limit int) ([]WebhookDelivery, error)
+	Delivery(id, webhookID string) (WebhookDelivery, error)
+	// Enqueue ajoute une livraison 'pending' à envoyer à partir de NextAttemptAt
+	Enqueue(d WebhookDelivery) error
+	// Pending retourne les livraisons à envoyer avant now des webhooks actifs,
+	// avec l'URL et le secret de leur webhook
+	Pending(now string, limit int) ([]WebhookDelivery, error)
+	// Delivered marque une livraison réussie
+	Delivered(id string, attempts int, statusCode *int, at string) error
+	// Retry enregistre un échec ; next nil pour abandonner (status 'failed')
+	Retry(id string, attempts int, statusCode *int, lastError string, next *string) error
+}
+
+type webhookRepo struct{ *querier }
+
+const webhookColumns = `id, user_id, url, secret, events, enabled, created_at`
+
+func (r *webhookRepo) Create(wh Webhook) error {
+	if wh.Events == "" {
+		wh.Events = "[]"
+	}
+	_, err := r.exec(
+		`INSERT INTO webhooks (id, user_id, url, secret, events, enabled) VALUES (?, ?, ?, ?, ?, ?)`,
+		wh.ID, wh.UserID, wh.URL, wh.Secret, wh.Events, boolInt(wh.Enabled),
+	)
+	if r.dialect.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *webhookRepo) scan(row interface{ Scan(...any) error }) (Webhook, error) {
+	var wh Webhook
+	var enabled int
+	err := row.Scan(&wh.ID, &wh.UserID, &wh.URL, &wh.Secret, &wh.Events, &enabled, &wh.CreatedAt)
+	wh.Enabled = enabled != 0
+	wh.CreatedAt = rfc3339(wh.CreatedAt)
+	return wh, notFound(err)
+}
+
+func (r *webhookRepo) GetForUser(id string, userID int) (Webhook, error) {
+	return r.scan(r.queryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ? AND user_id = ?`, id, userID))
+}
+
+func (r *webhookRepo) List(userID int) ([]Webhook, error) {
+	rows, err := r.query(`SELECT `+webhookColumns+` FROM webhooks WHERE user_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []Webhook
+	for rows.Next() {
+		wh, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, wh)
+	}
+	return webhooks, rows.Err()
+}
+
+func (r *webhookRepo) SetEnabled(id string, userID int, enabled bool) error {
+	return affected(r.exec(`UPDATE webhooks SET enabled = ? WHERE id = ? AND user_id = ?`, boolInt(enabled), id, userID))
+}
+
+func (r *webhookRepo) Delete(id string, userID int) error {
+	return affected(r.exec(`DELETE FROM webhooks WHERE id = ? AND user_id = ?`, id, userID))
+}
+
+const deliveryColumns = `d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_status_code,
+	d.last_error, d.created_at, d.delivered_at`
+
+func scanDelivery(row interface{ Scan(...any) error }, extra ...any) (WebhookDelivery, error) {
+	var d WebhookDelivery
+	err := row.Scan(append([]any{&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt}, extra...)...)
+	d.CreatedAt = rfc3339(d.CreatedAt)
+	return d, notFound(err)
+}
+
+func (r *webhookRepo) Deliveries(webhookID string, limit int) ([]WebhookDelivery, error) {
+	rows, err := r.query(
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries d WHERE d.webhook_id = ? ORDER BY d.created_at DESC, d.id DESC LIMIT ?`,
+		webhookID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *webhookRepo) Delivery(id, webhookID string) (WebhookDelivery, error) {
+	return scanDelivery(r.queryRow(
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries d WHERE d.id = ? AND d.webhook_id = ?`,
+		id, webhookID,
+	))
+}
+
+func (r *webhookRepo) Enqueue(d WebhookDelivery) error {
+	_, err := r.exec(
+		`INSERT INTO webhook_deliveries (id, webhook_id, event, payload, status, attempts, next_attempt_at)
+		 VALUES (?, ?, ?, ?, 'pending', 0, ?)`,
+		d.ID, d.WebhookID, d.Event, d.Payload, d.NextAttemptAt,
+	)
+	return err
+}
+
+func (r *webhookRepo) Pending(now string, limit int) ([]WebhookDelivery, error) {
+	rows, err := r.query(
+		`SELECT `+deliveryColumns+`, wh.url, wh.secret
+		 FROM webhook_deliveries d
+		 JOIN webhooks wh ON wh.id = d.webhook_id
+		 WHERE d.status = 'pending' AND d.next_attempt_at <= ? AND wh.enabled = ?
+		 ORDER BY d.next_attempt_at LIMIT ?`,
+		now, boolInt(true), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var url, secret string
+		d, err := scanDelivery(rows, &url, &secret)
+		if err != nil {
+			return nil, err
+		}
+		d.URL, d.Secret = url, secret
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *webhookRepo) Delivered(id string, attempts int, statusCode *int, at string) error {
+	return affected(r.exec(
+		`UPDATE webhook_deliveries SET status = 'delivered', attempts = ?, last_status_code = ?, last_error = NULL,
+		 next_attempt_at = NULL, delivered_at = ? WHERE id = ?`,
+		attempts, statusCode, at, id,
+	))
+}
+
+func (r *webhookRepo) Retry(id string, attempts int, statusCode *int, lastError string, next *string) error {
+	status := "pending"
+	if next == nil {
+		status = "failed"
+	}
+	return affected(r.exec(
+		`UPDATE webhook_deliveries SET status = ?, attempts = ?, last_status_code = ?, last_error = ?, next_attempt_at = ? WHERE id = ?`,
+		status, attempts, statusCode, lastError, next, id,
+	))
+}